@@ -2,8 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
-	"github.com/sirupsen/logrus"
 	"k8s.io/klog/v2"
 
 	"kube8-operator/internal"
@@ -11,30 +17,136 @@ import (
 )
 
 func main() {
-	ctx := context.Background()
+	// The root context is cancelled on SIGTERM or interrupt, which stops the controller gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A second signal kills the operator right away
+	context.AfterFunc(ctx, stop)
 
 	config := internal.Configuration{Environment: "local"}
 
-	// set up signals so we handle the shutdown signal gracefully
-	logger := &logrus.Entry{}
+	flag.StringVar(&config.Environment, "environment", "local", "Environment the operator runs in, local uses ~/.kube/config and anything else the in-cluster configuration")
+	flag.StringVar(&config.Namespace, "namespace", os.Getenv("POD_NAMESPACE"), "Namespace the operator runs in, Secrets holding chart source credentials are read from it")
+	flag.IntVar(&config.Workers, "workers", 2, "Number of workers reconciling Collector resources concurrently")
+	flag.BoolVar(&config.KeepReleaseHistory, "keep-release-history", false, "Keep the Helm release history of deleted collectors")
+	flag.StringVar(&config.HelmStorageDriver, "helm-storage-driver", operator.StorageDriverSecret, "Helm storage driver for collector releases: secret, configmap, sql or memory")
+	flag.DurationVar(&config.ReleaseTimeout, "release-timeout", 5*time.Minute, "How long an install, upgrade or rollback of a collector waits for it to become ready")
+	flag.StringVar(&config.ChartSourcesConfig, "chart-sources-config", "", "Path of the chart sources configuration file, defaults to the development-helm and production-helm S3 buckets")
+	flag.StringVar(&config.ValuesSchemasDir, "values-schemas-dir", "", "Directory of the values schemas of the collectors, {collector name}.schema.json, the values are validated against along with the schema of the chart")
+	flag.IntVar(&config.ChartCacheEntries, "chart-cache-entries", 100, "Number of charts kept in the chart cache, 0 disables the cache")
+	flag.Int64Var(&config.ChartCacheBytes, "chart-cache-bytes", 256<<20, "Total size in bytes of the charts kept in the chart cache, 0 means unlimited")
+	flag.DurationVar(&config.ChartCacheTTL, "chart-cache-ttl", 0, "How long a cached chart is used before it is fetched again, 0 keeps it until it is evicted")
+	flag.DurationVar(&config.VersionCacheTTL, "version-cache-ttl", time.Minute, "How long the chart versions published by a source are used to resolve version constraints and latest before they are listed again, 0 lists them on every reconcile")
+	flag.BoolVar(&config.LeaderElect, "leader-elect", false, "Elect a leader through a Lease so that only one replica of the operator reconciles collectors")
+	flag.StringVar(&config.LeaderElectLeaseName, "leader-elect-lease-name", "kube8-operator", "Name of the leader election Lease, created in the operator namespace")
+	flag.StringVar(&config.LeaderElectIdentity, "leader-elect-identity", "", "Leader election identity of this replica, defaults to the host name with a random suffix")
+	flag.DurationVar(&config.LeaderElectLeaseDuration, "leader-elect-lease-duration", 15*time.Second, "How long followers wait after the last renewal of the lease before taking it over")
+	flag.DurationVar(&config.LeaderElectRenewDeadline, "leader-elect-renew-deadline", 10*time.Second, "How long the leader keeps trying to renew the lease before it stops leading")
+	flag.DurationVar(&config.LeaderElectRetryPeriod, "leader-elect-retry-period", 2*time.Second, "How often the lease is renewed or tried to be acquired")
+	flag.DurationVar(&config.ShutdownGracePeriod, "shutdown-grace-period", 25*time.Second, "How long running reconciles are given to finish when the operator is stopped")
+	flag.DurationVar(&config.DriftCheckInterval, "drift-check-interval", 5*time.Minute, "How often the live objects of the collectors are compared with the manifest of their Helm release, 0 only checks the objects when they change")
+	flag.IntVar(&config.WebhookPort, "webhook-port", 0, "Port the admission and conversion webhooks are served on over HTTPS, 0 disables them, which the operator refuses when the served Collector CRD converts with the webhook")
+	flag.StringVar(&config.WebhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "Directory holding the tls.crt and tls.key serving certificate of the webhooks")
+	flag.StringVar(&config.DefaultCluster, "default-cluster", "", "Cluster the defaulting webhook sets on Collectors without one, defaults to --environment unless it is local")
+	flag.StringVar(&config.DefaultCollectorVersion, "default-collector-version", "latest", "Version channel the defaulting webhook sets on Collectors without a version")
+	flag.BoolVar(&config.InstallCRD, "install-crd", false, "Install or upgrade the Collector, Tenant and CollectorSet CRDs, the webhook Service and the admission webhook configurations shipped with the operator when it starts")
+	flag.StringVar(&config.WebhookServiceName, "webhook-service-name", "kube8-operator-webhook", "Service in the operator namespace the API server reaches the webhooks through, used for the conversion webhook of the installed CRD")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	config.HelmSQLConnectionString = os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING")
+
+	if config.DefaultCluster == "" && config.Environment != "local" {
+		config.DefaultCluster = config.Environment
+	}
+
+	// The installed CRD converts between its versions through the webhook server
+	if config.InstallCRD && config.WebhookPort <= 0 {
+		klog.ErrorS(errors.New("--install-crd needs --webhook-port"), "Invalid configuration")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
 
 	kubeconfig, err := config.Kubeconfig()
 	if err != nil {
-		panic(err)
+		klog.ErrorS(err, "Error building kubeconfig")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 
 	// Set up a new controller object.
-	ctrl, err := operator.NewController(ctx, kubeconfig)
+	ctrl, err := operator.NewController(ctx, kubeconfig, operator.Options{
+		Workers:                 config.Workers,
+		KeepReleaseHistory:      config.KeepReleaseHistory,
+		ReleaseTimeout:          config.ReleaseTimeout,
+		ChartSourcesConfig:      config.ChartSourcesConfig,
+		ValuesSchemasDir:        config.ValuesSchemasDir,
+		ChartCacheMaxEntries:    config.ChartCacheEntries,
+		ChartCacheMaxBytes:      config.ChartCacheBytes,
+		ChartCacheTTL:           config.ChartCacheTTL,
+		VersionCacheTTL:         config.VersionCacheTTL,
+		HelmStorageDriver:       config.HelmStorageDriver,
+		HelmSQLConnectionString: config.HelmSQLConnectionString,
+		Namespace:               config.Namespace,
+		LeaderElection: operator.LeaderElection{
+			Enabled:       config.LeaderElect,
+			LeaseName:     config.LeaderElectLeaseName,
+			Identity:      config.LeaderElectIdentity,
+			LeaseDuration: config.LeaderElectLeaseDuration,
+			RenewDeadline: config.LeaderElectRenewDeadline,
+			RetryPeriod:   config.LeaderElectRetryPeriod,
+		},
+		ShutdownGracePeriod: config.ShutdownGracePeriod,
+		DriftCheckInterval:  config.DriftCheckInterval,
+		InstallCRD:          config.InstallCRD,
+		CRDConversion: operator.CRDConversion{
+			ServiceName:      config.WebhookServiceName,
+			ServiceNamespace: config.Namespace,
+			WebhookPort:      config.WebhookPort,
+			CABundleFile:     filepath.Join(config.WebhookCertDir, "ca.crt"),
+		},
+	})
 	if err != nil {
-		logger.Error(err, "Error creating controller")
+		klog.ErrorS(err, "Error creating controller")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 
-	// Set up channels for stopping the controller.
-	stop := make(chan struct{})
+	// The webhooks are served by every replica, a webhook server that fails stops the operator
+	webhookErr := make(chan error, 1)
 
-	err = ctrl.Start(stop)
-	if err != nil {
-		logger.Error(err, "Error starting controller")
+	if config.WebhookPort > 0 {
+		chartSourcesConfig, err := operator.LoadChartSourcesConfig(config.ChartSourcesConfig)
+		if err != nil {
+			klog.ErrorS(err, "Error loading chart sources config")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+
+		webhookServer := operator.NewWebhookServer(
+			operator.WebhookOptions{Port: config.WebhookPort, CertDir: config.WebhookCertDir},
+			operator.NewCollectorValidator(chartSourcesConfig),
+			operator.NewCollectorDefaulter(config.DefaultCluster, config.DefaultCollectorVersion),
+		)
+
+		go func() {
+			if err := webhookServer.Start(ctx); err != nil {
+				webhookErr <- err
+
+				stop()
+			}
+		}()
+	}
+
+	if err = ctrl.Start(ctx); err != nil {
+		klog.ErrorS(err, "Error running controller")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
+
+	select {
+	case err = <-webhookErr:
+		klog.ErrorS(err, "Error running webhook server")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	default:
+	}
+
+	klog.Info("Controller stopped")
+	klog.Flush()
 }
@@ -4,4 +4,7 @@
 // This package imports things required by build scripts, to force `go mod` to see them as dependencies
 package hack
 
-import _ "k8s.io/code-generator"
+import (
+	_ "k8s.io/code-generator"
+	_ "sigs.k8s.io/controller-tools/cmd/controller-gen"
+)
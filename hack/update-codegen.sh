@@ -4,26 +4,35 @@ set -o errexit
 set -o nounset
 set -o pipefail
 
+cd "$(dirname "${BASH_SOURCE[0]}")/.."
+
 CODEGEN_SCRIPT="./vendor/k8s.io/code-generator/generate-groups.sh"
 GENERATORS="all"
 OUTPUT_PACKAGE="kube8-operator/pkg/generated"
 APIS_PACKAGE="kube8-operator/pkg/apis"
-GROUPS_VERSIONS="collector:v1alpha"
-OUTPUT_BASE="."
+GROUP="collector"
+VERSIONS="v1alpha v1beta1"
 GO_HEADER_FILE="./hack/boilerplate.go.txt"
 
-chmod +x ${CODEGEN_SCRIPT}
-"${CODEGEN_SCRIPT}" "${GENERATORS}" "${OUTPUT_PACKAGE}" "${APIS_PACKAGE}" "${GROUPS_VERSIONS}" \
-  --output-base "${OUTPUT_BASE}" \
-  --go-header-file "${GO_HEADER_FILE}"
-
-# Copy generated files to the target directory
-TARGET_DIR="kube8-operator/pkg"
+# The generators write the packages under their import path, which starts with the module name rather than
+# the repository root, so they are generated in a temporary directory and copied over
+OUTPUT_BASE="$(mktemp -d)"
+cp go.sum "${OUTPUT_BASE}/go.sum"
 
-cp -rf "${OUTPUT_PACKAGE}" "${TARGET_DIR}"
+cleanup() {
+  # Installing the generators adds their dependencies to go.sum
+  cp "${OUTPUT_BASE}/go.sum" go.sum
+  rm -rf "${OUTPUT_BASE}"
+}
+trap cleanup EXIT
 
-cp -rf "${APIS_PACKAGE}/collector/v1alpha/zz_generated.deepcopy.go" "${TARGET_DIR}/apis/collector/v1alpha"
+bash "${CODEGEN_SCRIPT}" "${GENERATORS}" "${OUTPUT_PACKAGE}" "${APIS_PACKAGE}" "${GROUP}:${VERSIONS// /,}" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${GO_HEADER_FILE}"
 
-# Delete the generated code folder
-rm -rf "kube8-operator"
+rm -rf pkg/generated
+cp -r "${OUTPUT_BASE}/${OUTPUT_PACKAGE}" pkg/generated
 
+for VERSION in ${VERSIONS}; do
+  cp -f "${OUTPUT_BASE}/${APIS_PACKAGE}/${GROUP}/${VERSION}/zz_generated.deepcopy.go" "pkg/apis/${GROUP}/${VERSION}/"
+done
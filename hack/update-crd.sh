@@ -0,0 +1,44 @@
+#!/usr/bin/env bash
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+CONTROLLER_GEN="sigs.k8s.io/controller-tools/cmd/controller-gen"
+APIS_PATHS="./pkg/apis/..."
+CRD_DIR="internal/operator"
+# The Service and namespace the API server reaches the conversion webhook of the operator through depend on where
+# the operator is deployed, they are left as placeholders that --install-crd or envsubst replace, like in webhook.yaml
+WEBHOOK_SERVICE_NAME='${WEBHOOK_SERVICE_NAME}'
+WEBHOOK_SERVICE_NAMESPACE='${WEBHOOK_SERVICE_NAMESPACE}'
+
+OUTPUT_DIR="$(mktemp -d)"
+trap 'rm -rf "${OUTPUT_DIR}"' EXIT
+
+# Generate the CRD from the API types and their +kubebuilder markers
+go run -mod=vendor "${CONTROLLER_GEN}" crd paths="${APIS_PATHS}" output:crd:dir="${OUTPUT_DIR}"
+
+# The Collector CRD serves two versions, which the conversion webhook converts between. Its CA bundle isn't known
+# here, cert-manager injects the CA of the webhook serving certificate named by the inject-ca-from annotation
+cat > "${OUTPUT_DIR}/annotations.yaml" <<EOF
+    cert-manager.io/inject-ca-from: ${WEBHOOK_SERVICE_NAMESPACE}/${WEBHOOK_SERVICE_NAME}
+EOF
+
+cat > "${OUTPUT_DIR}/conversion.yaml" <<EOF
+  conversion:
+    strategy: Webhook
+    webhook:
+      clientConfig:
+        service:
+          name: ${WEBHOOK_SERVICE_NAME}
+          namespace: ${WEBHOOK_SERVICE_NAMESPACE}
+          path: /convert
+          port: 443
+      conversionReviewVersions:
+      - v1
+EOF
+
+sed -e "/^  annotations:$/r ${OUTPUT_DIR}/annotations.yaml" -e "/^spec:$/r ${OUTPUT_DIR}/conversion.yaml" \
+  "${OUTPUT_DIR}/example.com_collectors.yaml" > "${CRD_DIR}/crd.yaml"
+cp -f "${OUTPUT_DIR}/example.com_tenants.yaml" "${CRD_DIR}/tenant_crd.yaml"
+cp -f "${OUTPUT_DIR}/example.com_collectorsets.yaml" "${CRD_DIR}/collectorset_crd.yaml"
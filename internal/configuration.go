@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -10,7 +11,34 @@ import (
 
 // Configuration is the amalgamation of various configurations that may be needed.
 type Configuration struct {
-	Environment string `mapstructure:"environment"`
+	Environment              string        `mapstructure:"environment"`
+	Namespace                string        `mapstructure:"namespace"`
+	Workers                  int           `mapstructure:"workers"`
+	KeepReleaseHistory       bool          `mapstructure:"keep-release-history"`
+	HelmStorageDriver        string        `mapstructure:"helm-storage-driver"`
+	ReleaseTimeout           time.Duration `mapstructure:"release-timeout"`
+	ChartSourcesConfig       string        `mapstructure:"chart-sources-config"`
+	ValuesSchemasDir         string        `mapstructure:"values-schemas-dir"`
+	ChartCacheEntries        int           `mapstructure:"chart-cache-entries"`
+	ChartCacheBytes          int64         `mapstructure:"chart-cache-bytes"`
+	ChartCacheTTL            time.Duration `mapstructure:"chart-cache-ttl"`
+	VersionCacheTTL          time.Duration `mapstructure:"version-cache-ttl"`
+	LeaderElect              bool          `mapstructure:"leader-elect"`
+	LeaderElectLeaseName     string        `mapstructure:"leader-elect-lease-name"`
+	LeaderElectIdentity      string        `mapstructure:"leader-elect-identity"`
+	LeaderElectLeaseDuration time.Duration `mapstructure:"leader-elect-lease-duration"`
+	LeaderElectRenewDeadline time.Duration `mapstructure:"leader-elect-renew-deadline"`
+	LeaderElectRetryPeriod   time.Duration `mapstructure:"leader-elect-retry-period"`
+	ShutdownGracePeriod      time.Duration `mapstructure:"shutdown-grace-period"`
+	DriftCheckInterval       time.Duration `mapstructure:"drift-check-interval"`
+	WebhookPort              int           `mapstructure:"webhook-port"`
+	WebhookCertDir           string        `mapstructure:"webhook-cert-dir"`
+	DefaultCluster           string        `mapstructure:"default-cluster"`
+	DefaultCollectorVersion  string        `mapstructure:"default-collector-version"`
+	InstallCRD               bool          `mapstructure:"install-crd"`
+	WebhookServiceName       string        `mapstructure:"webhook-service-name"`
+	// HelmSQLConnectionString is read from HELM_DRIVER_SQL_CONNECTION_STRING so that it doesn't show up in the process arguments.
+	HelmSQLConnectionString string `mapstructure:"helm-sql-connection-string"`
 }
 
 func (c Configuration) Kubeconfig() (*rest.Config, error) {
@@ -0,0 +1,127 @@
+package operator
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChartArchive is a packaged chart as fetched from a chart source.
+type ChartArchive struct {
+	// Data is the content of the .tgz archive.
+	Data []byte
+	// Digest is the hex encoded SHA-256 digest the source publishes for the archive, empty if it doesn't publish one.
+	Digest string
+	// ManifestDigest is the digest of the OCI manifest the chart was pulled with, only set for OCI sources.
+	ManifestDigest string
+}
+
+// verify checks the archive against the digest published by the source.
+func (a *ChartArchive) verify() error {
+	if a.Digest == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(a.Data)
+
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, a.Digest) {
+		return fmt.Errorf("chart digest mismatch: published sha256:%s, downloaded sha256:%s", a.Digest, actual)
+	}
+
+	return nil
+}
+
+// ChartCache is an in-memory LRU cache of chart archives keyed by source, name and version, so that charts are
+// not downloaded again on every reconcile. The cache is bounded by the number of charts and their total size.
+type ChartCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+	size       int64
+	lru        *list.List
+	entries    map[string]*list.Element
+}
+
+// chartCacheEntry is a cached chart archive.
+type chartCacheEntry struct {
+	key     string
+	archive *ChartArchive
+	added   time.Time
+}
+
+// NewChartCache creates a chart cache holding at most maxEntries charts and maxBytes bytes of archives.
+// Entries older than ttl are fetched again, a ttl of zero keeps them until they are evicted.
+// A maxEntries of zero or less disables the cache.
+func NewChartCache(maxEntries int, maxBytes int64, ttl time.Duration) *ChartCache {
+	return &ChartCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// chartCacheKey returns the cache key of a chart in a source.
+func chartCacheKey(source string, name string, version string) string {
+	return source + "/" + name + "@" + version
+}
+
+// Get returns the cached archive of the key, if it is present and hasn't expired.
+func (c *ChartCache) Get(key string) (*ChartArchive, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*chartCacheEntry) // nolint: forcetypeassert
+	if c.ttl > 0 && time.Since(entry.added) > c.ttl {
+		c.remove(element)
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+
+	return entry.archive, true
+}
+
+// Add caches the archive under the key, evicting the least recently used archives to stay within the limits.
+// Archives larger than the whole cache are not cached.
+func (c *ChartCache) Add(key string, archive *ChartArchive) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxEntries <= 0 || (c.maxBytes > 0 && int64(len(archive.Data)) > c.maxBytes) {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	c.entries[key] = c.lru.PushFront(&chartCacheEntry{key: key, archive: archive, added: time.Now()})
+	c.size += int64(len(archive.Data))
+
+	for c.lru.Len() > c.maxEntries || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops the element from the cache, the lock must be held.
+func (c *ChartCache) remove(element *list.Element) {
+	entry := element.Value.(*chartCacheEntry) // nolint: forcetypeassert
+
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.archive.Data))
+}
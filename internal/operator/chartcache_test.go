@@ -0,0 +1,147 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChartCacheEviction(t *testing.T) {
+	archive := func(size int) *ChartArchive {
+		return &ChartArchive{Data: make([]byte, size)}
+	}
+
+	tests := []struct {
+		name       string
+		maxEntries int
+		maxBytes   int64
+		// add are the keys added in order, a key prefixed with ? is read instead
+		add     []string
+		sizes   map[string]int
+		present []string
+		absent  []string
+	}{
+		{
+			name:       "least recently added is evicted past the entry limit",
+			maxEntries: 2,
+			add:        []string{"a", "b", "c"},
+			present:    []string{"b", "c"},
+			absent:     []string{"a"},
+		},
+		{
+			name:       "a read keeps an archive from being evicted",
+			maxEntries: 2,
+			add:        []string{"a", "b", "?a", "c"},
+			present:    []string{"a", "c"},
+			absent:     []string{"b"},
+		},
+		{
+			name:       "archives are evicted past the byte limit",
+			maxEntries: 10,
+			maxBytes:   10,
+			add:        []string{"a", "b", "c"},
+			sizes:      map[string]int{"a": 4, "b": 4, "c": 4},
+			present:    []string{"b", "c"},
+			absent:     []string{"a"},
+		},
+		{
+			name:       "an archive larger than the cache isn't cached",
+			maxEntries: 10,
+			maxBytes:   10,
+			add:        []string{"a", "b"},
+			sizes:      map[string]int{"a": 4, "b": 11},
+			present:    []string{"a"},
+			absent:     []string{"b"},
+		},
+		{
+			name:       "adding a key again replaces it",
+			maxEntries: 2,
+			maxBytes:   10,
+			add:        []string{"a", "a", "b"},
+			sizes:      map[string]int{"a": 5, "b": 5},
+			present:    []string{"a", "b"},
+		},
+		{
+			name:       "zero entries disables the cache",
+			maxEntries: 0,
+			add:        []string{"a"},
+			absent:     []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewChartCache(tt.maxEntries, tt.maxBytes, 0)
+
+			for _, key := range tt.add {
+				if strings.HasPrefix(key, "?") {
+					cache.Get(strings.TrimPrefix(key, "?"))
+
+					continue
+				}
+
+				cache.Add(key, archive(tt.sizes[key]))
+			}
+
+			for _, key := range tt.present {
+				if _, ok := cache.Get(key); !ok {
+					t.Errorf("%s was evicted", key)
+				}
+			}
+
+			for _, key := range tt.absent {
+				if _, ok := cache.Get(key); ok {
+					t.Errorf("%s is cached", key)
+				}
+			}
+		})
+	}
+}
+
+func TestChartCacheTTL(t *testing.T) {
+	cache := NewChartCache(10, 0, time.Minute)
+	cache.Add("a", &ChartArchive{Data: []byte("chart")})
+
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a fresh archive isn't cached")
+	}
+
+	cache.entries["a"].Value.(*chartCacheEntry).added = time.Now().Add(-2 * time.Minute)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Error("an expired archive is still cached")
+	}
+
+	if cache.size != 0 || cache.lru.Len() != 0 {
+		t.Errorf("an expired archive is still accounted for: %d bytes, %d entries", cache.size, cache.lru.Len())
+	}
+}
+
+func TestChartArchiveVerify(t *testing.T) {
+	data := []byte("chart archive")
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		digest  string
+		wantErr bool
+	}{
+		{name: "no published digest", digest: ""},
+		{name: "matching digest", digest: digest},
+		{name: "digest case doesn't matter", digest: strings.ToUpper(digest)},
+		{name: "mismatching digest", digest: strings.Repeat("0", len(digest)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := &ChartArchive{Data: data, Digest: tt.digest}
+
+			if err := archive.verify(); (err != nil) != tt.wantErr {
+				t.Errorf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// Chart source types that can be configured.
+const (
+	ChartSourceS3        = "s3"
+	ChartSourceOCI       = "oci"
+	ChartSourceHTTP      = "http"
+	ChartSourceDirectory = "directory"
+)
+
+// chartArchiveExtension is the extension of packaged charts.
+const chartArchiveExtension = ".tgz"
+
+// ChartSource retrieves collector charts from where they are published.
+type ChartSource interface {
+	// Fetch returns the packaged chart archive (.tgz) of the named chart at the given version,
+	// along with the digest the source publishes for it.
+	Fetch(ctx context.Context, name string, version string) (*ChartArchive, error)
+	// Versions lists the published versions of the named chart.
+	Versions(ctx context.Context, name string) ([]string, error)
+	// Provenance returns the Helm provenance (.prov) file published alongside the chart.
+	Provenance(ctx context.Context, name string, version string) ([]byte, error)
+}
+
+// ChartSourcesConfig is the configuration file of the chart sources the operator can install collectors from.
+type ChartSourcesConfig struct {
+	// Sources are the chart sources by name.
+	Sources map[string]ChartSourceConfig `yaml:"sources"`
+	// Clusters maps a Spec.Cluster value to the name of the chart source used for it. The validating webhook
+	// only admits collectors for the clusters listed here.
+	Clusters map[string]string `yaml:"clusters"`
+	// Default is the name of the chart source used when neither the collector nor its cluster selects one.
+	Default string `yaml:"default"`
+}
+
+// ChartSourceConfig configures a single chart source. Only the block matching Type is used.
+type ChartSourceConfig struct {
+	Type      string               `yaml:"type"`
+	S3        S3ChartSource        `yaml:"s3"`
+	OCI       OCIChartSource       `yaml:"oci"`
+	HTTP      HTTPChartSource      `yaml:"http"`
+	Directory DirectoryChartSource `yaml:"directory"`
+	// GitHubReleases, when set, resolves the latest version of a chart to the tag of the latest GitHub release
+	// of {owner}/{chart name} rather than to the newest version published in the source.
+	GitHubReleases *GitHubReleases `yaml:"githubReleases"`
+	// Verification optionally verifies the provenance or signature of the charts before they are installed.
+	Verification *ChartVerification `yaml:"verification"`
+}
+
+// GitHubReleases identifies the GitHub owner whose repositories publish releases of the collector charts.
+type GitHubReleases struct {
+	Owner string `yaml:"owner"`
+	// Token authenticates the GitHub API requests, which are anonymous and heavily rate limited without one.
+	Token *Credential `yaml:"token"`
+}
+
+// configuredChartSource is a chart source along with the name and options it was configured with.
+type configuredChartSource struct {
+	ChartSource
+	name           string
+	githubReleases *GitHubReleases
+	verification   *ChartVerification
+	secrets        *SecretStore
+}
+
+// defaultChartSourcesConfig reproduces the historical behaviour: development collectors come from the
+// development-helm bucket and everything else from the production-helm bucket.
+func defaultChartSourcesConfig() ChartSourcesConfig {
+	return ChartSourcesConfig{
+		Sources: map[string]ChartSourceConfig{
+			"development": {Type: ChartSourceS3, S3: S3ChartSource{Bucket: "development-helm", Region: "us-west-2", Prefix: "charts/"}},
+			"production": {
+				Type:           ChartSourceS3,
+				S3:             S3ChartSource{Bucket: "production-helm", Region: "us-west-2", Prefix: "charts/"},
+				GitHubReleases: &GitHubReleases{Owner: "rmschick", Token: &Credential{Env: "GITHUB_TOKEN"}},
+			},
+		},
+		Clusters: map[string]string{"development": "development", "production": "production"},
+		Default:  "production",
+	}
+}
+
+// LoadChartSourcesConfig reads the chart sources configuration file. An empty path returns the default configuration.
+func LoadChartSourcesConfig(path string) (ChartSourcesConfig, error) {
+	if path == "" {
+		return defaultChartSourcesConfig(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ChartSourcesConfig{}, fmt.Errorf("could not read chart sources config: %w", err)
+	}
+
+	config := ChartSourcesConfig{}
+
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return ChartSourcesConfig{}, fmt.Errorf("could not unmarshal chart sources config: %w", err)
+	}
+
+	return config, nil
+}
+
+// ChartSources selects the chart source of a collector, either the one named by the collector itself,
+// the one configured for its cluster, or the default one.
+type ChartSources struct {
+	sources       map[string]*configuredChartSource
+	clusters      map[string]string
+	defaultSource string
+	cache         *ChartCache
+	versions      *VersionCache
+}
+
+// NewChartSources creates the chart sources described by the configuration. Fetched charts are kept in the cache and
+// published versions in the version cache, credentials stored in Secrets are read from the secret store.
+func NewChartSources(config ChartSourcesConfig, cache *ChartCache, versions *VersionCache, secrets *SecretStore) (*ChartSources, error) {
+	sources := make(map[string]*configuredChartSource, len(config.Sources))
+
+	for name, sourceConfig := range config.Sources {
+		source, err := newChartSource(sourceConfig, secrets)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chart source %q: %w", name, err)
+		}
+
+		if sourceConfig.GitHubReleases != nil && sourceConfig.GitHubReleases.Token != nil {
+			if err = sourceConfig.GitHubReleases.Token.validate(secrets); err != nil {
+				return nil, fmt.Errorf("chart source %q: invalid GitHub token: %w", name, err)
+			}
+		}
+
+		if sourceConfig.Verification != nil && sourceConfig.Verification.CosignPublicKey != "" && sourceConfig.Type != ChartSourceOCI {
+			return nil, fmt.Errorf("chart source %q: cosign signatures are only supported for %s sources", name, ChartSourceOCI)
+		}
+
+		sources[name] = &configuredChartSource{
+			ChartSource:    source,
+			name:           name,
+			githubReleases: sourceConfig.GitHubReleases,
+			verification:   sourceConfig.Verification,
+			secrets:        secrets,
+		}
+	}
+
+	for cluster, name := range config.Clusters {
+		if _, ok := sources[name]; !ok {
+			return nil, fmt.Errorf("cluster %q uses unknown chart source %q", cluster, name)
+		}
+	}
+
+	if _, ok := sources[config.Default]; config.Default != "" && !ok {
+		return nil, fmt.Errorf("unknown default chart source %q", config.Default)
+	}
+
+	return &ChartSources{sources: sources, clusters: config.Clusters, defaultSource: config.Default, cache: cache, versions: versions}, nil
+}
+
+// newChartSource creates the chart source of the configured type.
+// nolint: ireturn
+func newChartSource(config ChartSourceConfig, secrets *SecretStore) (ChartSource, error) {
+	switch config.Type {
+	case ChartSourceS3:
+		source := config.S3
+
+		provider, err := source.Credentials.provider(source.Region, secrets)
+		if err != nil {
+			return nil, err
+		}
+
+		source.credentialsProvider = provider
+
+		return &source, nil
+	case ChartSourceOCI:
+		source := config.OCI
+
+		return &source, nil
+	case ChartSourceHTTP:
+		source := config.HTTP
+
+		return &source, nil
+	case ChartSourceDirectory:
+		source := config.Directory
+
+		return &source, nil
+	default:
+		return nil, fmt.Errorf("unknown chart source type %q", config.Type)
+	}
+}
+
+// For returns the chart source used for the collector.
+func (c *ChartSources) For(resource *v1alpha.Collector) (*configuredChartSource, error) {
+	name := resource.Spec.ChartSource
+	if name == "" {
+		name = c.clusters[resource.Spec.Cluster]
+	}
+
+	if name == "" {
+		name = c.defaultSource
+	}
+
+	source, ok := c.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("no chart source %q for collector %s (cluster %q)", name, resource.Name, resource.Spec.Cluster)
+	}
+
+	return source, nil
+}
+
+// Fetch returns the chart archive from the cache, or fetches it from the source and verifies it against
+// the digest published by the source, and its provenance or signature when configured, before caching it.
+func (c *ChartSources) Fetch(ctx context.Context, source *configuredChartSource, name string, version string) (*ChartArchive, error) {
+	key := chartCacheKey(source.name, name, version)
+
+	if archive, ok := c.cache.Get(key); ok {
+		return archive, nil
+	}
+
+	archive, err := source.Fetch(ctx, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch chart from source %s: %w", source.name, err)
+	}
+
+	if err = archive.verify(); err != nil {
+		return nil, fmt.Errorf("chart %s-%s from source %s: %w", name, version, source.name, err)
+	}
+
+	if err = source.verify(ctx, name, version, archive); err != nil {
+		return nil, err
+	}
+
+	c.cache.Add(key, archive)
+
+	return archive, nil
+}
@@ -0,0 +1,66 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirectoryChartSource reads charts from a local directory as {name}-{version}.tgz, for tests and air-gapped clusters.
+type DirectoryChartSource struct {
+	Path string `yaml:"path"`
+}
+
+// Fetch reads the chart archive from the directory. The published digest is read from a {name}-{version}.tgz.sha256
+// file next to the archive, in the format written by sha256sum, if there is one.
+func (d *DirectoryChartSource) Fetch(_ context.Context, name string, version string) (*ChartArchive, error) {
+	path := filepath.Join(d.Path, filepath.Base(name+"-"+version+chartArchiveExtension))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read chart %s-%s: %w", name, version, err)
+	}
+
+	archive := &ChartArchive{Data: data}
+
+	checksum, err := os.ReadFile(path + ".sha256")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read digest of chart %s-%s: %w", name, version, err)
+	}
+
+	if fields := strings.Fields(string(checksum)); len(fields) > 0 {
+		archive.Digest = fields[0]
+	}
+
+	return archive, nil
+}
+
+// Provenance reads the {name}-{version}.tgz.prov file next to the archive.
+func (d *DirectoryChartSource) Provenance(_ context.Context, name string, version string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(d.Path, filepath.Base(name+"-"+version+chartArchiveExtension+provenanceExtension)))
+	if err != nil {
+		return nil, fmt.Errorf("could not read provenance of chart %s-%s: %w", name, version, err)
+	}
+
+	return data, nil
+}
+
+// Versions lists the versions of the chart in the directory.
+func (d *DirectoryChartSource) Versions(_ context.Context, name string) ([]string, error) {
+	prefix := filepath.Base(name) + "-"
+
+	archives, err := filepath.Glob(filepath.Join(d.Path, prefix+"*"+chartArchiveExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(archives))
+	for _, archive := range archives {
+		versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(filepath.Base(archive), prefix), chartArchiveExtension))
+	}
+
+	return versions, nil
+}
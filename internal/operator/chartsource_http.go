@@ -0,0 +1,126 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+
+	"kube8-operator/internal/instrumentation"
+)
+
+// HTTPChartSource fetches charts from a classic Helm chart repository, resolving them through its index.yaml.
+type HTTPChartSource struct {
+	// URL is the base URL of the repository, the one index.yaml is served under.
+	URL string `yaml:"url"`
+}
+
+// Fetch looks the chart up in the repository index and downloads the archive it points to.
+// The published digest is the one recorded in the index.
+func (h *HTTPChartSource) Fetch(ctx context.Context, name string, version string) (*ChartArchive, error) {
+	chartVersion, chartURL, err := h.chartURL(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.get(ctx, chartURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChartArchive{Data: data, Digest: chartVersion.Digest}, nil
+}
+
+// Provenance downloads the provenance file, which Helm repositories serve next to the chart archive.
+func (h *HTTPChartSource) Provenance(ctx context.Context, name string, version string) ([]byte, error) {
+	_, chartURL, err := h.chartURL(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.get(ctx, chartURL+provenanceExtension)
+}
+
+// chartURL looks the chart up in the repository index and returns its entry and download URL.
+func (h *HTTPChartSource) chartURL(ctx context.Context, name string, version string) (*repo.ChartVersion, string, error) {
+	index, err := h.index(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	chartVersion, err := index.Get(name, version)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not find chart %s-%s in %s: %w", name, version, h.URL, err)
+	}
+
+	if len(chartVersion.URLs) == 0 {
+		return nil, "", fmt.Errorf("chart %s-%s in %s has no download URL", name, version, h.URL)
+	}
+
+	// The URLs in an index may be relative to the repository
+	chartURL, err := repo.ResolveReferenceURL(h.URL, chartVersion.URLs[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return chartVersion, chartURL, nil
+}
+
+// Versions lists the versions of the chart in the repository index.
+func (h *HTTPChartSource) Versions(ctx context.Context, name string) ([]string, error) {
+	index, err := h.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chartVersions := index.Entries[name]
+
+	versions := make([]string, 0, len(chartVersions))
+	for _, chartVersion := range chartVersions {
+		versions = append(versions, chartVersion.Version)
+	}
+
+	return versions, nil
+}
+
+// index downloads and parses the index.yaml of the repository.
+func (h *HTTPChartSource) index(ctx context.Context) (*repo.IndexFile, error) {
+	data, err := h.get(ctx, strings.TrimSuffix(h.URL, "/")+"/index.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	index := &repo.IndexFile{}
+
+	if err = yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("could not unmarshal index of %s: %w", h.URL, err)
+	}
+
+	index.SortEntries()
+
+	return index, nil
+}
+
+// get downloads the given URL.
+func (h *HTTPChartSource) get(ctx context.Context, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := instrumentation.InstrumentHTTPClient(&http.Client{}).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s: %w", url, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not get %s: %s", url, response.Status)
+	}
+
+	return io.ReadAll(response.Body)
+}
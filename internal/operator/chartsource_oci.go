@@ -0,0 +1,126 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	dockerauth "oras.land/oras-go/pkg/auth/docker"
+	"oras.land/oras-go/pkg/registry/remote/auth"
+
+	"kube8-operator/internal/instrumentation"
+)
+
+// OCIChartSource fetches charts pushed to an OCI registry with `helm push`, as {repository}/{name}:{version}.
+type OCIChartSource struct {
+	// Repository is the registry and path the charts are pushed to, e.g. oci://registry.example.com/charts.
+	Repository string `yaml:"repository"`
+	// CredentialsFile is the Docker config file holding the credentials of the registry, the Helm registry config
+	// ($HELM_REGISTRY_CONFIG) by default. The Docker config of the operator is used as a fallback.
+	CredentialsFile string `yaml:"credentialsFile"`
+}
+
+// Fetch pulls the chart layer from the registry. The published digest is the digest of the chart layer.
+func (o *OCIChartSource) Fetch(_ context.Context, name string, version string) (*ChartArchive, error) {
+	registryClient, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := registryClient.Pull(o.reference(name) + ":" + version)
+	if err != nil {
+		return nil, fmt.Errorf("could not pull chart %s:%s: %w", o.reference(name), version, err)
+	}
+
+	return &ChartArchive{
+		Data:           result.Chart.Data,
+		Digest:         strings.TrimPrefix(result.Chart.Digest, "sha256:"),
+		ManifestDigest: result.Manifest.Digest,
+	}, nil
+}
+
+// Provenance pulls the provenance layer of the chart from the registry.
+func (o *OCIChartSource) Provenance(_ context.Context, name string, version string) ([]byte, error) {
+	registryClient, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := registryClient.Pull(o.reference(name)+":"+version, registry.PullOptWithChart(false), registry.PullOptWithProv(true))
+	if err != nil {
+		return nil, fmt.Errorf("could not pull provenance of chart %s:%s: %w", o.reference(name), version, err)
+	}
+
+	return result.Prov.Data, nil
+}
+
+// Versions lists the semver tags of the chart in the registry.
+func (o *OCIChartSource) Versions(_ context.Context, name string) ([]string, error) {
+	registryClient, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := registryClient.Tags(o.reference(name))
+	if err != nil {
+		return nil, fmt.Errorf("could not list tags of %s: %w", o.reference(name), err)
+	}
+
+	return tags, nil
+}
+
+// client creates a registry client, authenticated with the registry credentials of the source.
+func (o *OCIChartSource) client() (*registry.Client, error) {
+	registryClient, err := registry.NewClient(
+		registry.ClientOptHTTPClient(instrumentation.InstrumentHTTPClient(&http.Client{})),
+		registry.ClientOptCredentialsFile(o.credentialsFile()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create registry client: %w", err)
+	}
+
+	return registryClient, nil
+}
+
+// credentialsFile returns the path of the Docker config file holding the credentials of the registry.
+func (o *OCIChartSource) credentialsFile() string {
+	if o.CredentialsFile != "" {
+		return o.CredentialsFile
+	}
+
+	return cli.New().RegistryConfig
+}
+
+// credential returns the credential of the registry host, read from the credentials file the way the Helm
+// registry client reads it, so that requests made outside of it authenticate the same way.
+func (o *OCIChartSource) credential(_ context.Context, host string) (auth.Credential, error) {
+	authClient, err := dockerauth.NewClientWithDockerFallback(o.credentialsFile())
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("could not load registry credentials: %w", err)
+	}
+
+	dockerClient, ok := authClient.(*dockerauth.Client)
+	if !ok {
+		return auth.EmptyCredential, fmt.Errorf("unexpected registry credentials client %T", authClient)
+	}
+
+	username, password, err := dockerClient.Credential(host)
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("could not get the credentials of registry %s: %w", host, err)
+	}
+
+	// A password without a username is an identity token
+	if username == "" && password != "" {
+		return auth.Credential{RefreshToken: password}, nil
+	}
+
+	return auth.Credential{Username: username, Password: password}, nil
+}
+
+// reference returns the registry reference of the named chart, without a tag.
+func (o *OCIChartSource) reference(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(o.Repository, registry.OCIScheme+"://"), "/") + "/" + name
+}
@@ -0,0 +1,142 @@
+package operator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"kube8-operator/internal/instrumentation"
+)
+
+// S3ChartSource fetches charts from an S3 bucket, stored as {prefix}{name}-{version}.tgz.
+// Setting Endpoint allows S3 compatible stores such as MinIO.
+type S3ChartSource struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	Prefix string `yaml:"prefix"`
+	// Endpoint overrides the AWS endpoint, e.g. http://minio.minio.svc:9000.
+	Endpoint string `yaml:"endpoint"`
+	// UsePathStyle addresses the bucket in the path rather than the host name, which most S3 compatible stores require.
+	UsePathStyle bool `yaml:"usePathStyle"`
+	// Credentials selects how requests to the bucket are authenticated, the default credential chain when unset.
+	Credentials AWSCredentials `yaml:"credentials"`
+
+	credentialsProvider aws.CredentialsProvider
+}
+
+// Fetch downloads the chart archive from the bucket. The published digest is the SHA-256 checksum of the object,
+// which S3 only has when the chart was uploaded with one.
+func (s *S3ChartSource) Fetch(ctx context.Context, name string, version string) (*ChartArchive, error) {
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the collector chart file from aws bucket
+	object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:       aws.String(s.Bucket),
+		Key:          aws.String(s.Prefix + name + "-" + version + chartArchiveExtension),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get chart %s-%s from bucket %s: %w", name, version, s.Bucket, err)
+	}
+	defer object.Body.Close()
+
+	data, err := io.ReadAll(object.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &ChartArchive{Data: data}
+
+	// The checksum of a multipart upload is a checksum of the part checksums, not of the archive
+	checksum := aws.ToString(object.ChecksumSHA256)
+	if checksum != "" && object.ChecksumType != types.ChecksumTypeComposite && !strings.Contains(checksum, "-") {
+		digest, err := base64.StdEncoding.DecodeString(checksum)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum of chart %s-%s: %w", name, version, err)
+		}
+
+		archive.Digest = hex.EncodeToString(digest)
+	}
+
+	return archive, nil
+}
+
+// Provenance downloads the {prefix}{name}-{version}.tgz.prov file from the bucket.
+func (s *S3ChartSource) Provenance(ctx context.Context, name string, version string) ([]byte, error) {
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Prefix + name + "-" + version + chartArchiveExtension + provenanceExtension),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get provenance of chart %s-%s from bucket %s: %w", name, version, s.Bucket, err)
+	}
+	defer object.Body.Close()
+
+	return io.ReadAll(object.Body)
+}
+
+// Versions lists the versions of the chart in the bucket.
+func (s *S3ChartSource) Versions(ctx context.Context, name string) ([]string, error) {
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := s.Prefix + name + "-"
+
+	var versions []string
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not list charts %s in bucket %s: %w", name, s.Bucket, err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, chartArchiveExtension) {
+				versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(key, prefix), chartArchiveExtension))
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// client creates an S3 client for the configured region and endpoint, authenticated with the configured credentials.
+func (s *S3ChartSource) client(ctx context.Context) (*s3.Client, error) {
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(s.credentialsProvider), config.WithRegion(s.Region), config.WithHTTPClient(instrumentation.InstrumentHTTPClient(&http.Client{})))
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(awsConfig, func(options *s3.Options) {
+		if s.Endpoint != "" {
+			options.BaseEndpoint = aws.String(s.Endpoint)
+		}
+
+		options.UsePathStyle = s.UsePathStyle
+	}), nil
+}
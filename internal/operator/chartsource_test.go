@@ -0,0 +1,257 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+func TestChartSourcesFor(t *testing.T) {
+	sources, err := NewChartSources(ChartSourcesConfig{
+		Sources: map[string]ChartSourceConfig{
+			"production": {Type: ChartSourceDirectory},
+			"staging":    {Type: ChartSourceDirectory},
+			"mirror":     {Type: ChartSourceHTTP},
+		},
+		Clusters: map[string]string{"staging": "staging"},
+		Default:  "production",
+	}, NewChartCache(0, 0, 0), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		cluster     string
+		chartSource string
+		want        string
+		wantErr     bool
+	}{
+		{name: "source of the collector", cluster: "staging", chartSource: "mirror", want: "mirror"},
+		{name: "source of the cluster", cluster: "staging", want: "staging"},
+		{name: "default source", cluster: "eu-west-1", want: "production"},
+		{name: "unknown source", chartSource: "archive", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &v1alpha.Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs"},
+				Spec:       v1alpha.CollectorSpec{Cluster: tt.cluster, ChartSource: tt.chartSource},
+			}
+
+			source, err := sources.For(collector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("For() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && source.name != tt.want {
+				t.Errorf("For() = %s, want %s", source.name, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewChartSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ChartSourcesConfig
+		wantErr bool
+	}{
+		{name: "valid", config: ChartSourcesConfig{Sources: map[string]ChartSourceConfig{"local": {Type: ChartSourceDirectory}}, Clusters: map[string]string{"dev": "local"}, Default: "local"}},
+		{name: "unknown source type", config: ChartSourcesConfig{Sources: map[string]ChartSourceConfig{"local": {Type: "git"}}}, wantErr: true},
+		{name: "cluster with an unknown source", config: ChartSourcesConfig{Sources: map[string]ChartSourceConfig{"local": {Type: ChartSourceDirectory}}, Clusters: map[string]string{"dev": "remote"}}, wantErr: true},
+		{name: "unknown default source", config: ChartSourcesConfig{Sources: map[string]ChartSourceConfig{"local": {Type: ChartSourceDirectory}}, Default: "remote"}, wantErr: true},
+		{
+			name:    "cosign signatures of a source that isn't OCI",
+			config:  ChartSourcesConfig{Sources: map[string]ChartSourceConfig{"local": {Type: ChartSourceDirectory, Verification: &ChartVerification{CosignPublicKey: "cosign.pub"}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewChartSources(tt.config, NewChartCache(0, 0, 0), nil, nil); (err != nil) != tt.wantErr {
+				t.Errorf("NewChartSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDirectoryChartSource(t *testing.T) {
+	dir := t.TempDir()
+	archive := []byte("fluent-bit chart")
+	digest := sha256.Sum256(archive)
+
+	files := map[string]string{
+		"fluent-bit-1.4.3.tgz":        string(archive),
+		"fluent-bit-1.4.3.tgz.sha256": hex.EncodeToString(digest[:]) + "  fluent-bit-1.4.3.tgz\n",
+		"fluent-bit-1.5.0.tgz":        "newer chart",
+		"fluentd-2.0.0.tgz":           "another chart",
+	}
+
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := &DirectoryChartSource{Path: dir}
+
+	versions, err := source.Versions(context.Background(), "fluent-bit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(versions, []string{"1.4.3", "1.5.0"}) {
+		t.Errorf("Versions() = %v, want [1.4.3 1.5.0]", versions)
+	}
+
+	tests := []struct {
+		name       string
+		version    string
+		wantDigest string
+		wantErr    bool
+	}{
+		{name: "archive with a digest", version: "1.4.3", wantDigest: hex.EncodeToString(digest[:])},
+		{name: "archive without a digest", version: "1.5.0"},
+		{name: "missing archive", version: "2.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched, err := source.Fetch(context.Background(), "fluent-bit", tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && fetched.Digest != tt.wantDigest {
+				t.Errorf("Fetch() digest = %q, want %q", fetched.Digest, tt.wantDigest)
+			}
+		})
+	}
+}
+
+func TestHTTPChartSource(t *testing.T) {
+	archive := []byte("fluent-bit chart")
+	digest := sha256.Sum256(archive)
+	index := `apiVersion: v1
+entries:
+  fluent-bit:
+  - name: fluent-bit
+    version: 1.4.3
+    digest: ` + hex.EncodeToString(digest[:]) + `
+    urls: [charts/fluent-bit-1.4.3.tgz]
+  - name: fluent-bit
+    version: 1.5.0
+    urls: []
+`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repo/index.yaml":
+			_, _ = w.Write([]byte(index))
+		case "/repo/charts/fluent-bit-1.4.3.tgz":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := &HTTPChartSource{URL: server.URL + "/repo/"}
+
+	versions, err := source.Versions(context.Background(), "fluent-bit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(versions, []string{"1.5.0", "1.4.3"}) {
+		t.Errorf("Versions() = %v, want [1.5.0 1.4.3]", versions)
+	}
+
+	tests := []struct {
+		name    string
+		chart   string
+		version string
+		wantErr bool
+	}{
+		{name: "archive at a relative URL", chart: "fluent-bit", version: "1.4.3"},
+		{name: "version without a download URL", chart: "fluent-bit", version: "1.5.0", wantErr: true},
+		{name: "chart that isn't in the index", chart: "fluentd", version: "1.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched, err := source.Fetch(context.Background(), tt.chart, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && (string(fetched.Data) != string(archive) || fetched.Digest != hex.EncodeToString(digest[:])) {
+				t.Errorf("Fetch() = %q with digest %q, want the archive and the digest of the index", fetched.Data, fetched.Digest)
+			}
+		})
+	}
+
+	if _, err = (&HTTPChartSource{URL: server.URL + "/missing"}).Versions(context.Background(), "fluent-bit"); err == nil {
+		t.Errorf("Versions() of a repository without an index succeeded")
+	}
+}
+
+func TestChartSourcesFetch(t *testing.T) {
+	archive := []byte("fluent-bit chart")
+	digest := sha256.Sum256(archive)
+
+	tests := []struct {
+		name      string
+		digest    string
+		wantErr   bool
+		wantCache bool
+	}{
+		{name: "archive matching its digest is cached", digest: hex.EncodeToString(digest[:]), wantCache: true},
+		{name: "archive without a digest is cached", wantCache: true},
+		{name: "archive that doesn't match its digest is rejected", digest: hex.EncodeToString(make([]byte, sha256.Size)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "fluent-bit-1.4.3.tgz"), archive, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.digest != "" {
+				if err := os.WriteFile(filepath.Join(dir, "fluent-bit-1.4.3.tgz.sha256"), []byte(tt.digest), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sources, err := NewChartSources(ChartSourcesConfig{
+				Sources: map[string]ChartSourceConfig{"local": {Type: ChartSourceDirectory, Directory: DirectoryChartSource{Path: dir}}},
+				Default: "local",
+			}, NewChartCache(10, 0, 0), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = sources.Fetch(context.Background(), sources.sources["local"], "fluent-bit", "1.4.3"); (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, cached := sources.cache.Get(chartCacheKey("local", "fluent-bit", "1.4.3")); cached != tt.wantCache {
+				t.Errorf("cached = %v, want %v", cached, tt.wantCache)
+			}
+		})
+	}
+}
@@ -0,0 +1,621 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+	applyv1 "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+)
+
+const (
+	// LabelCollectorSet is put on the Collectors of a CollectorSet, with the name of the CollectorSet.
+	LabelCollectorSet = v1alpha.GroupName + "/collector-set"
+	// collectorSetHashAnnotation is the hash of the template a Collector of a CollectorSet was last written from,
+	// so that Collectors that match the template aren't written again.
+	collectorSetHashAnnotation = v1alpha.GroupName + "/collector-set-hash"
+	// collectorSetIndex indexes collectors by the namespace/name key of the CollectorSet controlling them.
+	collectorSetIndex = "collectorSet"
+	// maxNotAvailableCollectors is the number of Collectors that aren't available listed in the Ready condition.
+	maxNotAvailableCollectors = 5
+)
+
+// CollectorSet condition reasons.
+const (
+	reasonCollectorsAvailable    = "CollectorsAvailable"
+	reasonCollectorsNotAvailable = "CollectorsNotAvailable"
+	reasonCollectorsSynced       = "CollectorsSynced"
+	reasonCollectorsSyncFailed   = "CollectorsSyncFailed"
+	reasonCollectorCreated       = "CollectorCreated"
+	reasonCollectorDeleted       = "CollectorDeleted"
+)
+
+// collectorSetTenant is a tenant a CollectorSet deploys its collector for.
+type collectorSetTenant struct {
+	name      string
+	namespace string
+	id        string
+	values    map[string]interface{}
+}
+
+// collectorSetChildName returns the name of the Collector of the CollectorSet for the tenant, {set}-{tenant}.
+func collectorSetChildName(set *v1alpha.CollectorSet, tenant string) string {
+	return set.Name + "-" + tenant
+}
+
+// collectorSetOwnerIndex indexes a collector by the CollectorSet controlling it, other collectors aren't indexed.
+func collectorSetOwnerIndex(obj interface{}) ([]string, error) {
+	collector, ok := obj.(*v1alpha.Collector)
+	if !ok {
+		return nil, fmt.Errorf("expected Collector but got %T", obj)
+	}
+
+	owner := metav1.GetControllerOf(collector)
+	if owner == nil || owner.APIVersion != v1alpha.SchemeGroupVersion.String() || owner.Kind != v1alpha.CollectorSetKind {
+		return nil, nil
+	}
+
+	return []string{collector.Namespace + "/" + owner.Name}, nil
+}
+
+// enqueueCollectorSet queues the namespace/name key of the CollectorSet.
+func (c *Controller) enqueueCollectorSet(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+
+		return
+	}
+
+	c.collectorSetQueue.Add(key)
+}
+
+// collectorSetEventHandler queues a CollectorSet whenever it changes, periodic resyncs included.
+func (c *Controller) collectorSetEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueCollectorSet,
+		UpdateFunc: func(_, newObject interface{}) { c.enqueueCollectorSet(newObject) },
+	}
+}
+
+// collectorSetCollectorEventHandler queues the CollectorSet of a Collector whenever the Collector changes, so that
+// its status follows that of its Collectors and a deleted Collector is created again.
+func (c *Controller) collectorSetCollectorEventHandler() cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+
+		keys, err := collectorSetOwnerIndex(obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			return
+		}
+
+		for _, key := range keys {
+			c.collectorSetQueue.Add(key)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			if oldObject.(*v1alpha.Collector).ResourceVersion != newObject.(*v1alpha.Collector).ResourceVersion {
+				enqueue(newObject)
+			}
+		},
+		DeleteFunc: enqueue,
+	}
+}
+
+// collectorSetTenantEventHandler queues every CollectorSet when a Tenant is added or deleted, or its labels or
+// namespace change, since the Tenant may be selected by any of them.
+func (c *Controller) collectorSetTenantEventHandler() cache.ResourceEventHandler {
+	enqueueAll := func(interface{}) {
+		for _, set := range c.collectorSetInformer.GetStore().List() {
+			c.enqueueCollectorSet(set)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueueAll,
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			oldTenant, newTenant := oldObject.(*v1alpha.Tenant), newObject.(*v1alpha.Tenant)
+
+			if equality.Semantic.DeepEqual(oldTenant.Labels, newTenant.Labels) && oldTenant.Status.Namespace == newTenant.Status.Namespace && oldTenant.Spec.ID == newTenant.Spec.ID {
+				return
+			}
+
+			enqueueAll(newObject)
+		},
+		DeleteFunc: enqueueAll,
+	}
+}
+
+// RunCollectorSetWorker processes CollectorSet keys off the collector set queue until it is shut down.
+func (c *Controller) RunCollectorSetWorker(ctx context.Context) {
+	for c.processNextCollectorSetItem(ctx) {
+	}
+}
+
+// processNextCollectorSetItem pops a single CollectorSet key off the collector set queue and syncs its Collectors.
+func (c *Controller) processNextCollectorSetItem(ctx context.Context) bool {
+	item, shutdown := c.collectorSetQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.collectorSetQueue.Done(item)
+
+	if c.collectorSetQueue.ShuttingDown() {
+		return false
+	}
+
+	key, ok := item.(string)
+	if !ok {
+		c.collectorSetQueue.Forget(item)
+		utilruntime.HandleError(fmt.Errorf("expected string in collector set queue but got %#v", item))
+
+		return true
+	}
+
+	if err := c.syncCollectorSet(ctx, key); err != nil {
+		c.collectorSetQueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing collector set %q, requeuing: %w", key, err))
+
+		return true
+	}
+
+	c.collectorSetQueue.Forget(key)
+
+	return true
+}
+
+// syncCollectorSet creates and updates a Collector of the CollectorSet for every tenant it selects, deletes its
+// Collectors of the tenants it doesn't select anymore and rolls their availability up into its status. The
+// Collectors are owned by the CollectorSet, they are garbage collected along with it.
+// nolint: funlen
+func (c *Controller) syncCollectorSet(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+
+		return nil
+	}
+
+	set, err := c.collectorSetLister.CollectorSets(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if set.DeletionTimestamp != nil {
+		return nil
+	}
+
+	tenants, err := c.collectorSetTenants(set)
+	if err != nil {
+		message := fmt.Sprintf("Could not select the tenants of the collector set: (%s)", err)
+
+		if statusErr := c.setCollectorSetStatus(ctx, set, func(status *v1alpha.CollectorSetStatus) {
+			meta.SetStatusCondition(&status.Conditions, collectorSetCondition(set, typeDegraded, metav1.ConditionTrue, reasonCollectorsSyncFailed, message))
+		}); statusErr != nil {
+			return statusErr
+		}
+
+		return err
+	}
+
+	children, err := c.informer.GetIndexer().ByIndex(collectorSetIndex, key)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*v1alpha.Collector, len(children))
+	for _, child := range children {
+		existing[child.(*v1alpha.Collector).Name] = child.(*v1alpha.Collector)
+	}
+
+	var (
+		syncErrs []error
+		desired  = make(map[string]string, len(tenants))
+	)
+
+	for _, tenant := range tenants {
+		child, hash, err := collectorSetChild(set, tenant)
+		if err != nil {
+			syncErrs = append(syncErrs, fmt.Errorf("tenant %s: %w", tenant.name, err))
+
+			continue
+		}
+
+		desired[child.Name] = hash
+
+		if err = c.writeCollectorSetChild(ctx, set, child, hash); err != nil {
+			syncErrs = append(syncErrs, fmt.Errorf("tenant %s: %w", tenant.name, err))
+		}
+	}
+
+	// Collectors of the tenants that aren't selected anymore are pruned, their releases are uninstalled by the
+	// collector finalizer
+	for childName, child := range existing {
+		if _, ok := desired[childName]; ok || child.DeletionTimestamp != nil {
+			continue
+		}
+
+		err = c.resourceclientset.ExampleV1alpha().Collectors(namespace).Delete(ctx, childName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			syncErrs = append(syncErrs, fmt.Errorf("could not delete Collector %s: %w", childName, err))
+
+			continue
+		}
+
+		c.recorder.Eventf(set, corev1.EventTypeNormal, reasonCollectorDeleted, "Deleted Collector %s of tenant %s", childName, child.Spec.Tenant.Name)
+	}
+
+	syncErr := errors.Join(syncErrs...)
+
+	err = c.setCollectorSetStatus(ctx, set, func(status *v1alpha.CollectorSetStatus) {
+		c.rollUpCollectorSet(set, status, desired, existing)
+
+		if syncErr != nil {
+			meta.SetStatusCondition(&status.Conditions, collectorSetCondition(set, typeDegraded, metav1.ConditionTrue, reasonCollectorsSyncFailed, fmt.Sprintf("Could not sync the collectors: (%s)", syncErr)))
+		} else {
+			meta.SetStatusCondition(&status.Conditions, collectorSetCondition(set, typeDegraded, metav1.ConditionFalse, reasonCollectorsSynced, fmt.Sprintf("Synced %d collectors", len(desired))))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return syncErr
+}
+
+// collectorSetTenants returns the tenants the CollectorSet selects, sorted by name. The Tenants of an explicit list
+// that don't exist are still returned, their Collectors wait for the Tenant to be created.
+func (c *Controller) collectorSetTenants(set *v1alpha.CollectorSet) ([]collectorSetTenant, error) {
+	var tenants []collectorSetTenant
+
+	if set.Spec.TenantSelector != nil {
+		if !c.tenantsServed {
+			return nil, errors.New("the Tenant CRD is not installed")
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(set.Spec.TenantSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant selector: %w", err)
+		}
+
+		selected, err := c.tenantLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tenant := range selected {
+			tenants = append(tenants, collectorSetTenant{name: tenant.Name, namespace: tenantNamespaceName(tenant), id: tenant.Spec.ID})
+		}
+	}
+
+	for _, entry := range set.Spec.Tenants {
+		tenant := collectorSetTenant{name: entry.Name, namespace: entry.Name}
+
+		if c.tenantsServed {
+			if found, err := c.tenantLister.Get(entry.Name); err == nil {
+				tenant.namespace, tenant.id = tenantNamespaceName(found), found.Spec.ID
+			}
+		}
+
+		if entry.Values != nil {
+			if err := json.Unmarshal(entry.Values.Raw, &tenant.values); err != nil {
+				return nil, fmt.Errorf("values of tenant %s: %w", entry.Name, err)
+			}
+		}
+
+		tenants = append(tenants, tenant)
+	}
+
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].name < tenants[j].name })
+
+	return tenants, nil
+}
+
+// collectorSetChild returns the Collector of the CollectorSet for the tenant and the hash of what it is written from.
+func collectorSetChild(set *v1alpha.CollectorSet, tenant collectorSetTenant) (*v1alpha.Collector, string, error) {
+	name := collectorSetChildName(set, tenant.name)
+	if messages := validation.IsDNS1123Subdomain(name); len(messages) > 0 {
+		return nil, "", fmt.Errorf("%s is not a valid Collector name: %s", name, strings.Join(messages, ", "))
+	}
+
+	template := set.Spec.Template
+
+	collectorInfo := *template.Spec.Collector.DeepCopy()
+
+	// The values of the tenant are merged over the configuration of the template
+	if tenant.values != nil {
+		vals, err := getValues(collectorInfo.Configuration)
+		if err != nil {
+			return nil, "", err
+		}
+
+		data, err := yaml.Marshal(mergeValues(vals, tenant.values))
+		if err != nil {
+			return nil, "", err
+		}
+
+		collectorInfo.Configuration = base64.StdEncoding.EncodeToString(data)
+	}
+
+	controller := true
+	child := &v1alpha.Collector{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   set.Namespace,
+			Labels:      map[string]string{LabelCollectorSet: set.Name},
+			Annotations: map[string]string{},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         v1alpha.SchemeGroupVersion.String(),
+				Kind:               v1alpha.CollectorSetKind,
+				Name:               set.Name,
+				UID:                set.UID,
+				Controller:         &controller,
+				BlockOwnerDeletion: &controller,
+			}},
+		},
+		Spec: v1alpha.CollectorSpec{
+			Collector: collectorInfo,
+			Tenant: v1alpha.TenantInfo{
+				ID:        tenant.id,
+				Reference: tenant.namespace,
+				Instance:  template.Spec.Instance,
+				Name:      tenant.name,
+			},
+			Cluster:     template.Spec.Cluster,
+			ChartSource: template.Spec.ChartSource,
+			DriftPolicy: template.Spec.DriftPolicy,
+		},
+	}
+
+	for label, value := range template.Metadata.Labels {
+		child.Labels[label] = value
+	}
+
+	for annotation, value := range template.Metadata.Annotations {
+		child.Annotations[annotation] = value
+	}
+
+	// Maps are marshalled with sorted keys, so the same Collector always has the same hash
+	encoded, err := json.Marshal(struct {
+		Labels      map[string]string     `json:"labels"`
+		Annotations map[string]string     `json:"annotations"`
+		Spec        v1alpha.CollectorSpec `json:"spec"`
+	}{child.Labels, child.Annotations, child.Spec})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+	hash := hex.EncodeToString(sum[:])[:16]
+
+	child.Annotations[collectorSetHashAnnotation] = hash
+
+	return child, hash, nil
+}
+
+// writeCollectorSetChild creates the Collector of the CollectorSet, or updates it when it was written from another
+// template. The labels and annotations of the template are added to those of the Collector, the ones set by the
+// defaulting webhook are kept. A Collector of the same name that the CollectorSet doesn't control is left alone.
+func (c *Controller) writeCollectorSetChild(ctx context.Context, set *v1alpha.CollectorSet, child *v1alpha.Collector, hash string) error {
+	existing, err := c.lister.Collectors(child.Namespace).Get(child.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = c.resourceclientset.ExampleV1alpha().Collectors(child.Namespace).Create(ctx, child, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create Collector %s: %w", child.Name, err)
+		}
+
+		c.recorder.Eventf(set, corev1.EventTypeNormal, reasonCollectorCreated, "Created Collector %s of tenant %s", child.Name, child.Spec.Tenant.Name)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if owner := metav1.GetControllerOf(existing); owner == nil || owner.UID != set.UID {
+		return fmt.Errorf("collector %s already exists and is not controlled by the collector set", child.Name)
+	}
+
+	if existing.Annotations[collectorSetHashAnnotation] == hash || existing.DeletionTimestamp != nil {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Spec = child.Spec
+
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+
+	for label, value := range child.Labels {
+		updated.Labels[label] = value
+	}
+
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+
+	for annotation, value := range child.Annotations {
+		updated.Annotations[annotation] = value
+	}
+
+	_, err = c.resourceclientset.ExampleV1alpha().Collectors(child.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("could not update Collector %s: %w", child.Name, err)
+	}
+
+	klog.V(4).Infof("Updated Collector %s/%s of collector set %s", child.Namespace, child.Name, set.Name)
+
+	return nil
+}
+
+// rollUpCollectorSet sets the counts and the Ready condition of the CollectorSet from its Collectors. A Collector
+// is updated once it was written from the current template, and available once its current generation is.
+func (c *Controller) rollUpCollectorSet(set *v1alpha.CollectorSet, status *v1alpha.CollectorSetStatus, desired map[string]string, existing map[string]*v1alpha.Collector) {
+	var (
+		collectors, updated, available int32
+		notAvailable                   []string
+	)
+
+	for _, name := range sortedKeys(desired) {
+		child, ok := existing[name]
+		if !ok {
+			notAvailable = append(notAvailable, name+" (not created)")
+
+			continue
+		}
+
+		collectors++
+
+		if child.Annotations[collectorSetHashAnnotation] != desired[name] {
+			notAvailable = append(notAvailable, name+" (not updated)")
+
+			continue
+		}
+
+		updated++
+
+		condition := meta.FindStatusCondition(child.Status.Conditions, typeAvailableCollector)
+		if child.Status.ObservedGeneration != child.Generation || condition == nil || condition.Status != metav1.ConditionTrue || condition.ObservedGeneration != child.Generation {
+			reason := "not reconciled"
+			if tenantReady := meta.FindStatusCondition(child.Status.Conditions, typeTenantReady); tenantReady != nil && tenantReady.Status != metav1.ConditionTrue {
+				reason = tenantReady.Reason
+			} else if condition != nil {
+				reason = condition.Reason
+			}
+
+			notAvailable = append(notAvailable, fmt.Sprintf("%s (%s)", name, reason))
+
+			continue
+		}
+
+		available++
+	}
+
+	status.Tenants = int32(len(desired)) // nolint: gosec
+	status.Collectors = collectors
+	status.UpdatedCollectors = updated
+	status.AvailableCollectors = available
+
+	if len(notAvailable) == 0 {
+		meta.SetStatusCondition(&status.Conditions, collectorSetCondition(set, typeReady, metav1.ConditionTrue, reasonCollectorsAvailable, fmt.Sprintf("%d of %d collectors are available", available, len(desired))))
+
+		return
+	}
+
+	message := fmt.Sprintf("%d of %d collectors are available, not available: %s", available, len(desired), strings.Join(truncate(notAvailable, maxNotAvailableCollectors), ", "))
+
+	meta.SetStatusCondition(&status.Conditions, collectorSetCondition(set, typeReady, metav1.ConditionFalse, reasonCollectorsNotAvailable, message))
+}
+
+// sortedKeys returns the keys of the map, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// truncate returns the first max items, followed by how many more there are.
+func truncate(items []string, max int) []string {
+	if len(items) <= max {
+		return items
+	}
+
+	return append(items[:max:max], fmt.Sprintf("and %d more", len(items)-max))
+}
+
+// collectorSetCondition returns a condition observed for the current generation of the CollectorSet.
+func collectorSetCondition(set *v1alpha.CollectorSet, conditionType string, status metav1.ConditionStatus, reason string, message string) metav1.Condition {
+	return metav1.Condition{Type: conditionType, Status: status, Reason: reason, Message: message, ObservedGeneration: set.Generation}
+}
+
+// setCollectorSetStatus applies the mutation to the status of the CollectorSet, like MutateStatus does for the
+// collectors. Nothing is written when the mutation doesn't change the status.
+func (c *Controller) setCollectorSetStatus(ctx context.Context, set *v1alpha.CollectorSet, mutate func(status *v1alpha.CollectorSetStatus)) error {
+	current := set
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		status := current.Status.DeepCopy()
+		mutate(status)
+		status.ObservedGeneration = current.Generation
+
+		if equality.Semantic.DeepEqual(status, &current.Status) {
+			return nil
+		}
+
+		applyStatus := applyv1.CollectorSetStatus().WithConditions(status.Conditions...).WithObservedGeneration(status.ObservedGeneration)
+
+		if status.Tenants != 0 {
+			applyStatus.WithTenants(status.Tenants)
+		}
+
+		if status.Collectors != 0 {
+			applyStatus.WithCollectors(status.Collectors)
+		}
+
+		if status.UpdatedCollectors != 0 {
+			applyStatus.WithUpdatedCollectors(status.UpdatedCollectors)
+		}
+
+		if status.AvailableCollectors != 0 {
+			applyStatus.WithAvailableCollectors(status.AvailableCollectors)
+		}
+
+		applyConfiguration := applyv1.CollectorSet(current.Name, current.Namespace).WithResourceVersion(current.ResourceVersion).WithStatus(applyStatus)
+
+		_, err := c.resourceclientset.ExampleV1alpha().CollectorSets(current.Namespace).ApplyStatus(ctx, applyConfiguration, metav1.ApplyOptions{FieldManager: statusFieldManager, Force: true})
+		if apierrors.IsConflict(err) {
+			latest, getErr := c.resourceclientset.ExampleV1alpha().CollectorSets(current.Namespace).Get(ctx, current.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+
+			current = latest
+
+			return err
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to update CollectorSet status: %w", err)
+		}
+
+		return nil
+	})
+}
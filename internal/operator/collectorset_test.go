@@ -0,0 +1,152 @@
+package operator
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+func testCollectorSet() *v1alpha.CollectorSet {
+	return &v1alpha.CollectorSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "platform", UID: "set-uid"},
+		Spec: v1alpha.CollectorSetSpec{
+			Template: v1alpha.CollectorTemplate{
+				Metadata: v1alpha.CollectorTemplateMetadata{
+					Labels:      map[string]string{"team": "observability"},
+					Annotations: map[string]string{"owner": "platform"},
+				},
+				Spec: v1alpha.CollectorTemplateSpec{
+					Collector: v1alpha.CollectorInfo{
+						Name:          "fluent-bit",
+						Version:       "~1.4",
+						Configuration: base64.StdEncoding.EncodeToString([]byte("output:\n  host: logs.example.com\n  port: 24224\n")),
+					},
+					Instance: "primary",
+					Cluster:  "production",
+				},
+			},
+		},
+	}
+}
+
+func TestCollectorSetChildHash(t *testing.T) {
+	tenant := collectorSetTenant{name: "acme", namespace: "acme", id: "t-1"}
+
+	tests := []struct {
+		name    string
+		mutate  func(set *v1alpha.CollectorSet, tenant *collectorSetTenant)
+		changed bool
+	}{
+		{name: "nothing changed", mutate: func(*v1alpha.CollectorSet, *collectorSetTenant) {}},
+		{name: "uid of the set", mutate: func(set *v1alpha.CollectorSet, _ *collectorSetTenant) { set.UID = "other-uid" }},
+		{name: "status of the set", mutate: func(set *v1alpha.CollectorSet, _ *collectorSetTenant) {
+			set.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}}
+		}},
+		{name: "template label", changed: true, mutate: func(set *v1alpha.CollectorSet, _ *collectorSetTenant) {
+			set.Spec.Template.Metadata.Labels["team"] = "platform"
+		}},
+		{name: "template annotation", changed: true, mutate: func(set *v1alpha.CollectorSet, _ *collectorSetTenant) {
+			set.Spec.Template.Metadata.Annotations["on-call"] = "observability"
+		}},
+		{name: "collector version", changed: true, mutate: func(set *v1alpha.CollectorSet, _ *collectorSetTenant) {
+			set.Spec.Template.Spec.Collector.Version = "~1.5"
+		}},
+		{name: "drift policy", changed: true, mutate: func(set *v1alpha.CollectorSet, _ *collectorSetTenant) {
+			set.Spec.Template.Spec.DriftPolicy = v1alpha.DriftPolicyCorrect
+		}},
+		{name: "tenant id", changed: true, mutate: func(_ *v1alpha.CollectorSet, tenant *collectorSetTenant) { tenant.id = "t-2" }},
+		{name: "tenant values", changed: true, mutate: func(_ *v1alpha.CollectorSet, tenant *collectorSetTenant) {
+			tenant.values = map[string]interface{}{"output": map[string]interface{}{"port": 24225}}
+		}},
+	}
+
+	_, baseline, err := collectorSetChild(testCollectorSet(), tenant)
+	if err != nil {
+		t.Fatalf("collectorSetChild() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, tenant := testCollectorSet(), tenant
+			tt.mutate(set, &tenant)
+
+			child, hash, err := collectorSetChild(set, tenant)
+			if err != nil {
+				t.Fatalf("collectorSetChild() error = %v", err)
+			}
+
+			if _, err := hex.DecodeString(hash); err != nil || len(hash) != 16 {
+				t.Errorf("hash %q isn't 16 hex characters", hash)
+			}
+
+			if child.Annotations[collectorSetHashAnnotation] != hash {
+				t.Errorf("hash annotation = %q, want %q", child.Annotations[collectorSetHashAnnotation], hash)
+			}
+
+			if (hash != baseline) != tt.changed {
+				t.Errorf("hash changed = %v, want %v", hash != baseline, tt.changed)
+			}
+		})
+	}
+}
+
+func TestCollectorSetChild(t *testing.T) {
+	set := testCollectorSet()
+
+	child, _, err := collectorSetChild(set, collectorSetTenant{
+		name:      "acme",
+		namespace: "acme-logs",
+		id:        "t-1",
+		values:    map[string]interface{}{"output": map[string]interface{}{"port": 24225}},
+	})
+	if err != nil {
+		t.Fatalf("collectorSetChild() error = %v", err)
+	}
+
+	if child.Name != "logs-acme" || child.Namespace != "platform" {
+		t.Errorf("child is %s/%s, want platform/logs-acme", child.Namespace, child.Name)
+	}
+
+	if owner := metav1.GetControllerOf(child); owner == nil || owner.UID != set.UID || owner.Kind != v1alpha.CollectorSetKind {
+		t.Errorf("child is controlled by %v, want the CollectorSet", owner)
+	}
+
+	if child.Labels[LabelCollectorSet] != "logs" || child.Labels["team"] != "observability" || child.Annotations["owner"] != "platform" {
+		t.Errorf("child metadata = %v %v, want the template metadata and the set label", child.Labels, child.Annotations)
+	}
+
+	wantTenant := v1alpha.TenantInfo{ID: "t-1", Reference: "acme-logs", Instance: "primary", Name: "acme"}
+	if child.Spec.Tenant != wantTenant {
+		t.Errorf("tenant = %+v, want %+v", child.Spec.Tenant, wantTenant)
+	}
+
+	vals, err := getValues(child.Spec.Collector.Configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The values are compared encoded, the type of their numbers depends on the decoder
+	encoded, err := json.Marshal(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"output":{"host":"logs.example.com","port":24225}}`; string(encoded) != want {
+		t.Errorf("values = %s, want %s", encoded, want)
+	}
+
+	// The template of the set is left as it was
+	if set.Spec.Template.Spec.Collector.Configuration != testCollectorSet().Spec.Template.Spec.Collector.Configuration {
+		t.Error("the values of the tenant were merged into the template")
+	}
+
+	if _, _, err := collectorSetChild(set, collectorSetTenant{name: strings.Repeat("a", 254)}); err == nil {
+		t.Error("collectorSetChild() accepted a name longer than 253 characters")
+	}
+}
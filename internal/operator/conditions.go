@@ -0,0 +1,86 @@
+package operator
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// Condition types of a Collector. Each one reports a single aspect of the collector, following the
+// Kubernetes API conventions.
+const (
+	// typeAvailableCollector is True when the release is installed and its workloads are ready, i.e. the collector is serving.
+	typeAvailableCollector = "Available"
+	// typeTerminatingCollector is True while the resources of a deleted collector are cleaned up.
+	typeTerminatingCollector = "Terminating"
+	// typeChartResolved is True once Spec.Collector.Version resolved to a chart that was fetched and verified.
+	typeChartResolved = "ChartResolved"
+	// typeConfigValid is True when the configuration decodes to chart values that match the values schemas.
+	typeConfigValid = "ConfigValid"
+	// typeInstalled is True when the Helm release of the current generation is deployed.
+	typeInstalled = "Installed"
+	// typeReady is True when every Deployment, StatefulSet and DaemonSet of the release is rolled out and available.
+	typeReady = "Ready"
+	// typeDegraded is True when the last reconcile failed, the collector may still run a previous revision.
+	typeDegraded = "Degraded"
+	// typeDrifted is True when the live objects of the release differ from the manifest of its deployed revision.
+	typeDrifted = "Drifted"
+	// typeTenantReady is True when the Tenant the collector is deployed for is Ready in the namespace of the collector.
+	typeTenantReady = "TenantReady"
+	// typeProgressing is True while a new generation of the collector is being rolled out.
+	typeProgressing = "Progressing"
+)
+
+// Condition reasons.
+const (
+	reasonReconciling           = "Reconciling"
+	reasonReconcileSucceeded    = "ReconcileSucceeded"
+	reasonChartResolved         = "ChartResolved"
+	reasonChartResolutionFailed = "ChartResolutionFailed"
+	// reasonChartVerificationFailed is the condition and event reason of a chart that failed provenance or signature verification.
+	reasonChartVerificationFailed = "ChartVerificationFailed"
+	reasonConfigurationValid      = "ConfigurationValid"
+	reasonInvalidConfiguration    = "InvalidConfiguration"
+	// reasonConfigurationSourceNotFound is the reason of a ConfigMap or Secret key in ConfigurationFrom that doesn't exist.
+	reasonConfigurationSourceNotFound = "ConfigurationSourceNotFound"
+	// reasonValuesSchemaViolation is the reason of values that don't match the values schema of the chart or of the collector.
+	reasonValuesSchemaViolation = "ValuesSchemaViolation"
+	reasonReleaseDeployed       = "ReleaseDeployed"
+	reasonReleaseFailed         = "ReleaseFailed"
+	reasonRolledBack            = "RolledBack"
+	reasonFinalizing            = "Finalizing"
+)
+
+// newCondition returns a condition observed for the current generation of the collector.
+func newCondition(resource *v1alpha.Collector, conditionType string, status metav1.ConditionStatus, reason string, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: resource.Generation,
+	}
+}
+
+// recordReconcile writes the conditions a reconcile ended with to the status of the collector, along with the
+// observed generation, the reconcile time and the other status changes made by mutate, which may be nil.
+func (r *CollectorReconciler) recordReconcile(ctx context.Context, resource *v1alpha.Collector, mutate func(status *v1alpha.CollectorStatus), conditions ...metav1.Condition) error {
+	_, err := r.Controller.MutateStatus(ctx, resource, func(status *v1alpha.CollectorStatus) {
+		for _, condition := range conditions {
+			meta.SetStatusCondition(&status.Conditions, condition)
+		}
+
+		now := metav1.Now()
+		status.ObservedGeneration = resource.Generation
+		status.LastReconcileTime = &now
+
+		if mutate != nil {
+			mutate(status)
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,106 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+func TestNewCondition(t *testing.T) {
+	collector := testCollector()
+	collector.Generation = 3
+
+	got := newCondition(collector, typeInstalled, metav1.ConditionTrue, reasonReleaseDeployed, "Revision 2 deployed")
+
+	want := metav1.Condition{Type: typeInstalled, Status: metav1.ConditionTrue, Reason: reasonReleaseDeployed, Message: "Revision 2 deployed", ObservedGeneration: 3}
+	if got != want {
+		t.Errorf("newCondition() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecordReconcile(t *testing.T) {
+	transitioned := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	tests := []struct {
+		name               string
+		conditions         []metav1.Condition
+		wantStatus         map[string]metav1.ConditionStatus
+		wantTransitioned   map[string]bool
+		wantChartVersion   string
+		wantLastReconciled bool
+	}{
+		{
+			name:             "first reconcile sets the conditions",
+			conditions:       []metav1.Condition{{Type: typeInstalled, Status: metav1.ConditionTrue, Reason: reasonReleaseDeployed}, {Type: typeDegraded, Status: metav1.ConditionFalse, Reason: reasonReconcileSucceeded}},
+			wantStatus:       map[string]metav1.ConditionStatus{typeInstalled: metav1.ConditionTrue, typeDegraded: metav1.ConditionFalse, typeReady: metav1.ConditionFalse},
+			wantTransitioned: map[string]bool{typeInstalled: true, typeDegraded: true},
+		},
+		{
+			name:             "condition keeps its transition time while its status holds",
+			conditions:       []metav1.Condition{{Type: typeReady, Status: metav1.ConditionFalse, Reason: reasonReconciling, Message: "Waiting for the DaemonSet"}},
+			wantStatus:       map[string]metav1.ConditionStatus{typeReady: metav1.ConditionFalse},
+			wantTransitioned: map[string]bool{typeReady: false},
+		},
+		{
+			name:             "condition that changes status transitions",
+			conditions:       []metav1.Condition{{Type: typeReady, Status: metav1.ConditionTrue, Reason: reasonReconcileSucceeded}},
+			wantStatus:       map[string]metav1.ConditionStatus{typeReady: metav1.ConditionTrue},
+			wantTransitioned: map[string]bool{typeReady: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := testCollector(collectorFinalizer)
+			collector.Generation = 2
+			collector.Status = v1.CollectorStatus{
+				ObservedGeneration: 1,
+				ChartVersion:       "1.0.0",
+				Conditions: []metav1.Condition{
+					{Type: typeReady, Status: metav1.ConditionFalse, Reason: reasonReconciling, ObservedGeneration: 1, LastTransitionTime: transitioned},
+				},
+			}
+
+			controller := newTestController(t, collector)
+
+			for i := range tt.conditions {
+				tt.conditions[i].ObservedGeneration = collector.Generation
+			}
+
+			err := controller.reconciler.recordReconcile(context.Background(), collector, func(status *v1.CollectorStatus) {
+				status.ChartVersion = "1.1.0"
+			}, tt.conditions...)
+			if err != nil {
+				t.Fatalf("recordReconcile() error = %v", err)
+			}
+
+			stored, err := controller.resourceclientset.ExampleV1alpha().Collectors("acme").Get(context.Background(), "logs", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Status.ObservedGeneration != 2 || stored.Status.LastReconcileTime == nil || stored.Status.ChartVersion != "1.1.0" {
+				t.Errorf("status = observed generation %d, reconciled at %v, chart %s, want generation 2 reconciled now with chart 1.1.0",
+					stored.Status.ObservedGeneration, stored.Status.LastReconcileTime, stored.Status.ChartVersion)
+			}
+
+			for conditionType, status := range tt.wantStatus {
+				condition := meta.FindStatusCondition(stored.Status.Conditions, conditionType)
+				if condition == nil || condition.Status != status {
+					t.Errorf("%s condition = %+v, want status %s", conditionType, condition, status)
+
+					continue
+				}
+
+				if transitionedNow, ok := tt.wantTransitioned[conditionType]; ok && transitionedNow == condition.LastTransitionTime.Equal(&transitioned) {
+					t.Errorf("%s condition transitioned at %v, want a new transition time %v", conditionType, condition.LastTransitionTime, transitionedNow)
+				}
+			}
+		})
+	}
+}
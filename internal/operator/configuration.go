@@ -0,0 +1,329 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+const (
+	// configurationIndex indexes collectors by the ConfigMaps and Secrets their values are read from.
+	configurationIndex = "configuration"
+	configMapKind      = "ConfigMap"
+	secretKind         = "Secret"
+	// configurationSyncTimeout is how long a reconcile waits for the watch of a ConfigMap or Secret to sync.
+	configurationSyncTimeout = 10 * time.Second
+)
+
+// errConfigurationSourceNotFound is returned for a ConfigMap or Secret key in ConfigurationFrom that doesn't exist.
+var errConfigurationSourceNotFound = errors.New("configuration source not found")
+
+// ConfigurationSources reads the ConfigMaps and Secrets the collectors take their values from. Only the objects a
+// Collector references are watched, each by its name, so that a collector is reconciled again as soon as its
+// values change without caching any other ConfigMap or Secret. A watch is started the first time a reconcile reads
+// the object, and stopped once no Collector references it anymore.
+type ConfigurationSources struct {
+	client  kubernetes.Interface
+	handler func(kind string) cache.ResourceEventHandler
+
+	mu      sync.Mutex
+	ctx     context.Context
+	watches map[string]*configurationSourceWatch
+}
+
+// configurationSourceWatch is the informer of a single ConfigMap or Secret.
+type configurationSourceWatch struct {
+	informer cache.SharedIndexInformer
+	stop     context.CancelFunc
+}
+
+// NewConfigurationSources creates the reader of the ConfigMaps and Secrets of the collectors. Nothing is watched
+// until the sources are started.
+func NewConfigurationSources(client kubernetes.Interface) *ConfigurationSources {
+	return &ConfigurationSources{client: client, watches: map[string]*configurationSourceWatch{}}
+}
+
+// start lets the sources watch ConfigMaps and Secrets until the context is cancelled, their events are sent to
+// the handler of their kind.
+func (s *ConfigurationSources) start(ctx context.Context, handler func(kind string) cache.ResourceEventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	s.handler = handler
+}
+
+// configurationSourceKey returns the key of a ConfigMap or Secret, which is also its key in the configuration index.
+func configurationSourceKey(kind string, namespace string, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// store returns the synced store of the watch of the ConfigMap or Secret, starting the watch if needed.
+func (s *ConfigurationSources) store(ctx context.Context, kind string, namespace string, name string) (cache.Store, error) {
+	s.mu.Lock()
+
+	key := configurationSourceKey(kind, namespace, name)
+
+	watch, ok := s.watches[key]
+	if !ok {
+		if s.ctx == nil {
+			s.mu.Unlock()
+
+			return nil, errors.New("configuration sources are not started")
+		}
+
+		resource, object := "configmaps", runtime.Object(&corev1.ConfigMap{})
+		if kind == secretKind {
+			resource, object = "secrets", &corev1.Secret{}
+		}
+
+		listWatch := cache.NewFilteredListWatchFromClient(s.client.CoreV1().RESTClient(), resource, namespace, func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		})
+
+		watchCtx, stop := context.WithCancel(s.ctx)
+		watch = &configurationSourceWatch{informer: cache.NewSharedIndexInformer(listWatch, object, resyncePeriod, cache.Indexers{}), stop: stop}
+
+		if _, err := watch.informer.AddEventHandler(s.handler(kind)); err != nil {
+			s.mu.Unlock()
+			stop()
+
+			return nil, fmt.Errorf("could not watch %s %s/%s: %w", kind, namespace, name, err)
+		}
+
+		go watch.informer.Run(watchCtx.Done())
+
+		s.watches[key] = watch
+	}
+
+	s.mu.Unlock()
+
+	syncCtx, cancel := context.WithTimeout(ctx, configurationSyncTimeout)
+	defer cancel()
+
+	if !cache.WaitForCacheSync(syncCtx.Done(), watch.informer.HasSynced) {
+		return nil, fmt.Errorf("could not sync the watch of %s %s/%s", kind, namespace, name)
+	}
+
+	return watch.informer.GetStore(), nil
+}
+
+// forget stops watching the ConfigMap or Secret.
+func (s *ConfigurationSources) forget(kind string, namespace string, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := configurationSourceKey(kind, namespace, name)
+
+	if watch, ok := s.watches[key]; ok {
+		watch.stop()
+		delete(s.watches, key)
+
+		klog.V(4).Infof("Stopped watching %s, no collector reads from it anymore", key)
+	}
+}
+
+// collectorValues merges the values of the collector: the ConfigMap and Secret keys of ConfigurationFrom in
+// order, and the inline Configuration over them. It also returns the hash of the merged values.
+func (s *ConfigurationSources) collectorValues(ctx context.Context, resource *v1alpha.Collector) (map[string]interface{}, string, error) {
+	vals := map[string]interface{}{}
+
+	for i, source := range resource.Spec.Collector.ConfigurationFrom {
+		data, err := s.read(ctx, resource.Namespace, source)
+		if err != nil {
+			return nil, "", fmt.Errorf("configurationFrom[%d]: %w", i, err)
+		}
+
+		sourceVals := map[string]interface{}{}
+		if err = yaml.Unmarshal([]byte(data), &sourceVals); err != nil {
+			return nil, "", fmt.Errorf("configurationFrom[%d]: %w", i, err)
+		}
+
+		vals = mergeValues(vals, sourceVals)
+	}
+
+	inlineVals, err := getValues(resource.Spec.Collector.Configuration)
+	if err != nil {
+		return nil, "", err
+	}
+
+	vals = mergeValues(vals, inlineVals)
+
+	// Maps are marshalled with sorted keys, so the same values always have the same hash
+	encoded, err := json.Marshal(vals)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash := sha256.Sum256(encoded)
+
+	return vals, "sha256:" + hex.EncodeToString(hash[:]), nil
+}
+
+// read returns the YAML values held by the ConfigMap or Secret key. A missing optional key reads as no values.
+func (s *ConfigurationSources) read(ctx context.Context, namespace string, source v1alpha.ConfigurationSource) (string, error) {
+	var (
+		kind, name, key string
+		optional        *bool
+	)
+
+	switch {
+	case source.ConfigMapKeyRef != nil && source.SecretKeyRef == nil:
+		kind, name, key, optional = configMapKind, source.ConfigMapKeyRef.Name, source.ConfigMapKeyRef.Key, source.ConfigMapKeyRef.Optional
+	case source.SecretKeyRef != nil && source.ConfigMapKeyRef == nil:
+		kind, name, key, optional = secretKind, source.SecretKeyRef.Name, source.SecretKeyRef.Key, source.SecretKeyRef.Optional
+	default:
+		return "", errors.New("exactly one of configMapKeyRef and secretKeyRef must be set")
+	}
+
+	store, err := s.store(ctx, kind, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	item, exists, err := store.GetByKey(namespace + "/" + name)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		data  string
+		found bool
+	)
+
+	if exists {
+		switch object := item.(type) {
+		case *corev1.ConfigMap:
+			data, found = object.Data[key]
+		case *corev1.Secret:
+			var value []byte
+			value, found = object.Data[key]
+			data = string(value)
+		}
+	}
+
+	if !found {
+		if optional != nil && *optional {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("%w: %s %s/%s has no key %q", errConfigurationSourceNotFound, kind, namespace, name, key)
+	}
+
+	return data, nil
+}
+
+// mergeValues merges src into dst, values of src win over those of dst and nested maps are merged.
+func mergeValues(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+		if srcIsMap && dstIsMap {
+			dst[key] = mergeValues(dstMap, srcMap)
+		} else {
+			dst[key] = value
+		}
+	}
+
+	return dst
+}
+
+// collectorConfigurationIndex indexes a collector by the ConfigMaps and Secrets of its ConfigurationFrom.
+func collectorConfigurationIndex(obj interface{}) ([]string, error) {
+	collector, ok := obj.(*v1alpha.Collector)
+	if !ok {
+		return nil, fmt.Errorf("expected Collector but got %T", obj)
+	}
+
+	var keys []string
+
+	for _, source := range collector.Spec.Collector.ConfigurationFrom {
+		if source.ConfigMapKeyRef != nil {
+			keys = append(keys, configurationSourceKey(configMapKind, collector.Namespace, source.ConfigMapKeyRef.Name))
+		}
+
+		if source.SecretKeyRef != nil {
+			keys = append(keys, configurationSourceKey(secretKind, collector.Namespace, source.SecretKeyRef.Name))
+		}
+	}
+
+	return keys, nil
+}
+
+// enqueueConfigurationCollectors returns a handler that reconciles the collectors whose values are read from
+// the ConfigMap or Secret.
+func (c *Controller) enqueueConfigurationCollectors(kind string) func(obj interface{}) {
+	return func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+
+		object, err := meta.Accessor(obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			return
+		}
+
+		collectors, err := c.informer.GetIndexer().ByIndex(configurationIndex, configurationSourceKey(kind, object.GetNamespace(), object.GetName()))
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			return
+		}
+
+		// The watch of an object no Collector reads from anymore is stopped, at the latest on the next resync
+		if len(collectors) == 0 {
+			c.configurationSources.forget(kind, object.GetNamespace(), object.GetName())
+
+			return
+		}
+
+		for _, collector := range collectors {
+			c.enqueue(collector)
+		}
+	}
+}
+
+// configurationEventHandler reconciles the collectors of a ConfigMap or Secret whenever it changes.
+func (c *Controller) configurationEventHandler(kind string) cache.ResourceEventHandler {
+	enqueue := c.enqueueConfigurationCollectors(kind)
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			oldMeta, oldErr := meta.Accessor(oldObject)
+			newMeta, newErr := meta.Accessor(newObject)
+			// Periodic resyncs don't change anything, they only stop the watches that aren't needed anymore
+			if oldErr == nil && newErr == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				if collectors, err := c.informer.GetIndexer().ByIndex(configurationIndex, configurationSourceKey(kind, newMeta.GetNamespace(), newMeta.GetName())); err == nil && len(collectors) == 0 {
+					c.configurationSources.forget(kind, newMeta.GetNamespace(), newMeta.GetName())
+				}
+
+				return
+			}
+
+			enqueue(newObject)
+		},
+		DeleteFunc: enqueue,
+	}
+}
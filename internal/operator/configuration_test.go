@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+func TestMergeValues(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  map[string]interface{}
+		src  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "into empty values",
+			dst:  map[string]interface{}{},
+			src:  map[string]interface{}{"replicas": 2},
+			want: map[string]interface{}{"replicas": 2},
+		},
+		{
+			name: "scalars are overridden",
+			dst:  map[string]interface{}{"replicas": 1, "image": "fluent-bit"},
+			src:  map[string]interface{}{"replicas": 2},
+			want: map[string]interface{}{"replicas": 2, "image": "fluent-bit"},
+		},
+		{
+			name: "nested maps are merged",
+			dst:  map[string]interface{}{"output": map[string]interface{}{"host": "logs.example.com", "port": 24224}},
+			src:  map[string]interface{}{"output": map[string]interface{}{"port": 24225, "tls": true}},
+			want: map[string]interface{}{"output": map[string]interface{}{"host": "logs.example.com", "port": 24225, "tls": true}},
+		},
+		{
+			name: "a map replaces a scalar",
+			dst:  map[string]interface{}{"output": "stdout"},
+			src:  map[string]interface{}{"output": map[string]interface{}{"host": "logs.example.com"}},
+			want: map[string]interface{}{"output": map[string]interface{}{"host": "logs.example.com"}},
+		},
+		{
+			name: "a scalar replaces a map",
+			dst:  map[string]interface{}{"output": map[string]interface{}{"host": "logs.example.com"}},
+			src:  map[string]interface{}{"output": "stdout"},
+			want: map[string]interface{}{"output": "stdout"},
+		},
+		{
+			name: "lists are replaced",
+			dst:  map[string]interface{}{"inputs": []interface{}{"tail", "systemd"}},
+			src:  map[string]interface{}{"inputs": []interface{}{"kmsg"}},
+			want: map[string]interface{}{"inputs": []interface{}{"kmsg"}},
+		},
+		{
+			name: "null overrides",
+			dst:  map[string]interface{}{"resources": map[string]interface{}{"limits": "1"}},
+			src:  map[string]interface{}{"resources": nil},
+			want: map[string]interface{}{"resources": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeValues(tt.dst, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorConfigurationIndex(t *testing.T) {
+	configMap := func(name string) v1alpha.ConfigurationSource {
+		return v1alpha.ConfigurationSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "values.yaml"}}
+	}
+
+	secret := func(name string) v1alpha.ConfigurationSource {
+		return v1alpha.ConfigurationSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "values.yaml"}}
+	}
+
+	tests := []struct {
+		name    string
+		sources []v1alpha.ConfigurationSource
+		want    []string
+	}{
+		{name: "no sources"},
+		{name: "ConfigMap", sources: []v1alpha.ConfigurationSource{configMap("outputs")}, want: []string{"ConfigMap/acme/outputs"}},
+		{name: "Secret", sources: []v1alpha.ConfigurationSource{secret("credentials")}, want: []string{"Secret/acme/credentials"}},
+		{
+			name:    "ConfigMaps and Secrets of the same name",
+			sources: []v1alpha.ConfigurationSource{configMap("values"), secret("values")},
+			want:    []string{"ConfigMap/acme/values", "Secret/acme/values"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &v1alpha.Collector{ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"}}
+			collector.Spec.Collector.ConfigurationFrom = tt.sources
+
+			got, err := collectorConfigurationIndex(collector)
+			if err != nil {
+				t.Fatalf("collectorConfigurationIndex() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectorConfigurationIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := collectorConfigurationIndex(&corev1.ConfigMap{}); err == nil {
+		t.Error("collectorConfigurationIndex() indexed a ConfigMap")
+	}
+}
@@ -3,14 +3,18 @@ package operator
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
@@ -19,6 +23,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,20 +39,92 @@ type Controller struct {
 	kubeclientset          kubernetes.Interface
 	apiextensionsclientset apiextensionsclientset.Interface
 	resourceclientset      collectorclientset.Interface
+	dynamicclientset       dynamic.Interface
 	informer               cache.SharedIndexInformer
 	lister                 collectorlister.CollectorLister
 	recorder               record.EventRecorder
+	eventBroadcaster       record.EventBroadcaster
 	workqueue              workqueue.RateLimitingInterface
+	workloadInformers      []cache.SharedIndexInformer
+	podInformer            cache.SharedIndexInformer
+	releaseWorkloads       *releaseWorkloads
+	readinessQueue         workqueue.RateLimitingInterface
+	driftQueue             workqueue.RateLimitingInterface
+	driftCheckInterval     time.Duration
+	releaseObjects         *releaseObjectInformers
+	tenantInformer         cache.SharedIndexInformer
+	tenantLister           collectorlister.TenantLister
+	tenantQueue            workqueue.RateLimitingInterface
+	tenantsServed          bool
+	collectorSetInformer   cache.SharedIndexInformer
+	collectorSetLister     collectorlister.CollectorSetLister
+	collectorSetQueue      workqueue.RateLimitingInterface
+	collectorSetsServed    bool
+	reconciler             *CollectorReconciler
+	secrets                *SecretStore
+	configurationSources   *ConfigurationSources
+	workers                int
+	leaderElection         LeaderElection
+	shutdownGracePeriod    time.Duration
+	installCRD             bool
+	crdConversion          CRDConversion
+	namespace              string
 }
 
 const (
-	createCollectorFlag = false
-	updateCollectorFlag = true
-	resyncePeriod       = 5 * time.Minute
+	resyncePeriod         = 5 * time.Minute
+	defaultWorkers        = 2
+	defaultReleaseTimeout = 5 * time.Minute
+	// defaultShutdownGracePeriod leaves some of the default 30s pod termination grace period to flush logs and events.
+	defaultShutdownGracePeriod = 25 * time.Second
 )
 
+// Options configures the Controller.
+type Options struct {
+	// Workers is the number of goroutines that pop Collector keys off the workqueue and reconcile them.
+	Workers int
+	// KeepReleaseHistory keeps the Helm release history of deleted collectors, marked as uninstalled.
+	KeepReleaseHistory bool
+	// ReleaseTimeout is how long an install, upgrade or rollback waits for the collector to become ready.
+	ReleaseTimeout time.Duration
+	// ChartSourcesConfig is the path of the chart sources configuration file. When empty, charts are fetched
+	// from the development-helm and production-helm buckets.
+	ChartSourcesConfig string
+	// ValuesSchemasDir is the directory of the values schema registry, holding a {collector name}.schema.json
+	// values schema per collector. When empty the values are only validated against the schema of the chart.
+	ValuesSchemasDir string
+	// ChartCacheMaxEntries is the number of charts kept in the chart cache, zero disables the cache.
+	ChartCacheMaxEntries int
+	// ChartCacheMaxBytes is the total size of the charts kept in the chart cache, zero means unlimited.
+	ChartCacheMaxBytes int64
+	// ChartCacheTTL is how long a cached chart is used before it is fetched again, zero means until it is evicted.
+	ChartCacheTTL time.Duration
+	// VersionCacheTTL is how long the versions published by a chart source are used before they are listed again,
+	// zero lists them on every reconcile.
+	VersionCacheTTL time.Duration
+	// HelmStorageDriver is the Helm storage driver for release state: secret (default), configmap, sql or memory.
+	HelmStorageDriver string
+	// HelmSQLConnectionString is the PostgreSQL connection string used by the sql storage driver.
+	HelmSQLConnectionString string
+	// Namespace is the namespace the operator runs in, credentials stored in Secrets are read from it.
+	Namespace string
+	// LeaderElection coordinates the replicas of the operator so that only one of them reconciles.
+	LeaderElection LeaderElection
+	// ShutdownGracePeriod is how long running reconciles are given to finish once the operator is stopped.
+	ShutdownGracePeriod time.Duration
+	// DriftCheckInterval is how often the live objects of the collectors are compared with the manifest of their
+	// release, zero disables the periodic checks. Changes to the objects of a release are checked as they happen.
+	DriftCheckInterval time.Duration
+	// InstallCRD installs or upgrades the Collector, Tenant and CollectorSet CRDs shipped with the operator when it
+	// starts, along with the webhook Service and the admission webhook configurations of the Collectors.
+	InstallCRD bool
+	// CRDConversion configures the conversion webhook of the installed CRD and the webhooks installed with it. Its
+	// webhook port must be set when the served CRD converts with the webhook.
+	CRDConversion CRDConversion
+}
+
 // nolint: forcetypeassert, funlen
-func NewController(ctx context.Context, cfg *rest.Config) (*Controller, error) {
+func NewController(ctx context.Context, cfg *rest.Config, opts Options) (*Controller, error) {
 	// Create clients for interacting with Kubernetes API
 	kubeClient := kubernetes.NewForConfigOrDie(cfg)
 	apiextensionsClient := apiextensionsclientset.NewForConfigOrDie(cfg)
@@ -57,6 +134,8 @@ func NewController(ctx context.Context, cfg *rest.Config) (*Controller, error) {
 	// Create informer factory to receive notifications about changes to services
 	informerFactory := collectorinformers.NewSharedInformerFactory(serviceClient, resyncePeriod)
 	informer := informerFactory.Example().V1alpha().Collectors()
+	tenantInformer := informerFactory.Example().V1alpha().Tenants()
+	collectorSetInformer := informerFactory.Example().V1alpha().CollectorSets()
 
 	// Add necessary schemes for custom resources
 	scheme := runtime.NewScheme()
@@ -69,57 +148,46 @@ func NewController(ctx context.Context, cfg *rest.Config) (*Controller, error) {
 		return nil, err
 	}
 
-	reconciler := CollectorReconciler{
-		Client: reconcilerClient,
-		Scheme: scheme,
+	helmStorage := &HelmStorage{Driver: opts.HelmStorageDriver, SQLConnectionString: opts.HelmSQLConnectionString}
+	if helmStorage.Driver == "" {
+		helmStorage.Driver = StorageDriverSecret
 	}
 
-	// Add event handlers for the informer
-	// nolint: errcheck
-	_, err = informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		// AddFunc is called when a new service is added
-		AddFunc: func(object interface{}) {
-			err = reconciler.CreateOrUpdateCollector(ctx, object.(*v1.Collector), createCollectorFlag)
-			if err != nil {
-				klog.Error(err)
-			} else {
-				klog.Infof("Added: %v", object.(*v1.Collector).Name)
-			}
-		},
-		// UpdateFunc is called when an existing service is updated
-		UpdateFunc: func(oldObject, newObject interface{}) {
-			// Periodic resync will send update events for all known services.
-			// Two different versions of the same Resource will always have different Generation values. So if they're the same there's no changes.
-			if oldObject.(*v1.Collector).Generation == newObject.(*v1.Collector).Generation {
-				klog.Infof("Synced: %v", oldObject.(*v1.Collector).Name)
+	if err = helmStorage.validate(); err != nil {
+		return nil, err
+	}
 
-				return
-			}
+	releaseTimeout := opts.ReleaseTimeout
+	if releaseTimeout <= 0 {
+		releaseTimeout = defaultReleaseTimeout
+	}
 
-			// If the oldObject is not equal to the newObject, then update the service
-			err = reconciler.CreateOrUpdateCollector(ctx, newObject.(*v1.Collector), updateCollectorFlag)
-			if err != nil {
-				klog.Error(err)
-			} else {
-				klog.Infof("Updated: %v", newObject.(*v1.Collector).Name)
-			}
-		},
-		// DeleteFunc is called when a service is deleted
-		DeleteFunc: func(object interface{}) {
-			err = reconciler.DeleteCollector(ctx, kubeClient, dynamicClient, object.(*v1.Collector))
-			if err != nil {
-				klog.Error(err)
-			} else {
-				klog.Infof("Deleted: %v", object.(*v1.Collector).Name)
-			}
-		},
-	})
+	chartSourcesConfig, err := LoadChartSourcesConfig(opts.ChartSourcesConfig)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to add event handlers to informer")
+		return nil, err
+	}
+
+	secrets := NewSecretStore(kubeClient, opts.Namespace)
+
+	chartSources, err := NewChartSources(chartSourcesConfig, NewChartCache(opts.ChartCacheMaxEntries, opts.ChartCacheMaxBytes, opts.ChartCacheTTL), NewVersionCache(opts.VersionCacheTTL), secrets)
+	if err != nil {
+		return nil, err
 	}
 
-	// Start the informer factory to begin receiving events
-	informerFactory.Start(wait.NeverStop)
+	reconciler := &CollectorReconciler{
+		Client:         reconcilerClient,
+		Scheme:         scheme,
+		KeepHistory:    opts.KeepReleaseHistory,
+		Storage:        helmStorage,
+		ReleaseTimeout: releaseTimeout,
+		ChartSources:   chartSources,
+		ValuesSchemas:  &ValuesSchemas{Dir: opts.ValuesSchemasDir},
+	}
+
+	leaderElection, err := opts.LeaderElection.withDefaults(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create an event broadcaster to record events related to the controller
 	eventBroadcaster := record.NewBroadcaster()
@@ -130,71 +198,563 @@ func NewController(ctx context.Context, cfg *rest.Config) (*Controller, error) {
 	// Create a work queue for handling events
 	controllerWorkerQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
+	// Readiness is tracked on its own queue, so that changes to the workloads don't cause Helm upgrades
+	readinessQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	workloadInformers := newWorkloadInformers(kubeClient)
+
+	// Drift checks run on their own queue as well, so that they don't hold up the reconciles
+	driftQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	// Tenants are provisioned and the Collectors of the CollectorSets written on their own queues as well
+	tenantQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	collectorSetQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	shutdownGracePeriod := opts.ShutdownGracePeriod
+	if shutdownGracePeriod <= 0 {
+		shutdownGracePeriod = defaultShutdownGracePeriod
+	}
+
 	controller := &Controller{
 		kubeclientset:          kubeClient,
 		apiextensionsclientset: apiextensionsClient,
 		resourceclientset:      serviceClient,
+		dynamicclientset:       dynamicClient,
 		informer:               informer.Informer(),
 		lister:                 informer.Lister(),
 		recorder:               recorder,
+		eventBroadcaster:       eventBroadcaster,
 		workqueue:              controllerWorkerQueue,
+		workloadInformers:      workloadInformers,
+		podInformer:            newPodInformer(kubeClient),
+		releaseWorkloads:       newReleaseWorkloads(),
+		readinessQueue:         readinessQueue,
+		driftQueue:             driftQueue,
+		driftCheckInterval:     opts.DriftCheckInterval,
+		tenantInformer:         tenantInformer.Informer(),
+		tenantLister:           tenantInformer.Lister(),
+		tenantQueue:            tenantQueue,
+		collectorSetInformer:   collectorSetInformer.Informer(),
+		collectorSetLister:     collectorSetInformer.Lister(),
+		collectorSetQueue:      collectorSetQueue,
+		reconciler:             reconciler,
+		secrets:                secrets,
+		configurationSources:   NewConfigurationSources(kubeClient),
+		workers:                workers,
+		leaderElection:         leaderElection,
+		shutdownGracePeriod:    shutdownGracePeriod,
+		installCRD:             opts.InstallCRD,
+		crdConversion:          opts.CRDConversion,
+		namespace:              opts.Namespace,
 	}
 
 	reconciler.Controller = controller
+	controller.releaseObjects = newReleaseObjectInformers(dynamicClient, controller.releaseObjectEventHandler())
+
+	// Add event handlers for the informer. The handlers only enqueue the namespace/name key of the Collector,
+	// the actual reconciliation is done by the workers started in Start.
+	// Deletions are handled through the collector finalizer, so there is no DeleteFunc.
+	_, err = informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		// AddFunc is called when a new collector is added
+		AddFunc: controller.enqueue,
+		// UpdateFunc is called when an existing collector is updated
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			// The readiness is rolled up again whenever the collector changed, including its status, so that it
+			// follows the outcome of a reconcile. Writing an unchanged readiness is skipped, which ends the cycle.
+			if oldObject.(*v1.Collector).ResourceVersion != newObject.(*v1.Collector).ResourceVersion {
+				controller.enqueueReadiness(newObject)
+			}
+
+			// Periodic resync will send update events for all known collectors.
+			// Two different versions of the same Resource will always have different Generation values. So if they're the same there's no changes.
+			// A collector being deleted is always enqueued so that its finalizer can be processed.
+			if oldObject.(*v1.Collector).Generation == newObject.(*v1.Collector).Generation && newObject.(*v1.Collector).DeletionTimestamp == nil {
+				klog.Infof("Synced: %v", oldObject.(*v1.Collector).Name)
+
+				return
+			}
+
+			controller.enqueue(newObject)
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add event handlers to informer")
+	}
+
+	// Workload events are mapped back to their collectors through the Helm release
+	if err = informer.Informer().AddIndexers(cache.Indexers{releaseIndex: controller.collectorReleaseIndex}); err != nil {
+		return nil, errors.Wrap(err, "failed to add release index to informer")
+	}
+
+	for _, workloadInformer := range workloadInformers {
+		if _, err = workloadInformer.AddEventHandler(controller.workloadEventHandler()); err != nil {
+			return nil, errors.Wrap(err, "failed to add event handlers to workload informer")
+		}
+	}
+
+	if _, err = controller.podInformer.AddEventHandler(controller.podEventHandler()); err != nil {
+		return nil, errors.Wrap(err, "failed to add event handlers to pod informer")
+	}
+
+	// Events of the objects rendered for a collector are mapped back to it through its UID label
+	if err = informer.Informer().AddIndexers(cache.Indexers{uidIndex: collectorUIDIndex}); err != nil {
+		return nil, errors.Wrap(err, "failed to add UID index to informer")
+	}
+
+	// Changes to the ConfigMaps and Secrets of ConfigurationFrom reconcile the collectors that read them
+	if err = informer.Informer().AddIndexers(cache.Indexers{configurationIndex: collectorConfigurationIndex}); err != nil {
+		return nil, errors.Wrap(err, "failed to add configuration index to informer")
+	}
+
+	// Tenant events reconcile the collectors waiting for the Tenant, found through the name of their Tenant
+	if err = informer.Informer().AddIndexers(cache.Indexers{tenantIndex: collectorTenantIndex}); err != nil {
+		return nil, errors.Wrap(err, "failed to add tenant index to informer")
+	}
+
+	if _, err = tenantInformer.Informer().AddEventHandler(controller.tenantEventHandler()); err != nil {
+		return nil, errors.Wrap(err, "failed to add event handlers to tenant informer")
+	}
+
+	// Changes to the Collectors of a CollectorSet, found through their controller owner, and to the Tenants sync it
+	if err = informer.Informer().AddIndexers(cache.Indexers{collectorSetIndex: collectorSetOwnerIndex}); err != nil {
+		return nil, errors.Wrap(err, "failed to add collector set index to informer")
+	}
+
+	if _, err = collectorSetInformer.Informer().AddEventHandler(controller.collectorSetEventHandler()); err != nil {
+		return nil, errors.Wrap(err, "failed to add event handlers to collector set informer")
+	}
+
+	if _, err = informer.Informer().AddEventHandler(controller.collectorSetCollectorEventHandler()); err != nil {
+		return nil, errors.Wrap(err, "failed to add collector set event handlers to informer")
+	}
+
+	if _, err = tenantInformer.Informer().AddEventHandler(controller.collectorSetTenantEventHandler()); err != nil {
+		return nil, errors.Wrap(err, "failed to add collector set event handlers to tenant informer")
+	}
 
 	return controller, nil
 }
 
-func (c *Controller) Start(stopCh <-chan struct{}) error {
+// Start runs the informers, waits for their caches to sync and runs the workers until the context is cancelled.
+// Once it is, running reconciles are given the shutdown grace period to finish before Start returns.
+func (c *Controller) Start(ctx context.Context) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+	defer c.readinessQueue.ShutDown()
+	defer c.driftQueue.ShutDown()
+	defer c.tenantQueue.ShutDown()
+	defer c.collectorSetQueue.ShutDown()
+	// Shutting the broadcaster down delivers the events that are still buffered
+	defer c.eventBroadcaster.Shutdown()
+
+	// The CRD has to be served before the collectors can be listed
+	if c.installCRD {
+		if err := installCRDs(ctx, c.apiextensionsclientset, c.crdConversion); err != nil {
+			return err
+		}
+
+		if err := installWebhooks(ctx, c.dynamicclientset, c.crdConversion); err != nil {
+			return err
+		}
+	} else if err := checkCRDConversion(ctx, c.apiextensionsclientset, c.crdConversion); err != nil {
+		return err
+	}
+
 	// start informer
-	go c.informer.Run(stopCh)
+	go c.informer.Run(ctx.Done())
+
+	cacheSyncs := []cache.InformerSynced{c.informer.HasSynced}
+
+	// Tenants and CollectorSets are only watched when their CRDs are installed, so that the operator runs on
+	// clusters without them
+	served, err := c.servedResources()
+	if err != nil {
+		return err
+	}
+
+	c.tenantsServed = served[v1.TenantPlural]
+	c.collectorSetsServed = served[v1.CollectorSetPlural]
+
+	if c.tenantsServed {
+		go c.tenantInformer.Run(ctx.Done())
+
+		cacheSyncs = append(cacheSyncs, c.tenantInformer.HasSynced)
+	} else {
+		klog.Warningf("The %s CRD is not installed, collectors of a tenant won't be deployed", v1.TenantName)
+	}
+
+	if c.collectorSetsServed {
+		go c.collectorSetInformer.Run(ctx.Done())
+
+		cacheSyncs = append(cacheSyncs, c.collectorSetInformer.HasSynced)
+	} else {
+		klog.Warningf("The %s CRD is not installed, collector sets won't be synced", v1.CollectorSetName)
+	}
+
+	for _, workloadInformer := range c.workloadInformers {
+		go workloadInformer.Run(ctx.Done())
+
+		cacheSyncs = append(cacheSyncs, workloadInformer.HasSynced)
+	}
+
+	go c.podInformer.Run(ctx.Done())
+
+	cacheSyncs = append(cacheSyncs, c.podInformer.HasSynced)
+
+	// The ConfigMaps and Secrets of the collectors are watched one by one as the collectors read them
+	c.configurationSources.start(ctx, c.configurationEventHandler)
+
+	// The objects of the releases are watched kind by kind as the releases are seen, they aren't waited for
+	c.releaseObjects.start(ctx.Done())
+
+	// Secrets are only watched when a credential is read from one
+	if c.secrets.referenced {
+		go c.secrets.informer.Run(ctx.Done())
+
+		cacheSyncs = append(cacheSyncs, c.secrets.informer.HasSynced)
+	}
 
 	// wait for cache to sync
-	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), cacheSyncs...) {
 		return errors.New("failed to sync informer cache")
 	}
 
-	klog.Infoln("Kubewatch controller synced and ready")
+	// Followers keep the caches synced and the queue filled, only the workers wait for the lease
+	if c.leaderElection.Enabled {
+		klog.Infof("Kubewatch controller synced, %s is waiting for lease %s/%s", c.leaderElection.Identity, c.leaderElection.LeaseNamespace, c.leaderElection.LeaseName)
+
+		return c.runWithLeaderElection(ctx)
+	}
+
+	c.runWorkers(ctx, context.WithoutCancel(ctx))
+
+	return nil
+}
+
+// runWorkers runs the workers until the context is cancelled, then stops handing out work and waits up to
+// the shutdown grace period for the running reconciles. The reconciles get their own context derived from
+// reconcileParent, which is only cancelled once the grace period is over, so that a Helm install isn't cut short
+// as soon as the operator stops, or as soon as reconcileParent is cancelled.
+func (c *Controller) runWorkers(ctx context.Context, reconcileParent context.Context) {
+	klog.Infof("Kubewatch controller synced and ready, starting %d workers", c.workers)
+
+	reconcileCtx, cancelReconciles := context.WithCancel(reconcileParent)
+	defer cancelReconciles()
+
+	var running sync.WaitGroup
 
 	// runWorker will loop until "something bad" happens.  The .Until will
 	// then rekick the worker after one second
-	wait.Until(c.RunWorker, time.Second, stopCh)
+	for i := 0; i < c.workers; i++ {
+		running.Add(1)
+
+		go func() {
+			defer running.Done()
+
+			wait.Until(func() { c.RunWorker(reconcileCtx) }, time.Second, ctx.Done())
+		}()
+	}
+
+	running.Add(1)
+
+	go func() {
+		defer running.Done()
+
+		wait.Until(func() { c.RunReadinessWorker(reconcileCtx) }, time.Second, ctx.Done())
+	}()
+
+	running.Add(1)
+
+	go func() {
+		defer running.Done()
+
+		wait.Until(func() { c.RunDriftWorker(reconcileCtx) }, time.Second, ctx.Done())
+	}()
+
+	if c.tenantsServed {
+		running.Add(1)
+
+		go func() {
+			defer running.Done()
+
+			wait.Until(func() { c.RunTenantWorker(reconcileCtx) }, time.Second, ctx.Done())
+		}()
+	}
+
+	if c.collectorSetsServed {
+		running.Add(1)
+
+		go func() {
+			defer running.Done()
+
+			wait.Until(func() { c.RunCollectorSetWorker(reconcileCtx) }, time.Second, ctx.Done())
+		}()
+	}
+
+	if c.driftCheckInterval > 0 {
+		running.Add(1)
+
+		go func() {
+			defer running.Done()
+
+			wait.Until(c.enqueueDriftChecks, c.driftCheckInterval, ctx.Done())
+		}()
+	}
+
+	<-ctx.Done()
+
+	// Workers waiting for a key return right away, the others once their reconcile is done
+	klog.Infof("Stopping workers, waiting up to %s for running reconciles to finish", c.shutdownGracePeriod)
+	c.workqueue.ShutDown()
+	c.readinessQueue.ShutDown()
+	c.driftQueue.ShutDown()
+	c.tenantQueue.ShutDown()
+	c.collectorSetQueue.ShutDown()
+
+	drained := make(chan struct{})
+
+	go func() {
+		running.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		klog.Info("All reconciles finished")
+	case <-time.After(c.shutdownGracePeriod):
+		klog.Warningf("Shutdown grace period of %s expired, cancelling running reconciles", c.shutdownGracePeriod)
+	}
+}
+
+// servedResources returns the resources of the API group version the API server serves, by name. The Tenant and
+// CollectorSet resources are only served when their CRDs are installed.
+func (c *Controller) servedResources() (map[string]bool, error) {
+	resources, err := c.kubeclientset.Discovery().ServerResourcesForGroupVersion(v1.SchemeGroupVersion.String())
+	if apierrors.IsNotFound(err) {
+		return map[string]bool{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not discover the resources of %s: %w", v1.SchemeGroupVersion.String(), err)
+	}
+
+	served := make(map[string]bool, len(resources.APIResources))
+	for _, resource := range resources.APIResources {
+		served[resource.Name] = true
+	}
+
+	return served, nil
+}
+
+// RunWorker processes items off the workqueue until it is shut down.
+func (c *Controller) RunWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+// processNextWorkItem pops a single key off the workqueue and reconciles it. Failed keys are requeued
+// with the rate limiter, successful ones are forgotten so that their backoff is reset.
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	item, shutdown := c.workqueue.Get()
+	if shutdown {
+		return false
+	}
+
+	// Done must always be called so the queue knows the item has been processed
+	defer c.workqueue.Done(item)
+
+	// A queue that is shut down still hands out the keys it holds, they are left to the next leader or start
+	if c.workqueue.ShuttingDown() {
+		return false
+	}
+
+	key, ok := item.(string)
+	if !ok {
+		// Invalid items will never succeed, so drop them from the queue
+		c.workqueue.Forget(item)
+		utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", item))
+
+		return true
+	}
+
+	if err := c.syncHandler(ctx, key); err != nil {
+		c.workqueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing collector %q, requeuing: %w", key, err))
+
+		return true
+	}
+
+	c.workqueue.Forget(key)
+
+	return true
+}
+
+// syncHandler fetches the Collector for the given key from the lister and reconciles it.
+// Collectors marked for deletion have their resources cleaned up before the finalizer is removed.
+func (c *Controller) syncHandler(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		// A malformed key will never succeed, so don't return an error to avoid requeuing it
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+
+		return nil
+	}
+
+	collector, err := c.lister.Collectors(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		// The finalizer has been removed and the object is gone, so there is nothing left to do
+		klog.Infof("Collector %s no longer exists", key)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Objects from the lister are shared with the informer cache and must not be mutated
+	collector = collector.DeepCopy()
+
+	if collector.DeletionTimestamp != nil {
+		return c.finalize(ctx, collector)
+	}
+
+	// Make sure the finalizer is in place before anything is installed, so the resources can't be leaked
+	collector, err = c.setCollectorFinalizer(ctx, collector, true)
+	if err != nil {
+		return fmt.Errorf("failed to add finalizer to Collector: %w", err)
+	}
+
+	if err = c.reconciler.CreateOrUpdateCollector(ctx, collector); err != nil {
+		return err
+	}
+
+	klog.Infof("Reconciled: %v", key)
 
 	return nil
 }
 
-func (c *Controller) RunWorker() {
-	for {
-		_, shutdown := c.workqueue.Get()
-		if shutdown {
-			return
+// finalize deletes the resources of a Collector that is being deleted and removes the collector finalizer
+// once every one of them is confirmed gone.
+func (c *Controller) finalize(ctx context.Context, collector *v1.Collector) error {
+	if !hasFinalizer(collector, collectorFinalizer) {
+		return nil
+	}
+
+	terminating := meta.FindStatusCondition(collector.Status.Conditions, typeTerminatingCollector)
+	if terminating == nil || terminating.Status != metav1.ConditionTrue {
+		var err error
+
+		collector, err = c.SetCondition(ctx, collector, newCondition(collector, typeTerminatingCollector, metav1.ConditionTrue, reasonFinalizing, fmt.Sprintf("Deleting the resources of custom resource (%s)", collector.Name)))
+		if err != nil {
+			return err
 		}
 	}
+
+	err := c.reconciler.DeleteCollector(ctx, collector)
+	if err != nil {
+		return err
+	}
+
+	// Deletion of the resources may not be immediate, so the finalizer is only removed once they're all gone
+	remaining, err := c.reconciler.RemainingCollectorResources(ctx, collector)
+	if err != nil {
+		return err
+	}
+
+	if len(remaining) > 0 {
+		return fmt.Errorf("waiting for resources of Collector %s to be deleted: %s", collector.Name, strings.Join(remaining, ", "))
+	}
+
+	if err = c.reconciler.PurgeCollectorRelease(collector); err != nil {
+		return err
+	}
+
+	c.releaseWorkloads.forget(collector.UID)
+
+	_, err = c.setCollectorFinalizer(ctx, collector, false)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove finalizer from Collector: %w", err)
+	}
+
+	klog.Infof("Deleted: %v/%v", collector.Namespace, collector.Name)
+
+	return nil
 }
 
-// UpdateStatus updates the status of the Collector resource in the API server.
-func (c *Controller) UpdateStatus(ctx context.Context, resource *v1.Collector, status metav1.ConditionStatus, reason string, message string) (*v1.Collector, error) {
-	// Retrieve the updated Collector resource so that we have the most recent version and UID
-	// Otherwise, the next time we try to update the status, we will get a conflict error
-	currentCollector, err := c.resourceclientset.ExampleV1alpha().Collectors(resource.Namespace).Get(ctx, resource.Name, metav1.GetOptions{})
+// setCollectorFinalizer adds the collector finalizer to the Collector, or removes it, and returns the patched
+// Collector. Only the finalizers are patched, guarded by the resource version of the Collector, so that the other
+// finalizers and the rest of the Collector aren't overwritten with a stale copy. On a conflict the latest Collector
+// is read and the finalizer set on it again.
+func (c *Controller) setCollectorFinalizer(ctx context.Context, collector *v1.Collector, present bool) (*v1.Collector, error) {
+	current := collector
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if hasFinalizer(current, collectorFinalizer) == present {
+			return nil
+		}
+
+		updated := current.DeepCopy()
+		if present {
+			addFinalizer(updated, collectorFinalizer)
+		} else {
+			removeFinalizer(updated, collectorFinalizer)
+		}
+
+		patch, err := finalizersPatch(updated)
+		if err != nil {
+			return err
+		}
+
+		patched, err := c.resourceclientset.ExampleV1alpha().Collectors(current.Namespace).Patch(ctx, current.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if apierrors.IsConflict(err) {
+			latest, getErr := c.resourceclientset.ExampleV1alpha().Collectors(current.Namespace).Get(ctx, current.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+
+			current = latest
+		}
+
+		if err != nil {
+			return err
+		}
+
+		current = patched
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get updated resource Collector: %w", err)
+		return nil, err
 	}
 
-	meta.SetStatusCondition(&currentCollector.Status.Conditions, metav1.Condition{Type: typeAvailableCollector, Status: status, Reason: reason, Message: message})
+	return current, nil
+}
 
-	// Update the Collector resource
-	updatedCollector, err := c.resourceclientset.ExampleV1alpha().Collectors(currentCollector.Namespace).UpdateStatus(ctx, currentCollector, metav1.UpdateOptions{})
+// enqueue converts a Collector into a namespace/name key and adds it to the workqueue.
+func (c *Controller) enqueue(object interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(object)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update Collector status: %w", err)
+		utilruntime.HandleError(err)
+
+		return
 	}
 
-	// Retrieve the updated Collector resource so that we have the most recent version and UID
-	// Otherwise, the next time we try to update the status, we will get a conflict error
-	updatedCollector, err = c.resourceclientset.ExampleV1alpha().Collectors(updatedCollector.Namespace).Get(ctx, updatedCollector.Name, metav1.GetOptions{})
+	c.workqueue.Add(key)
+}
+
+// enqueueReadiness converts a Collector into a namespace/name key and adds it to the readiness queue.
+func (c *Controller) enqueueReadiness(object interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(object)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get updated resource Collector: %w", err)
+		utilruntime.HandleError(err)
+
+		return
 	}
 
-	return updatedCollector, nil
+	c.readinessQueue.Add(key)
 }
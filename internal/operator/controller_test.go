@@ -0,0 +1,433 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+	collectorfake "kube8-operator/pkg/generated/clientset/versioned/fake"
+	collectorinformers "kube8-operator/pkg/generated/informers/externalversions"
+)
+
+// testListKinds are the list kinds of the resources the fake dynamic client serves.
+var testListKinds = map[schema.GroupVersionResource]string{ // nolint: gochecknoglobals
+	{Version: "v1", Resource: "configmaps"}:                 "ConfigMapList",
+	{Version: "v1", Resource: "secrets"}:                    "SecretList",
+	{Version: "v1", Resource: "services"}:                   "ServiceList",
+	{Group: "apps", Version: "v1", Resource: "deployments"}: "DeploymentList",
+	{Group: "apps", Version: "v1", Resource: "daemonsets"}:  "DaemonSetList",
+}
+
+// testAPIResources are the resources the fake discovery client reports, the same as testListKinds.
+var testAPIResources = []*metav1.APIResourceList{ // nolint: gochecknoglobals
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: metav1.Verbs{"list", "delete"}},
+			{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: metav1.Verbs{"list", "delete"}},
+			{Name: "services", Kind: "Service", Namespaced: true, Verbs: metav1.Verbs{"list", "delete"}},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: metav1.Verbs{"list", "delete"}},
+			{Name: "daemonsets", Kind: "DaemonSet", Namespaced: true, Verbs: metav1.Verbs{"list", "delete"}},
+		},
+	},
+}
+
+// newTestController returns a Controller backed by fake clients holding the objects. Collectors, Tenants and
+// CollectorSets are served by the collector clientset and added to the caches of their informers, unstructured
+// objects are served by the dynamic client and the other objects by the Kubernetes clientset.
+func newTestController(t *testing.T, objects ...runtime.Object) *Controller {
+	t.Helper()
+
+	var collectorObjects, dynamicObjects, kubeObjects []runtime.Object
+
+	for _, object := range objects {
+		switch object.(type) {
+		case *v1.Collector, *v1.Tenant, *v1.CollectorSet:
+			collectorObjects = append(collectorObjects, object)
+		case *unstructured.Unstructured:
+			dynamicObjects = append(dynamicObjects, object)
+		default:
+			kubeObjects = append(kubeObjects, object)
+		}
+	}
+
+	resourceClient := collectorfake.NewSimpleClientset(collectorObjects...)
+	kubeClient := kubefake.NewSimpleClientset(kubeObjects...)
+	kubeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = testAPIResources
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), testListKinds, dynamicObjects...)
+
+	informerFactory := collectorinformers.NewSharedInformerFactory(resourceClient, 0)
+	informer := informerFactory.Example().V1alpha().Collectors()
+	tenantInformer := informerFactory.Example().V1alpha().Tenants()
+	collectorSetInformer := informerFactory.Example().V1alpha().CollectorSets()
+
+	controller := &Controller{
+		kubeclientset:        kubeClient,
+		resourceclientset:    resourceClient,
+		dynamicclientset:     dynamicClient,
+		informer:             informer.Informer(),
+		lister:               informer.Lister(),
+		recorder:             record.NewFakeRecorder(100),
+		workqueue:            workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		releaseWorkloads:     newReleaseWorkloads(),
+		readinessQueue:       workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		driftQueue:           workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		tenantInformer:       tenantInformer.Informer(),
+		tenantLister:         tenantInformer.Lister(),
+		tenantQueue:          workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		tenantsServed:        true,
+		collectorSetInformer: collectorSetInformer.Informer(),
+		collectorSetLister:   collectorSetInformer.Lister(),
+		collectorSetQueue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		collectorSetsServed:  true,
+		configurationSources: NewConfigurationSources(kubeClient),
+		workers:              1,
+		shutdownGracePeriod:  time.Second,
+	}
+
+	err := informer.Informer().AddIndexers(cache.Indexers{
+		releaseIndex:       controller.collectorReleaseIndex,
+		uidIndex:           collectorUIDIndex,
+		configurationIndex: collectorConfigurationIndex,
+		tenantIndex:        collectorTenantIndex,
+		collectorSetIndex:  collectorSetOwnerIndex,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, object := range collectorObjects {
+		switch object.(type) {
+		case *v1.Tenant:
+			err = tenantInformer.Informer().GetIndexer().Add(object)
+		case *v1.CollectorSet:
+			err = collectorSetInformer.Informer().GetIndexer().Add(object)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Collectors are indexed once their Tenants are known, their release is in the namespace of the Tenant
+	for _, object := range collectorObjects {
+		if collector, ok := object.(*v1.Collector); ok {
+			if err = informer.Informer().GetIndexer().Add(collector); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	controller.reconciler = &CollectorReconciler{
+		Controller:     controller,
+		Storage:        &HelmStorage{Driver: StorageDriverMemory},
+		ReleaseTimeout: time.Minute,
+	}
+	controller.releaseObjects = newReleaseObjectInformers(dynamicClient, controller.releaseObjectEventHandler())
+
+	t.Cleanup(func() {
+		controller.workqueue.ShutDown()
+		controller.readinessQueue.ShutDown()
+		controller.driftQueue.ShutDown()
+		controller.tenantQueue.ShutDown()
+		controller.collectorSetQueue.ShutDown()
+	})
+
+	return controller
+}
+
+// testCollector returns a Collector of the fluent-bit collector for the acme tenant.
+func testCollector(finalizers ...string) *v1.Collector {
+	return &v1.Collector{
+		ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme", UID: "4a7d", Generation: 1, ResourceVersion: "1", Finalizers: finalizers},
+		Spec: v1.CollectorSpec{
+			Collector: v1.CollectorInfo{Name: "fluent-bit"},
+			Tenant:    v1.TenantInfo{Reference: "acme", Instance: "main"},
+		},
+	}
+}
+
+func TestProcessNextWorkItem(t *testing.T) {
+	tests := []struct {
+		name         string
+		item         interface{}
+		objects      []runtime.Object
+		failWrites   bool
+		shutDown     bool
+		wantContinue bool
+		wantRequeues int
+	}{
+		{name: "collector that no longer exists is forgotten", item: "acme/logs", wantContinue: true},
+		{name: "malformed key is forgotten", item: "acme/logs/extra", wantContinue: true},
+		{name: "item that isn't a key is dropped", item: 42, wantContinue: true},
+		{name: "failed reconcile is requeued", item: "acme/logs", objects: []runtime.Object{testCollector()}, failWrites: true, wantContinue: true, wantRequeues: 1},
+		{name: "queue shutting down stops the worker", item: "acme/logs", shutDown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestController(t, tt.objects...)
+
+			if tt.failWrites {
+				controller.resourceclientset.(*collectorfake.Clientset).PrependReactor("*", "collectors", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					if action.GetVerb() == "get" || action.GetVerb() == "list" {
+						return false, nil, nil
+					}
+
+					return true, nil, errors.New("API server unavailable")
+				})
+			}
+
+			controller.workqueue.Add(tt.item)
+
+			if tt.shutDown {
+				controller.workqueue.ShutDown()
+			}
+
+			if got := controller.processNextWorkItem(context.Background()); got != tt.wantContinue {
+				t.Errorf("processNextWorkItem() = %v, want %v", got, tt.wantContinue)
+			}
+
+			if got := controller.workqueue.NumRequeues(tt.item); got != tt.wantRequeues {
+				t.Errorf("requeues = %d, want %d", got, tt.wantRequeues)
+			}
+		})
+	}
+}
+
+func TestSetCollectorFinalizer(t *testing.T) {
+	tests := []struct {
+		name           string
+		finalizers     []string
+		present        bool
+		conflicts      int
+		wantPatches    int
+		wantFinalizers []string
+	}{
+		{name: "finalizer is added", present: true, wantPatches: 1, wantFinalizers: []string{collectorFinalizer}},
+		{name: "finalizer is added next to others", finalizers: []string{"other"}, present: true, wantPatches: 1, wantFinalizers: []string{"other", collectorFinalizer}},
+		{name: "finalizer that is present isn't patched", finalizers: []string{collectorFinalizer}, present: true, wantFinalizers: []string{collectorFinalizer}},
+		{name: "finalizer is removed", finalizers: []string{"other", collectorFinalizer}, wantPatches: 1, wantFinalizers: []string{"other"}},
+		{name: "conflict is retried on the latest collector", present: true, conflicts: 2, wantPatches: 3, wantFinalizers: []string{collectorFinalizer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := testCollector(tt.finalizers...)
+			controller := newTestController(t, collector)
+			clientset := controller.resourceclientset.(*collectorfake.Clientset)
+
+			patches := 0
+			clientset.PrependReactor("patch", "collectors", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				patches++
+
+				patch := action.(clienttesting.PatchAction)
+				if patch.GetPatchType() != types.MergePatchType || !strings.Contains(string(patch.GetPatch()), `"resourceVersion":"1"`) {
+					t.Errorf("patch = %s %s, want a merge patch guarded by the resource version", patch.GetPatchType(), patch.GetPatch())
+				}
+
+				if patches <= tt.conflicts {
+					return true, nil, apierrors.NewConflict(v1.Resource(v1.Plural), collector.Name, errors.New("the object has been modified"))
+				}
+
+				return false, nil, nil
+			})
+
+			patched, err := controller.setCollectorFinalizer(context.Background(), collector, tt.present)
+			if err != nil {
+				t.Fatalf("setCollectorFinalizer() error = %v", err)
+			}
+
+			if patches != tt.wantPatches {
+				t.Errorf("patches = %d, want %d", patches, tt.wantPatches)
+			}
+
+			stored, err := clientset.ExampleV1alpha().Collectors(collector.Namespace).Get(context.Background(), collector.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(stored.Finalizers, tt.wantFinalizers) || !slices.Equal(patched.Finalizers, tt.wantFinalizers) {
+				t.Errorf("finalizers = %v, returned %v, want %v", stored.Finalizers, patched.Finalizers, tt.wantFinalizers)
+			}
+		})
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	tests := []struct {
+		name           string
+		finalizers     []string
+		keepObjects    bool
+		wantErr        bool
+		wantFinalizers []string
+		wantTerminated bool
+	}{
+		{
+			name:           "objects are swept and the finalizer removed",
+			finalizers:     []string{collectorFinalizer, "other"},
+			wantFinalizers: []string{"other"},
+			wantTerminated: true,
+		},
+		{
+			name:           "finalizer is kept while objects remain",
+			finalizers:     []string{collectorFinalizer},
+			keepObjects:    true,
+			wantErr:        true,
+			wantFinalizers: []string{collectorFinalizer},
+			wantTerminated: true,
+		},
+		{
+			name:           "collector without the finalizer is left alone",
+			finalizers:     []string{"other"},
+			wantFinalizers: []string{"other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := metav1.Now()
+			collector := testCollector(tt.finalizers...)
+			collector.DeletionTimestamp = &deleted
+
+			configMap := &unstructured.Unstructured{}
+			configMap.SetAPIVersion("v1")
+			configMap.SetKind("ConfigMap")
+			configMap.SetName("fluent-bit-main")
+			configMap.SetNamespace("acme")
+			configMap.SetLabels(map[string]string{LabelCollectorUID: string(collector.UID)})
+
+			controller := newTestController(t, collector, configMap)
+
+			if tt.keepObjects {
+				controller.dynamicclientset.(*dynamicfake.FakeDynamicClient).PrependReactor("delete", "*", func(clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, nil
+				})
+			}
+
+			err := controller.syncHandler(context.Background(), "acme/logs")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			stored, err := controller.resourceclientset.ExampleV1alpha().Collectors("acme").Get(context.Background(), "logs", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(stored.Finalizers, tt.wantFinalizers) {
+				t.Errorf("finalizers = %v, want %v", stored.Finalizers, tt.wantFinalizers)
+			}
+
+			if terminated := meta.IsStatusConditionTrue(stored.Status.Conditions, typeTerminatingCollector); terminated != tt.wantTerminated {
+				t.Errorf("Terminating = %v, want %v", terminated, tt.wantTerminated)
+			}
+		})
+	}
+}
+
+func TestRunWorkersShutdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		finish      bool
+	}{
+		{name: "running reconcile is drained", gracePeriod: 10 * time.Second, finish: true},
+		{name: "running reconcile is abandoned once the grace period expires", gracePeriod: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestController(t, testCollector())
+			controller.shutdownGracePeriod = tt.gracePeriod
+
+			// The reconcile blocks on adding the finalizer until it is let finish
+			started := make(chan struct{})
+			finish := make(chan struct{})
+
+			var startOnce, finishOnce sync.Once
+
+			t.Cleanup(func() { finishOnce.Do(func() { close(finish) }) })
+
+			controller.resourceclientset.(*collectorfake.Clientset).PrependReactor("patch", "collectors", func(clienttesting.Action) (bool, runtime.Object, error) {
+				startOnce.Do(func() { close(started) })
+				<-finish
+
+				return true, nil, errors.New("API server unavailable")
+			})
+
+			controller.workqueue.Add("acme/logs")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan struct{})
+
+			go func() {
+				controller.runWorkers(ctx, context.Background())
+				close(done)
+			}()
+
+			select {
+			case <-started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("reconcile didn't start")
+			}
+
+			cancel()
+
+			if !tt.finish {
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatal("runWorkers() didn't return once the grace period expired")
+				}
+
+				return
+			}
+
+			select {
+			case <-done:
+				t.Fatal("runWorkers() returned before the running reconcile finished")
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			if !controller.workqueue.ShuttingDown() {
+				t.Error("workqueue isn't shutting down, want no new work handed out")
+			}
+
+			finishOnce.Do(func() { close(finish) })
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("runWorkers() didn't return once the running reconcile finished")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+	"kube8-operator/pkg/apis/collector/v1beta1"
+)
+
+// ConvertCollectorPath is the path the conversion webhook of the Collector CRD is served on.
+const ConvertCollectorPath = "/convert"
+
+// serveConvert converts Collectors between v1alpha and the v1beta1 storage version.
+func (s *WebhookServer) serveConvert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "unsupported method "+r.Method, http.StatusBadRequest)
+
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionReviewBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	review := &apiextensionsv1.ConversionReview{}
+	if err = json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "could not decode conversion review", http.StatusBadRequest)
+
+		return
+	}
+
+	response := &apiextensionsv1.ConversionResponse{UID: review.Request.UID, Result: metav1.Status{Status: metav1.StatusSuccess}}
+
+	for _, object := range review.Request.Objects {
+		converted, err := convertCollector(object.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+
+			break
+		}
+
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err = json.NewEncoder(w).Encode(&apiextensionsv1.ConversionReview{TypeMeta: review.TypeMeta, Response: response}); err != nil {
+		klog.Errorf("Could not write conversion review: %v", err)
+	}
+}
+
+// convertCollector converts the raw Collector to the API version.
+func convertCollector(raw []byte, apiVersion string) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("could not decode object: %w", err)
+	}
+
+	if typeMeta.APIVersion == apiVersion {
+		return raw, nil
+	}
+
+	alpha, beta := &v1alpha.Collector{}, &v1beta1.Collector{}
+
+	switch {
+	case typeMeta.APIVersion == v1alpha.SchemeGroupVersion.String() && apiVersion == v1beta1.SchemeGroupVersion.String():
+		if err := json.Unmarshal(raw, alpha); err != nil {
+			return nil, err
+		}
+
+		if err := alpha.ConvertTo(beta); err != nil {
+			return nil, fmt.Errorf("could not convert Collector %s/%s to %s: %w", alpha.Namespace, alpha.Name, apiVersion, err)
+		}
+
+		return json.Marshal(beta)
+	case typeMeta.APIVersion == v1beta1.SchemeGroupVersion.String() && apiVersion == v1alpha.SchemeGroupVersion.String():
+		if err := json.Unmarshal(raw, beta); err != nil {
+			return nil, err
+		}
+
+		if err := alpha.ConvertFrom(beta); err != nil {
+			return nil, fmt.Errorf("could not convert Collector %s/%s to %s: %w", beta.Namespace, beta.Name, apiVersion, err)
+		}
+
+		return json.Marshal(alpha)
+	default:
+		return nil, fmt.Errorf("unsupported conversion of %s %s to %s", typeMeta.APIVersion, typeMeta.Kind, apiVersion)
+	}
+}
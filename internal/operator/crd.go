@@ -0,0 +1,314 @@
+package operator
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+const (
+	// crdFieldManager is the field manager the operator applies its CRDs with.
+	crdFieldManager = "kube8-operator"
+	// crdEstablishTimeout is how long the operator waits for an applied CRD to be served.
+	crdEstablishTimeout = 30 * time.Second
+	// injectCAFromAnnotation asks the cert-manager CA injector to set the CA bundle of the conversion webhook.
+	injectCAFromAnnotation = "cert-manager.io/inject-ca-from"
+	// webhookServicePort is the port of the Service the API server reaches the webhooks through.
+	webhookServicePort = 443
+)
+
+// CRDConversion configures the conversion webhook of the Collector CRD installed by the operator, and the webhook
+// Service and admission webhook configurations installed along with it.
+type CRDConversion struct {
+	// ServiceName and ServiceNamespace are the Service the API server reaches the webhooks of the operator through.
+	ServiceName      string
+	ServiceNamespace string
+	// WebhookPort is the port the operator serves the webhooks on, which the Service targets.
+	WebhookPort int
+	// CABundleFile is the PEM CA bundle the API server verifies the webhook certificate with. When the file
+	// doesn't exist the CA bundle is left to be injected, e.g. by the cert-manager CA injector.
+	CABundleFile string
+}
+
+// validate checks that the webhooks can be reached through the Service.
+func (c CRDConversion) validate() error {
+	if c.ServiceName == "" || c.ServiceNamespace == "" {
+		return errors.New("installing the CRD needs the webhook service name and the operator namespace for its conversion webhook")
+	}
+
+	if c.WebhookPort <= 0 {
+		return errors.New("installing the CRD needs the webhook port for its conversion webhook")
+	}
+
+	return nil
+}
+
+// caBundle returns the CA bundle of the webhooks, nil when it is left to be injected.
+func (c CRDConversion) caBundle() ([]byte, error) {
+	caBundle, err := os.ReadFile(c.CABundleFile)
+
+	switch {
+	case err == nil:
+		return caBundle, nil
+	case errors.Is(err, fs.ErrNotExist):
+		klog.Infof("No CA bundle at %s, leaving the CA bundle of the webhooks to be injected", c.CABundleFile)
+
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("could not read the CA bundle of the webhooks: %w", err)
+	}
+}
+
+// webhookConversion returns the webhook conversion of the CRD.
+func (c CRDConversion) webhookConversion() (*apiextensionsv1.CustomResourceConversion, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	path := ConvertCollectorPath
+	port := int32(webhookServicePort)
+
+	clientConfig := &apiextensionsv1.WebhookClientConfig{
+		Service: &apiextensionsv1.ServiceReference{Namespace: c.ServiceNamespace, Name: c.ServiceName, Path: &path, Port: &port},
+	}
+
+	caBundle, err := c.caBundle()
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig.CABundle = caBundle
+
+	return &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook:  &apiextensionsv1.WebhookConversion{ClientConfig: clientConfig, ConversionReviewVersions: []string{"v1"}},
+	}, nil
+}
+
+// render replaces the placeholders of the manifests with the webhook Service and port.
+func (c CRDConversion) render(manifests []byte) []byte {
+	return []byte(strings.NewReplacer(
+		"${WEBHOOK_SERVICE_NAME}", c.ServiceName,
+		"${WEBHOOK_SERVICE_NAMESPACE}", c.ServiceNamespace,
+		"${WEBHOOK_PORT}", strconv.Itoa(c.WebhookPort),
+	).Replace(string(manifests)))
+}
+
+// collectorCRD, tenantCRD and collectorSetCRD are the CRDs generated from the API types by hack/update-crd.sh.
+//
+//go:embed crd.yaml
+var collectorCRD []byte // nolint: gochecknoglobals
+
+//go:embed tenant_crd.yaml
+var tenantCRD []byte // nolint: gochecknoglobals
+
+//go:embed collectorset_crd.yaml
+var collectorSetCRD []byte // nolint: gochecknoglobals
+
+// webhookManifests are the webhook Service and the admission webhook configurations of the Collectors.
+//
+//go:embed webhook.yaml
+var webhookManifests []byte // nolint: gochecknoglobals
+
+// webhookResources are the resources of the kinds of webhookManifests.
+var webhookResources = map[string]schema.GroupVersionResource{ // nolint: gochecknoglobals
+	"Service":                        {Version: "v1", Resource: "services"},
+	"MutatingWebhookConfiguration":   admissionregistrationv1.SchemeGroupVersion.WithResource("mutatingwebhookconfigurations"),
+	"ValidatingWebhookConfiguration": admissionregistrationv1.SchemeGroupVersion.WithResource("validatingwebhookconfigurations"),
+}
+
+// installCRDs installs or upgrades the Collector, Tenant and CollectorSet CRDs shipped with the operator and waits until they
+// are established. The Collector CRD converts between its versions with the conversion webhook of the operator, the
+// placeholders of its webhook Service are replaced with the Service of the operator.
+func installCRDs(ctx context.Context, client apiextensionsclientset.Interface, conversion CRDConversion) error {
+	webhookConversion, err := conversion.webhookConversion()
+	if err != nil {
+		return err
+	}
+
+	if err = installCRD(ctx, client, conversion.render(collectorCRD), webhookConversion); err != nil {
+		return err
+	}
+
+	if err = installCRD(ctx, client, tenantCRD, nil); err != nil {
+		return err
+	}
+
+	return installCRD(ctx, client, collectorSetCRD, nil)
+}
+
+// installCRD installs or upgrades the CRD, with the conversion when it has one, and waits until it is established.
+// It is applied server side, so replicas starting together apply the same CRD without conflicting.
+func installCRD(ctx context.Context, client apiextensionsclientset.Interface, data []byte, conversion *apiextensionsv1.CustomResourceConversion) error {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(data, crd); err != nil {
+		return fmt.Errorf("could not decode CRD: %w", err)
+	}
+
+	crd.Spec.Conversion = conversion
+
+	// A CA bundle set by the operator isn't left for the CA injector to overwrite
+	if conversion != nil && conversion.Webhook != nil && len(conversion.Webhook.ClientConfig.CABundle) != 0 {
+		delete(crd.Annotations, injectCAFromAnnotation)
+	}
+
+	data, err := json.Marshal(crd)
+	if err != nil {
+		return err
+	}
+
+	force := true
+
+	_, err = client.ApiextensionsV1().CustomResourceDefinitions().Patch(ctx, crd.Name, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: crdFieldManager, Force: &force})
+	if err != nil {
+		return fmt.Errorf("could not apply CRD %s: %w", crd.Name, err)
+	}
+
+	klog.Infof("Applied CRD %s, waiting for it to be established", crd.Name)
+
+	err = wait.PollUntilContextTimeout(ctx, time.Second, crdEstablishTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crd.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == apiextensionsv1.NamesAccepted && condition.Status == apiextensionsv1.ConditionFalse {
+				return false, fmt.Errorf("names of CRD %s not accepted: %s", crd.Name, condition.Message)
+			}
+
+			if condition.Type == apiextensionsv1.Established && condition.Status == apiextensionsv1.ConditionTrue {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("CRD %s was not established: %w", crd.Name, err)
+	}
+
+	return nil
+}
+
+// installWebhooks installs or upgrades the webhook Service and the admission webhook configurations of the
+// Collectors, pointing at the webhook Service in the operator namespace. Like the CRDs they are applied server side.
+func installWebhooks(ctx context.Context, client dynamic.Interface, conversion CRDConversion) error {
+	if err := conversion.validate(); err != nil {
+		return err
+	}
+
+	caBundle, err := conversion.caBundle()
+	if err != nil {
+		return err
+	}
+
+	manifests := releaseutil.SplitManifests(string(conversion.render(webhookManifests)))
+
+	// The Service is applied before the webhook configurations that point at it
+	keys := slices.Collect(maps.Keys(manifests))
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	for _, key := range keys {
+		object := &unstructured.Unstructured{}
+		if err = yaml.Unmarshal([]byte(manifests[key]), &object.Object); err != nil {
+			return fmt.Errorf("could not decode webhook manifest: %w", err)
+		}
+
+		if len(object.Object) == 0 {
+			continue
+		}
+
+		gvr, ok := webhookResources[object.GetKind()]
+		if !ok {
+			return fmt.Errorf("unexpected webhook manifest of kind %s", object.GetKind())
+		}
+
+		if err = setWebhooksCABundle(object, caBundle); err != nil {
+			return err
+		}
+
+		_, err = client.Resource(gvr).Namespace(object.GetNamespace()).Apply(ctx, object.GetName(), object, metav1.ApplyOptions{FieldManager: crdFieldManager, Force: true})
+		if err != nil {
+			return fmt.Errorf("could not apply %s %s: %w", object.GetKind(), object.GetName(), err)
+		}
+
+		klog.Infof("Applied %s %s", object.GetKind(), object.GetName())
+	}
+
+	return nil
+}
+
+// setWebhooksCABundle sets the CA bundle of the webhooks of a webhook configuration. A CA bundle set by the operator
+// isn't left for the CA injector to overwrite.
+func setWebhooksCABundle(object *unstructured.Unstructured, caBundle []byte) error {
+	webhooks, ok, err := unstructured.NestedSlice(object.Object, "webhooks")
+	if err != nil || !ok || caBundle == nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		webhookObject, ok := webhook.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected webhook of %s %s", object.GetKind(), object.GetName())
+		}
+
+		if err = unstructured.SetNestedField(webhookObject, base64.StdEncoding.EncodeToString(caBundle), "clientConfig", "caBundle"); err != nil {
+			return err
+		}
+	}
+
+	annotations := object.GetAnnotations()
+	delete(annotations, injectCAFromAnnotation)
+	object.SetAnnotations(annotations)
+
+	return unstructured.SetNestedSlice(object.Object, webhooks, "webhooks")
+}
+
+// checkCRDConversion fails when the served Collector CRD converts between its versions with the conversion webhook
+// of the operator, but the operator doesn't serve the webhooks. Collectors are stored as v1beta1, so every read of
+// v1alpha would fail.
+func checkCRDConversion(ctx context.Context, client apiextensionsclientset.Interface, conversion CRDConversion) error {
+	crd, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, v1alpha.Plural+"."+v1alpha.GroupName, metav1.GetOptions{})
+	// Without the CRD there is nothing to convert, the informer reports it
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not get the Collector CRD: %w", err)
+	}
+
+	if crd.Spec.Conversion != nil && crd.Spec.Conversion.Strategy == apiextensionsv1.WebhookConverter && conversion.WebhookPort <= 0 {
+		return fmt.Errorf("CRD %s converts its versions with the conversion webhook of the operator, which needs the webhook port", crd.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+func TestGeneratedCRDs(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           []byte
+		wantName       string
+		wantScope      apiextensionsv1.ResourceScope
+		wantShortNames []string
+		wantVersions   []string
+		wantStorage    string
+		wantColumns    []string
+	}{
+		{
+			name:           "collector",
+			data:           collectorCRD,
+			wantName:       v1.Plural + "." + v1.GroupName,
+			wantScope:      apiextensionsv1.NamespaceScoped,
+			wantShortNames: []string{"col"},
+			wantVersions:   []string{"v1alpha", "v1beta1"},
+			wantStorage:    "v1beta1",
+			wantColumns:    []string{"Collector", "Tenant", "Version", "Ready", "Status", "Age"},
+		},
+		{
+			name:         "tenant",
+			data:         tenantCRD,
+			wantName:     v1.TenantPlural + "." + v1.GroupName,
+			wantScope:    apiextensionsv1.ClusterScoped,
+			wantVersions: []string{"v1alpha"},
+			wantStorage:  "v1alpha",
+		},
+		{
+			name:         "collector set",
+			data:         collectorSetCRD,
+			wantName:     v1.CollectorSetPlural + "." + v1.GroupName,
+			wantScope:    apiextensionsv1.NamespaceScoped,
+			wantVersions: []string{"v1alpha"},
+			wantStorage:  "v1alpha",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := yaml.UnmarshalStrict(tt.data, crd); err != nil {
+				t.Fatalf("could not decode CRD: %v", err)
+			}
+
+			if crd.Name != tt.wantName || crd.Spec.Scope != tt.wantScope {
+				t.Errorf("CRD = %s %s, want %s %s", crd.Spec.Scope, crd.Name, tt.wantScope, tt.wantName)
+			}
+
+			if tt.wantShortNames != nil && !slices.Equal(crd.Spec.Names.ShortNames, tt.wantShortNames) {
+				t.Errorf("short names = %v, want %v", crd.Spec.Names.ShortNames, tt.wantShortNames)
+			}
+
+			var versions []string
+
+			for _, version := range crd.Spec.Versions {
+				versions = append(versions, version.Name)
+
+				if !version.Served || version.Storage != (version.Name == tt.wantStorage) {
+					t.Errorf("version %s served = %v, storage = %v, want served and stored as %s", version.Name, version.Served, version.Storage, tt.wantStorage)
+				}
+
+				if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil || version.Schema.OpenAPIV3Schema.Properties["spec"].Type != "object" {
+					t.Errorf("version %s has no OpenAPI schema of its spec", version.Name)
+				}
+
+				if version.Subresources == nil || version.Subresources.Status == nil {
+					t.Errorf("version %s has no status subresource", version.Name)
+				}
+
+				if tt.wantColumns != nil {
+					var columns []string
+					for _, column := range version.AdditionalPrinterColumns {
+						columns = append(columns, column.Name)
+					}
+
+					if !slices.Equal(columns, tt.wantColumns) {
+						t.Errorf("version %s printer columns = %v, want %v", version.Name, columns, tt.wantColumns)
+					}
+				}
+			}
+
+			if !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestInstallCRDs(t *testing.T) {
+	tests := []struct {
+		name         string
+		conversion   CRDConversion
+		caBundle     string
+		notAccepted  string
+		wantErr      bool
+		wantInjected bool
+	}{
+		{
+			name:         "CA bundle is left to be injected",
+			conversion:   CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators", WebhookPort: 9443},
+			wantInjected: true,
+		},
+		{
+			name:       "CA bundle is set",
+			conversion: CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators", WebhookPort: 9443},
+			caBundle:   "-----BEGIN CERTIFICATE-----",
+		},
+		{
+			name:        "CRD whose names aren't accepted",
+			conversion:  CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators", WebhookPort: 9443},
+			notAccepted: v1.TenantPlural + "." + v1.GroupName,
+			wantErr:     true,
+		},
+		{
+			name:       "conversion webhook without a Service",
+			conversion: CRDConversion{WebhookPort: 9443},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conversion.CABundleFile = filepath.Join(t.TempDir(), "ca.crt")
+			if tt.caBundle != "" {
+				if err := os.WriteFile(tt.conversion.CABundleFile, []byte(tt.caBundle), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			client := apiextensionsfake.NewSimpleClientset()
+
+			// The API server establishes the applied CRDs, or refuses their names
+			client.PrependReactor("patch", "customresourcedefinitions", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				patch := action.(clienttesting.PatchAction)
+				if patch.GetPatchType() != types.ApplyPatchType {
+					t.Errorf("patch type = %s, want apply", patch.GetPatchType())
+				}
+
+				crd := &apiextensionsv1.CustomResourceDefinition{}
+				if err := json.Unmarshal(patch.GetPatch(), crd); err != nil {
+					t.Fatal(err)
+				}
+
+				condition := apiextensionsv1.CustomResourceDefinitionCondition{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}
+				if crd.Name == tt.notAccepted {
+					condition = apiextensionsv1.CustomResourceDefinitionCondition{Type: apiextensionsv1.NamesAccepted, Status: apiextensionsv1.ConditionFalse, Message: "the plural is in use"}
+				}
+
+				crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{condition}
+
+				return true, crd, client.Tracker().Add(crd)
+			})
+
+			err := installCRDs(context.Background(), client, tt.conversion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("installCRDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			crds, err := client.ApiextensionsV1().CustomResourceDefinitions().List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(crds.Items) != 3 {
+				t.Errorf("installed %d CRDs, want the Collector, Tenant and CollectorSet CRDs", len(crds.Items))
+			}
+
+			collector, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(context.Background(), v1.Plural+"."+v1.GroupName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conversion := collector.Spec.Conversion
+			if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter || conversion.Webhook.ClientConfig.Service.Name != "collectors-webhook" ||
+				conversion.Webhook.ClientConfig.Service.Namespace != "operators" || string(conversion.Webhook.ClientConfig.CABundle) != tt.caBundle {
+				t.Errorf("conversion = %+v, want the webhook of the collectors-webhook Service in operators with CA bundle %q", conversion, tt.caBundle)
+			}
+
+			if injectFrom, injected := collector.Annotations[injectCAFromAnnotation]; injected != tt.wantInjected || injected && injectFrom != "operators/collectors-webhook" {
+				t.Errorf("annotations = %v, want the CA bundle injected %v from operators/collectors-webhook", collector.Annotations, tt.wantInjected)
+			}
+		})
+	}
+}
+
+func TestCheckCRDConversion(t *testing.T) {
+	webhookConversion := &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.WebhookConverter}
+
+	tests := []struct {
+		name        string
+		conversion  *apiextensionsv1.CustomResourceConversion
+		missing     bool
+		webhookPort int
+		wantErr     bool
+	}{
+		{name: "webhook conversion without a webhook port", conversion: webhookConversion, wantErr: true},
+		{name: "webhook conversion with a webhook port", conversion: webhookConversion, webhookPort: 9443},
+		{name: "no conversion", conversion: &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}},
+		{name: "CRD that isn't installed", missing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := apiextensionsfake.NewSimpleClientset()
+			if !tt.missing {
+				crd := &apiextensionsv1.CustomResourceDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: v1.Plural + "." + v1.GroupName},
+					Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Conversion: tt.conversion},
+				}
+				if err := client.Tracker().Add(crd); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := checkCRDConversion(context.Background(), client, CRDConversion{WebhookPort: tt.webhookPort})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkCRDConversion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderCRD(t *testing.T) {
+	rendered := CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators"}.render(collectorCRD)
+
+	if strings.Contains(string(rendered), "${") {
+		t.Errorf("rendered CRD has placeholders left:\n%s", rendered)
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(rendered, crd); err != nil {
+		t.Fatal(err)
+	}
+
+	if injectFrom := crd.Annotations[injectCAFromAnnotation]; injectFrom != "operators/collectors-webhook" {
+		t.Errorf("%s annotation = %q, want operators/collectors-webhook", injectCAFromAnnotation, injectFrom)
+	}
+}
@@ -0,0 +1,223 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"kube8-operator/internal/instrumentation"
+)
+
+// AWS credential sources of an S3 chart source.
+const (
+	// AWSCredentialsDefault uses the AWS default credential chain: environment variables, IRSA web identity,
+	// shared configuration and the instance or task role.
+	AWSCredentialsDefault = "default"
+	// AWSCredentialsStatic uses an access key read from a Secret or from environment variables.
+	AWSCredentialsStatic = "static"
+	// AWSCredentialsWebIdentity assumes a role with a projected service account token.
+	AWSCredentialsWebIdentity = "webIdentity"
+	// AWSCredentialsAnonymous sends unsigned requests, for public buckets.
+	AWSCredentialsAnonymous = "anonymous"
+)
+
+// SecretKeySelector selects a key of a Secret in the operator namespace.
+type SecretKeySelector struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// Credential is read either from a key of a Secret in the operator namespace or from an environment variable.
+type Credential struct {
+	Secret *SecretKeySelector `yaml:"secret"`
+	Env    string             `yaml:"env"`
+}
+
+// AWSCredentials configures how an S3 chart source authenticates.
+type AWSCredentials struct {
+	// Source is default, static, webIdentity or anonymous. The default credential chain is used when it's empty.
+	Source string `yaml:"source"`
+	// AccessKeyID, SecretAccessKey and the optional SessionToken are the access key of the static source.
+	AccessKeyID     *Credential `yaml:"accessKeyId"`
+	SecretAccessKey *Credential `yaml:"secretAccessKey"`
+	SessionToken    *Credential `yaml:"sessionToken"`
+	// RoleARN and WebIdentityTokenFile configure the webIdentity source, they default to the AWS_ROLE_ARN and
+	// AWS_WEB_IDENTITY_TOKEN_FILE environment variables that IRSA injects.
+	RoleARN              string `yaml:"roleArn"`
+	WebIdentityTokenFile string `yaml:"webIdentityTokenFile"`
+}
+
+// SecretStore reads credentials from the Secrets of the operator namespace. The Secrets are watched rather
+// than read once, so a rotated credential is used as soon as its Secret is updated.
+type SecretStore struct {
+	namespace string
+	informer  cache.SharedIndexInformer
+	// referenced is set once a credential reads from a Secret, the Secrets are only watched when it is.
+	referenced bool
+}
+
+// NewSecretStore creates a store for the Secrets of the namespace. Nothing is watched until the store is run.
+func NewSecretStore(client kubernetes.Interface, namespace string) *SecretStore {
+	listWatch := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), "secrets", namespace, fields.Everything())
+
+	return &SecretStore{
+		namespace: namespace,
+		informer:  cache.NewSharedIndexInformer(listWatch, &corev1.Secret{}, resyncePeriod, cache.Indexers{}),
+	}
+}
+
+// reference records that a credential reads from the Secret.
+func (s *SecretStore) reference(selector *SecretKeySelector) error {
+	if selector.Name == "" || selector.Key == "" {
+		return errors.New("a secret credential needs both a name and a key")
+	}
+
+	if s == nil || s.namespace == "" {
+		return fmt.Errorf("secret %s can't be read, the operator namespace is not set", selector.Name)
+	}
+
+	s.referenced = true
+
+	return nil
+}
+
+// get returns the value of the key of the Secret.
+func (s *SecretStore) get(name string, key string) (string, error) {
+	item, exists, err := s.informer.GetStore().GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		return "", fmt.Errorf("secret %s/%s not found", s.namespace, name)
+	}
+
+	secret, ok := item.(*corev1.Secret)
+	if !ok {
+		return "", fmt.Errorf("unexpected object %T in the secret store", item)
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", s.namespace, name, key)
+	}
+
+	return string(value), nil
+}
+
+// validate checks that the credential has a source and records the Secret it reads from.
+func (c *Credential) validate(secrets *SecretStore) error {
+	if c.Secret != nil {
+		return secrets.reference(c.Secret)
+	}
+
+	if c.Env == "" {
+		return errors.New("a credential needs either a secret or an env")
+	}
+
+	return nil
+}
+
+// value reads the credential. It is read on every use, so that rotated credentials are picked up.
+func (c *Credential) value(secrets *SecretStore) (string, error) {
+	switch {
+	case c == nil:
+		return "", nil
+	case c.Secret != nil:
+		return secrets.get(c.Secret.Name, c.Secret.Key)
+	default:
+		return os.Getenv(c.Env), nil
+	}
+}
+
+// provider creates the AWS credentials provider of the configured source.
+// nolint: ireturn
+func (a *AWSCredentials) provider(region string, secrets *SecretStore) (aws.CredentialsProvider, error) {
+	switch a.Source {
+	case "", AWSCredentialsDefault:
+		// The default chain is resolved once, its credentials are cached and refreshed before they expire
+		awsConfig, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region), config.WithHTTPClient(instrumentation.InstrumentHTTPClient(&http.Client{})))
+		if err != nil {
+			return nil, fmt.Errorf("could not load the default AWS credential chain: %w", err)
+		}
+
+		return awsConfig.Credentials, nil
+	case AWSCredentialsStatic:
+		return a.staticProvider(secrets)
+	case AWSCredentialsWebIdentity:
+		roleARN, tokenFile := a.RoleARN, a.WebIdentityTokenFile
+		if roleARN == "" {
+			roleARN = os.Getenv("AWS_ROLE_ARN")
+		}
+
+		if tokenFile == "" {
+			tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+		}
+
+		if roleARN == "" || tokenFile == "" {
+			return nil, errors.New("web identity credentials need a role ARN and a token file")
+		}
+
+		stsClient := sts.New(sts.Options{Region: region, HTTPClient: instrumentation.InstrumentHTTPClient(&http.Client{})})
+
+		// The token file is read again every time the credentials are refreshed, kubelet rotates it in place
+		return aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(stsClient, roleARN, stscreds.IdentityTokenFile(tokenFile))), nil
+	case AWSCredentialsAnonymous:
+		return aws.AnonymousCredentials{}, nil
+	default:
+		return nil, fmt.Errorf("unknown AWS credentials source %q", a.Source)
+	}
+}
+
+// staticProvider reads the access key on every request rather than caching it, so that a rotated Secret
+// is used right away.
+// nolint: ireturn
+func (a *AWSCredentials) staticProvider(secrets *SecretStore) (aws.CredentialsProvider, error) {
+	if a.AccessKeyID == nil || a.SecretAccessKey == nil {
+		return nil, errors.New("static credentials need an access key id and a secret access key")
+	}
+
+	for _, credential := range []*Credential{a.AccessKeyID, a.SecretAccessKey, a.SessionToken} {
+		if credential == nil {
+			continue
+		}
+
+		if err := credential.validate(secrets); err != nil {
+			return nil, err
+		}
+	}
+
+	return aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		accessKeyID, err := a.AccessKeyID.value(secrets)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+
+		secretAccessKey, err := a.SecretAccessKey.value(secrets)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+
+		sessionToken, err := a.SessionToken.value(secrets)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+
+		if accessKeyID == "" || secretAccessKey == "" {
+			return aws.Credentials{}, errors.New("the static AWS access key is empty")
+		}
+
+		return aws.Credentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken, Source: "SecretStore"}, nil
+	}), nil
+}
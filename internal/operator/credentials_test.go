@@ -0,0 +1,198 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestSecretStore returns a secret store of the kube8-operator namespace holding the Secrets.
+func newTestSecretStore(t *testing.T, secrets ...*corev1.Secret) *SecretStore {
+	t.Helper()
+
+	store := NewSecretStore(kubefake.NewSimpleClientset(), "kube8-operator")
+	for _, secret := range secrets {
+		if err := store.informer.GetStore().Add(secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store
+}
+
+// testSecret returns a Secret of the kube8-operator namespace with the data.
+func testSecret(name string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube8-operator"}, Data: map[string][]byte{}}
+	for key, value := range data {
+		secret.Data[key] = []byte(value)
+	}
+
+	return secret
+}
+
+func TestCredentialValue(t *testing.T) {
+	t.Setenv("KUBE8_TEST_TOKEN", "from-env")
+
+	secrets := newTestSecretStore(t, testSecret("github", map[string]string{"token": "from-secret"}))
+
+	tests := []struct {
+		name       string
+		credential *Credential
+		want       string
+		wantErr    bool
+	}{
+		{name: "no credential", want: ""},
+		{name: "secret", credential: &Credential{Secret: &SecretKeySelector{Name: "github", Key: "token"}}, want: "from-secret"},
+		{name: "environment variable", credential: &Credential{Env: "KUBE8_TEST_TOKEN"}, want: "from-env"},
+		{name: "unset environment variable", credential: &Credential{Env: "KUBE8_TEST_UNSET"}, want: ""},
+		{name: "missing secret", credential: &Credential{Secret: &SecretKeySelector{Name: "gitlab", Key: "token"}}, wantErr: true},
+		{name: "missing key", credential: &Credential{Secret: &SecretKeySelector{Name: "github", Key: "password"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.credential.value(secrets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("value() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("value() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		credential     Credential
+		namespace      string
+		wantErr        bool
+		wantReferenced bool
+	}{
+		{name: "secret", credential: Credential{Secret: &SecretKeySelector{Name: "github", Key: "token"}}, namespace: "kube8-operator", wantReferenced: true},
+		{name: "environment variable", credential: Credential{Env: "GITHUB_TOKEN"}, namespace: "kube8-operator"},
+		{name: "secret without a key", credential: Credential{Secret: &SecretKeySelector{Name: "github"}}, namespace: "kube8-operator", wantErr: true},
+		{name: "secret without the operator namespace", credential: Credential{Secret: &SecretKeySelector{Name: "github", Key: "token"}}, wantErr: true},
+		{name: "neither secret nor environment variable", namespace: "kube8-operator", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := NewSecretStore(kubefake.NewSimpleClientset(), tt.namespace)
+
+			if err := tt.credential.validate(secrets); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if secrets.referenced != tt.wantReferenced {
+				t.Errorf("secrets watched = %v, want %v", secrets.referenced, tt.wantReferenced)
+			}
+		})
+	}
+}
+
+func TestAWSCredentialsProvider(t *testing.T) {
+	t.Setenv("AWS_ROLE_ARN", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+
+	secrets := newTestSecretStore(t, testSecret("aws", map[string]string{"id": "AKIA", "secret": "s3cret", "empty": ""}))
+	accessKeyID := &Credential{Secret: &SecretKeySelector{Name: "aws", Key: "id"}}
+	secretAccessKey := &Credential{Secret: &SecretKeySelector{Name: "aws", Key: "secret"}}
+
+	tests := []struct {
+		name            string
+		credentials     AWSCredentials
+		wantErr         bool
+		wantRetrieveErr bool
+		want            aws.Credentials
+	}{
+		{
+			name:        "static access key from a secret",
+			credentials: AWSCredentials{Source: AWSCredentialsStatic, AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey},
+			want:        aws.Credentials{AccessKeyID: "AKIA", SecretAccessKey: "s3cret", Source: "SecretStore"},
+		},
+		{
+			name:        "static access key without a secret access key",
+			credentials: AWSCredentials{Source: AWSCredentialsStatic, AccessKeyID: accessKeyID},
+			wantErr:     true,
+		},
+		{
+			name:            "static access key that is empty",
+			credentials:     AWSCredentials{Source: AWSCredentialsStatic, AccessKeyID: &Credential{Secret: &SecretKeySelector{Name: "aws", Key: "empty"}}, SecretAccessKey: secretAccessKey},
+			wantRetrieveErr: true,
+		},
+		{
+			name:            "static access key from a missing secret",
+			credentials:     AWSCredentials{Source: AWSCredentialsStatic, AccessKeyID: &Credential{Secret: &SecretKeySelector{Name: "s3", Key: "id"}}, SecretAccessKey: secretAccessKey},
+			wantRetrieveErr: true,
+		},
+		{name: "web identity without a role", credentials: AWSCredentials{Source: AWSCredentialsWebIdentity}, wantErr: true},
+		{name: "anonymous", credentials: AWSCredentials{Source: AWSCredentialsAnonymous}},
+		{name: "unknown source", credentials: AWSCredentials{Source: "vault"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := tt.credentials.provider("us-west-2", secrets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("provider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			// Anonymous credentials can't be retrieved, the SDK sends the requests unsigned
+			if tt.credentials.Source == AWSCredentialsAnonymous {
+				if _, ok := provider.(aws.AnonymousCredentials); !ok {
+					t.Errorf("provider() = %T, want anonymous credentials", provider)
+				}
+
+				return
+			}
+
+			got, err := provider.Retrieve(context.Background())
+			if (err != nil) != tt.wantRetrieveErr {
+				t.Fatalf("Retrieve() error = %v, wantErr %v", err, tt.wantRetrieveErr)
+			}
+
+			if err == nil && tt.credentials.Source == AWSCredentialsStatic && got != tt.want {
+				t.Errorf("Retrieve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticCredentialsRotation(t *testing.T) {
+	secrets := newTestSecretStore(t, testSecret("aws", map[string]string{"id": "AKIA1", "secret": "s3cret"}))
+
+	provider, err := (&AWSCredentials{
+		Source:          AWSCredentialsStatic,
+		AccessKeyID:     &Credential{Secret: &SecretKeySelector{Name: "aws", Key: "id"}},
+		SecretAccessKey: &Credential{Secret: &SecretKeySelector{Name: "aws", Key: "secret"}},
+	}).provider("us-west-2", secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, accessKeyID := range []string{"AKIA1", "AKIA2"} {
+		if err = secrets.informer.GetStore().Update(testSecret("aws", map[string]string{"id": accessKeyID, "secret": "s3cret"})); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := provider.Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.AccessKeyID != accessKeyID {
+			t.Errorf("Retrieve() access key = %s, want the rotated %s", got.AccessKeyID, accessKeyID)
+		}
+	}
+}
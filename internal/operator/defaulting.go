@@ -0,0 +1,211 @@
+package operator
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// MutateCollectorPath is the path the defaulting webhook of the Collectors is served on.
+const MutateCollectorPath = "/mutate-collector"
+
+// Standard labels the defaulting webhook puts on every Collector, so that collectors can be selected by tenant,
+// collector and instance.
+const (
+	LabelTenantID  = v1alpha.GroupName + "/tenant-id"
+	LabelCollector = v1alpha.GroupName + "/collector"
+	LabelInstance  = v1alpha.GroupName + "/instance"
+)
+
+// CollectorDefaulter fills in the parts of a Collector spec that can be derived from the operator configuration.
+type CollectorDefaulter struct {
+	cluster string
+	version string
+}
+
+// NewCollectorDefaulter creates a defaulter that sets Spec.Cluster to the cluster and Spec.Collector.Version to
+// the version channel when they are empty. An empty cluster or version leaves the field alone.
+func NewCollectorDefaulter(cluster string, version string) *CollectorDefaulter {
+	return &CollectorDefaulter{cluster: cluster, version: version}
+}
+
+// Default sets the defaults on the Collector.
+func (d *CollectorDefaulter) Default(resource *v1alpha.Collector) {
+	if resource.Spec.Cluster == "" {
+		resource.Spec.Cluster = d.cluster
+	}
+
+	if strings.TrimSpace(resource.Spec.Collector.Version) == "" {
+		resource.Spec.Collector.Version = d.version
+	}
+
+	// The tenant reference is the namespace of the collector, storing it lowercased keeps the two the same
+	resource.Spec.Tenant.Reference = strings.ToLower(resource.Spec.Tenant.Reference)
+
+	for label, value := range collectorLabels(resource) {
+		if resource.Labels == nil {
+			resource.Labels = map[string]string{}
+		}
+
+		resource.Labels[label] = value
+	}
+}
+
+// collectorLabels returns the standard labels of the Collector, leaving out values that aren't valid label values.
+func collectorLabels(resource *v1alpha.Collector) map[string]string {
+	labels := map[string]string{}
+
+	for label, value := range map[string]string{
+		LabelTenantID:  resource.Spec.Tenant.ID,
+		LabelCollector: resource.Spec.Collector.Name,
+		LabelInstance:  resource.Spec.Tenant.Instance,
+	} {
+		if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+			labels[label] = value
+		}
+	}
+
+	return labels
+}
+
+// serveMutate answers with a JSON patch that sets the defaults on the Collector. Deletes and Collectors being
+// deleted are left alone.
+func (s *WebhookServer) serveMutate(w http.ResponseWriter, r *http.Request) {
+	review, err := readAdmissionReview(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	response := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+
+	if review.Request.Operation == admissionv1.Delete {
+		writeAdmissionReview(w, review, response)
+
+		return
+	}
+
+	collector := &v1alpha.Collector{}
+	if err = json.Unmarshal(review.Request.Object.Raw, collector); err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest, Message: "could not decode Collector: " + err.Error()}
+
+		writeAdmissionReview(w, review, response)
+
+		return
+	}
+
+	if collector.DeletionTimestamp != nil {
+		writeAdmissionReview(w, review, response)
+
+		return
+	}
+
+	patch, err := defaultingPatch(review.Request.Object.Raw, collector, s.defaulter)
+	if err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError, Message: err.Error()}
+	} else if patch != nil {
+		patchType := admissionv1.PatchTypeJSONPatch
+		response.Patch, response.PatchType = patch, &patchType
+	}
+
+	writeAdmissionReview(w, review, response)
+}
+
+// jsonPatchOperation is an operation of an RFC 6902 JSON patch.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// defaultingPatch returns the JSON patch that sets the defaults on the raw object, or nil when nothing changes.
+func defaultingPatch(raw []byte, collector *v1alpha.Collector, defaulter *CollectorDefaulter) ([]byte, error) {
+	defaulted := collector.DeepCopy()
+	defaulter.Default(defaulted)
+
+	object := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, err
+	}
+
+	var operations []jsonPatchOperation
+
+	set := func(value interface{}, path ...string) {
+		operations = append(operations, setJSONPath(object, value, path)...)
+	}
+
+	if defaulted.Spec.Cluster != collector.Spec.Cluster {
+		set(defaulted.Spec.Cluster, "spec", "cluster")
+	}
+
+	if defaulted.Spec.Collector.Version != collector.Spec.Collector.Version {
+		set(defaulted.Spec.Collector.Version, "spec", "collector", "version")
+	}
+
+	if defaulted.Spec.Tenant.Reference != collector.Spec.Tenant.Reference {
+		set(defaulted.Spec.Tenant.Reference, "spec", "tenant", "reference")
+	}
+
+	for _, label := range slices.Sorted(maps.Keys(defaulted.Labels)) {
+		if collector.Labels[label] != defaulted.Labels[label] {
+			set(defaulted.Labels[label], "metadata", "labels", label)
+		}
+	}
+
+	if len(operations) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(operations)
+}
+
+// setJSONPath returns the operations that set the value at the path of the object, adding the parents that are
+// missing. The object is updated along, so that later paths see the parents added before them.
+func setJSONPath(object map[string]interface{}, value interface{}, path []string) []jsonPatchOperation {
+	pointer := ""
+	current := object
+
+	for i, segment := range path {
+		pointer += "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(segment)
+
+		if i == len(path)-1 {
+			current[segment] = value
+
+			// add replaces a member that exists already
+			return []jsonPatchOperation{{Op: "add", Path: pointer, Value: value}}
+		}
+
+		child, ok := current[segment].(map[string]interface{})
+		if !ok {
+			// The parent is missing or null, it is added with the rest of the path in it. The object gets its own
+			// copy, so that the value of the operation doesn't change when later paths are set in the parent.
+			nested := func() interface{} {
+				nested := value
+				for j := len(path) - 1; j > i; j-- {
+					nested = map[string]interface{}{path[j]: nested}
+				}
+
+				return nested
+			}
+
+			current[segment] = nested()
+
+			return []jsonPatchOperation{{Op: "add", Path: pointer, Value: nested()}}
+		}
+
+		current = child
+	}
+
+	return nil
+}
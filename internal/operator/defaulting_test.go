@@ -0,0 +1,95 @@
+package operator
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+func TestDefaultingPatch(t *testing.T) {
+	defaulter := NewCollectorDefaulter("eu-west", "stable")
+
+	tests := []struct {
+		name string
+		raw  string
+		// want is the expected JSON patch, empty when nothing is defaulted
+		want string
+	}{
+		{
+			name: "nothing to default",
+			raw:  `{"metadata":{"name":"logs"},"spec":{"cluster":"us-east","collector":{"name":"","version":"1.4"},"tenant":{"reference":"acme"}}}`,
+		},
+		{
+			name: "cluster and version are set",
+			raw:  `{"metadata":{"name":"logs"},"spec":{"cluster":"","collector":{"version":" "},"tenant":{"reference":"acme"}}}`,
+			want: `[{"op":"add","path":"/spec/cluster","value":"eu-west"},{"op":"add","path":"/spec/collector/version","value":"stable"}]`,
+		},
+		{
+			name: "missing parents are added",
+			raw:  `{"metadata":{"name":"logs"},"spec":{"tenant":{"reference":"acme"}}}`,
+			want: `[{"op":"add","path":"/spec/cluster","value":"eu-west"},{"op":"add","path":"/spec/collector","value":{"version":"stable"}}]`,
+		},
+		{
+			name: "missing spec is added once",
+			raw:  `{"metadata":{"name":"logs"}}`,
+			want: `[{"op":"add","path":"/spec","value":{"cluster":"eu-west"}},{"op":"add","path":"/spec/collector","value":{"version":"stable"}}]`,
+		},
+		{
+			name: "tenant reference is lowercased",
+			raw:  `{"metadata":{"name":"logs"},"spec":{"cluster":"us-east","collector":{"version":"1.4"},"tenant":{"reference":"ACME"}}}`,
+			want: `[{"op":"add","path":"/spec/tenant/reference","value":"acme"}]`,
+		},
+		{
+			name: "labels are added and escaped",
+			raw:  `{"metadata":{"name":"logs"},"spec":{"cluster":"us-east","collector":{"name":"fluent-bit","version":"1.4"},"tenant":{"id":"t-1","reference":"acme"}}}`,
+			want: `[{"op":"add","path":"/metadata/labels","value":{"` + v1alpha.GroupName + `/collector":"fluent-bit"}},` +
+				`{"op":"add","path":"/metadata/labels/` + v1alpha.GroupName + `~1tenant-id","value":"t-1"}]`,
+		},
+		{
+			name: "invalid label values are left out",
+			raw:  `{"metadata":{"name":"logs","labels":{"team":"a"}},"spec":{"cluster":"us-east","collector":{"name":"fluent bit","version":"1.4"},"tenant":{"reference":"acme"}}}`,
+		},
+		{
+			name: "existing labels are replaced",
+			raw:  `{"metadata":{"name":"logs","labels":{"` + v1alpha.GroupName + `/instance":"old"}},"spec":{"cluster":"us-east","collector":{"version":"1.4"},"tenant":{"instance":"new","reference":"acme"}}}`,
+			want: `[{"op":"add","path":"/metadata/labels/` + v1alpha.GroupName + `~1instance","value":"new"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &v1alpha.Collector{}
+			if err := json.Unmarshal([]byte(tt.raw), collector); err != nil {
+				t.Fatal(err)
+			}
+
+			patch, err := defaultingPatch([]byte(tt.raw), collector, defaulter)
+			if err != nil {
+				t.Fatalf("defaultingPatch() error = %v", err)
+			}
+
+			if tt.want == "" {
+				if patch != nil {
+					t.Errorf("defaultingPatch() = %s, want no patch", patch)
+				}
+
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(patch, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("defaultingPatch() = %s, want %s", patch, tt.want)
+			}
+		})
+	}
+}
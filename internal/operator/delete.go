@@ -2,53 +2,260 @@ package operator
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/discovery"
 	"k8s.io/klog/v2"
 
 	v1Controller "kube8-operator/pkg/apis/collector/v1alpha"
 )
 
-// DeleteCollector deletes a collector deployment, service, serviceMonitor, and secret.
-func (r *CollectorReconciler) DeleteCollector(ctx context.Context, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resource *v1Controller.Collector) error {
-	// Create names of resources being deleted which follows the naming convention of the release name in the create.go file
-	// {collector-name}-{tenant-instance} ex: cisco-amp-collector-main
-	releaseName := resource.Spec.Collector.Name + "-" + resource.Spec.Tenant.Instance
-	serviceName := releaseName + "-private"
+const (
+	// releaseNameAnnotation is the annotation Helm puts on every object it creates for a release.
+	releaseNameAnnotation = "meta.helm.sh/release-name"
+	// managedByLabel is the label Helm sets to managedByHelm on every object it creates for a release.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByHelm  = "Helm"
+	// resourcePolicyAnnotation marks objects that Helm keeps when the release is uninstalled.
+	resourcePolicyAnnotation = "helm.sh/resource-policy"
+	resourcePolicyKeep       = "keep"
+)
+
+// DeleteCollector uninstalls the Helm release of a collector. The release record is kept, marked as uninstalled,
+// so that the objects of its manifest can be checked by RemainingCollectorResources; PurgeCollectorRelease removes
+// it once they are gone. If the release record is missing, the objects labelled with the Collector or owned by its
+// release are deleted instead.
+// It is safe to call repeatedly while a collector is being finalized.
+func (r *CollectorReconciler) DeleteCollector(ctx context.Context, resource *v1Controller.Collector) error {
+	namespace := r.Controller.collectorNamespace(resource)
+	name := releaseName(resource)
 
-	// Delete Deployment
-	err := clientset.AppsV1().Deployments(resource.Namespace).Delete(ctx, releaseName, metav1.DeleteOptions{})
+	actionConfig, err := r.newActionConfiguration(namespace)
 	if err != nil {
 		return err
 	}
 
-	// Delete Secret
-	err = clientset.CoreV1().Secrets(resource.Namespace).Delete(ctx, releaseName, metav1.DeleteOptions{})
+	last, err := lastRelease(actionConfig, name)
 	if err != nil {
 		return err
 	}
 
-	// Delete Service
-	err = clientset.CoreV1().Services(resource.Namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+	if last == nil {
+		klog.Infof("No release record found for %s in namespace %s, sweeping its resources by label and ownership", name, namespace)
+
+		return r.sweepCollector(ctx, resource)
+	}
+
+	// A release uninstalled with its history kept has nothing left to delete
+	if last.Info.Status == release.StatusUninstalled {
+		return nil
+	}
+
+	uninstallAction := action.NewUninstall(actionConfig)
+	uninstallAction.KeepHistory = true
+
+	if _, err = uninstallAction.Run(name); err != nil {
+		return fmt.Errorf("could not uninstall release %s: %w", name, err)
+	}
+
+	klog.Infof("Successfully uninstalled release %s for resource: %s", name, resource.Name)
+
+	return nil
+}
+
+// PurgeCollectorRelease removes the history of the uninstalled release of a collector, unless it is kept.
+func (r *CollectorReconciler) PurgeCollectorRelease(resource *v1Controller.Collector) error {
+	if r.KeepHistory {
+		return nil
+	}
+
+	actionConfig, err := r.newActionConfiguration(r.Controller.collectorNamespace(resource))
 	if err != nil {
 		return err
 	}
 
-	// Because the service monitor is not a native Kubernetes resource, we need to use the dynamic client to delete it
-	err = dynamicClient.Resource(
-		schema.GroupVersionResource{
-			Group:    "monitoring.coreos.com",
-			Version:  "v1alpha",
-			Resource: "servicemonitors",
-		}).Namespace(resource.Namespace).Delete(ctx, releaseName, metav1.DeleteOptions{})
+	last, err := lastRelease(actionConfig, releaseName(resource))
+	if err != nil || last == nil {
+		return err
+	}
+
+	// Uninstalling a release that is already uninstalled purges its history
+	if _, err = action.NewUninstall(actionConfig).Run(last.Name); err != nil {
+		return fmt.Errorf("could not purge the history of release %s: %w", last.Name, err)
+	}
+
+	return nil
+}
+
+// RemainingCollectorResources returns the objects of a collector's release that still exist in the cluster. They
+// are the objects of the manifest of its last revision, only the kinds it holds are looked up. Without a release
+// record, they are the objects labelled with the Collector or owned by its release.
+func (r *CollectorReconciler) RemainingCollectorResources(ctx context.Context, resource *v1Controller.Collector) ([]string, error) {
+	actionConfig, err := r.newActionConfiguration(r.Controller.collectorNamespace(resource))
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := lastRelease(actionConfig, releaseName(resource))
+	if err != nil {
+		return nil, err
+	}
+
+	if last == nil {
+		objects, err := r.listCollectorObjects(ctx, resource)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := make([]string, 0, len(objects))
+		for _, object := range objects {
+			remaining = append(remaining, object.object.GetKind()+"/"+object.object.GetName())
+		}
+
+		return remaining, nil
+	}
+
+	var remaining []string
+
+	for _, manifest := range releaseutil.SplitManifests(last.Manifest) {
+		objects, err := actionConfig.KubeClient.Build(strings.NewReader(manifest), false)
+		// Objects of kinds that aren't served anymore, e.g. after their CRD was deleted, can't remain
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build the manifest of release %s: %w", last.Name, err)
+		}
+
+		for _, info := range objects {
+			// Objects the chart asks to keep outlive the release, just like Helm does on uninstall
+			if object, err := meta.Accessor(info.Object); err == nil && object.GetAnnotations()[resourcePolicyAnnotation] == resourcePolicyKeep {
+				continue
+			}
+
+			_, err = r.Controller.manifestObjectClient(info).Get(ctx, info.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("could not get %s %s: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
+			}
+
+			remaining = append(remaining, info.Mapping.GroupVersionKind.Kind+"/"+info.Name)
+		}
+	}
+
+	return remaining, nil
+}
+
+// releaseObject is an object in the cluster that belongs to a Helm release.
+type releaseObject struct {
+	gvr    schema.GroupVersionResource
+	object unstructured.Unstructured
+}
+
+// sweepCollector deletes the objects of the Collector in its tenant namespace, found by listCollectorObjects.
+func (r *CollectorReconciler) sweepCollector(ctx context.Context, resource *v1Controller.Collector) error {
+	objects, err := r.listCollectorObjects(ctx, resource)
 	if err != nil {
 		return err
 	}
 
-	klog.Infof("Successfully deleted all components for resource: %s", resource.Name)
+	namespace := r.Controller.collectorNamespace(resource)
+	propagation := metav1.DeletePropagationBackground
+
+	for _, object := range objects {
+		err = r.Controller.dynamicclientset.Resource(object.gvr).Namespace(namespace).Delete(ctx, object.object.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete %s %s: %w", object.gvr.Resource, object.object.GetName(), err)
+		}
+	}
+
+	klog.Infof("Swept %d objects of collector %s/%s in namespace %s", len(objects), resource.Namespace, resource.Name, namespace)
 
 	return nil
 }
+
+// listCollectorObjects lists the objects of every namespaced resource type in the tenant namespace that belong to
+// the Collector: those that carry its UID label, and those Helm created for its release, which carry the Helm
+// ownership label and annotations but may predate the UID label. Without a release manifest every type has to be
+// listed, so this is only done when the release record is missing.
+func (r *CollectorReconciler) listCollectorObjects(ctx context.Context, resource *v1Controller.Collector) ([]releaseObject, error) {
+	resourceLists, err := discovery.ServerPreferredNamespacedResources(r.Controller.kubeclientset.Discovery())
+	// Groups that fail discovery (e.g. an unavailable aggregated API) are skipped, everything else is still swept
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("could not discover namespaced resources: %w", err)
+	}
+
+	resourceLists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}}, resourceLists)
+
+	namespace := r.Controller.collectorNamespace(resource)
+	selectors := []string{
+		labels.SelectorFromSet(labels.Set{LabelCollectorUID: string(resource.UID)}).String(),
+		labels.SelectorFromSet(labels.Set{managedByLabel: managedByHelm}).String(),
+	}
+
+	var objects []releaseObject
+
+	seen := map[string]bool{}
+
+	for _, resourceList := range resourceLists {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, apiResource := range resourceList.APIResources {
+			gvr := groupVersion.WithResource(apiResource.Name)
+
+			for _, selector := range selectors {
+				list, err := r.Controller.dynamicclientset.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+				if err != nil {
+					return nil, fmt.Errorf("could not list %s: %w", gvr.String(), err)
+				}
+
+				for _, item := range list.Items {
+					key := gvr.String() + "/" + item.GetName()
+					if seen[key] || !isCollectorObject(resource, namespace, &item) {
+						continue
+					}
+
+					seen[key] = true
+
+					// Objects the chart asks to keep are left alone, just like Helm does on uninstall
+					if item.GetAnnotations()[resourcePolicyAnnotation] == resourcePolicyKeep {
+						continue
+					}
+
+					objects = append(objects, releaseObject{gvr: gvr, object: item})
+				}
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// isCollectorObject reports whether the object belongs to the collector, i.e. it carries the UID label of the
+// Collector, or the Helm ownership annotations of its release and no UID label of another Collector.
+func isCollectorObject(resource *v1Controller.Collector, namespace string, object *unstructured.Unstructured) bool {
+	if uid, ok := object.GetLabels()[LabelCollectorUID]; ok {
+		return uid == string(resource.UID)
+	}
+
+	annotations := object.GetAnnotations()
+
+	return annotations[releaseNameAnnotation] == releaseName(resource) && annotations[releaseNamespaceAnnotation] == namespace
+}
@@ -0,0 +1,128 @@
+package operator
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// testObject returns an object of the given kind with the labels and annotations.
+func testObject(apiVersion string, kind string, namespace string, name string, labels map[string]string, annotations map[string]string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(apiVersion)
+	object.SetKind(kind)
+	object.SetNamespace(namespace)
+	object.SetName(name)
+	object.SetLabels(labels)
+	object.SetAnnotations(annotations)
+
+	return object
+}
+
+func TestListCollectorObjects(t *testing.T) {
+	uidLabel := map[string]string{LabelCollectorUID: "4a7d"}
+	helmLabel := map[string]string{managedByLabel: managedByHelm}
+	releaseAnnotations := map[string]string{releaseNameAnnotation: "fluent-bit-main", releaseNamespaceAnnotation: "acme"}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    []string
+	}{
+		{
+			name:    "object with the UID label",
+			objects: []runtime.Object{testObject("v1", "ConfigMap", "acme", "config", uidLabel, nil)},
+			want:    []string{"ConfigMap/config"},
+		},
+		{
+			name:    "object owned by the release without the UID label",
+			objects: []runtime.Object{testObject("apps/v1", "DaemonSet", "acme", "fluent-bit", helmLabel, releaseAnnotations)},
+			want:    []string{"DaemonSet/fluent-bit"},
+		},
+		{
+			name:    "object with the UID label owned by the release is listed once",
+			objects: []runtime.Object{testObject("apps/v1", "Deployment", "acme", "aggregator", map[string]string{LabelCollectorUID: "4a7d", managedByLabel: managedByHelm}, releaseAnnotations)},
+			want:    []string{"Deployment/aggregator"},
+		},
+		{
+			name:    "object of another release",
+			objects: []runtime.Object{testObject("v1", "Service", "acme", "other", helmLabel, map[string]string{releaseNameAnnotation: "fluent-bit-other", releaseNamespaceAnnotation: "acme"})},
+		},
+		{
+			name:    "object of the release name in another namespace",
+			objects: []runtime.Object{testObject("v1", "Service", "acme", "other", helmLabel, map[string]string{releaseNameAnnotation: "fluent-bit-main", releaseNamespaceAnnotation: "globex"})},
+		},
+		{
+			name:    "object owned by the release with the UID label of another collector",
+			objects: []runtime.Object{testObject("v1", "Secret", "acme", "other", map[string]string{LabelCollectorUID: "9c1e", managedByLabel: managedByHelm}, releaseAnnotations)},
+		},
+		{
+			name:    "object owned by the release without the Helm label",
+			objects: []runtime.Object{testObject("v1", "Secret", "acme", "unmanaged", nil, releaseAnnotations)},
+		},
+		{
+			name:    "object the chart asks to keep",
+			objects: []runtime.Object{testObject("v1", "Secret", "acme", "kept", helmLabel, map[string]string{releaseNameAnnotation: "fluent-bit-main", releaseNamespaceAnnotation: "acme", resourcePolicyAnnotation: resourcePolicyKeep})},
+		},
+		{
+			name:    "object in another namespace",
+			objects: []runtime.Object{testObject("v1", "ConfigMap", "globex", "config", uidLabel, nil)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestController(t, tt.objects...)
+
+			objects, err := controller.reconciler.listCollectorObjects(context.Background(), testCollector())
+			if err != nil {
+				t.Fatalf("listCollectorObjects() error = %v", err)
+			}
+
+			var got []string
+			for _, object := range objects {
+				got = append(got, object.object.GetKind()+"/"+object.object.GetName())
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("listCollectorObjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSweepCollector(t *testing.T) {
+	releaseAnnotations := map[string]string{releaseNameAnnotation: "fluent-bit-main", releaseNamespaceAnnotation: "acme"}
+
+	controller := newTestController(t,
+		testObject("v1", "ConfigMap", "acme", "config", map[string]string{LabelCollectorUID: "4a7d"}, nil),
+		testObject("apps/v1", "DaemonSet", "acme", "fluent-bit", map[string]string{managedByLabel: managedByHelm}, releaseAnnotations),
+		testObject("v1", "Service", "acme", "other", map[string]string{managedByLabel: managedByHelm}, map[string]string{releaseNameAnnotation: "fluent-bit-other", releaseNamespaceAnnotation: "acme"}),
+	)
+
+	if err := controller.reconciler.sweepCollector(context.Background(), testCollector()); err != nil {
+		t.Fatalf("sweepCollector() error = %v", err)
+	}
+
+	tests := []struct {
+		gvr         schema.GroupVersionResource
+		name        string
+		wantDeleted bool
+	}{
+		{gvr: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, name: "config", wantDeleted: true},
+		{gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}, name: "fluent-bit", wantDeleted: true},
+		{gvr: schema.GroupVersionResource{Version: "v1", Resource: "services"}, name: "other"},
+	}
+
+	for _, tt := range tests {
+		_, err := controller.dynamicclientset.Resource(tt.gvr).Namespace("acme").Get(context.Background(), tt.name, metav1.GetOptions{})
+		if deleted := err != nil; deleted != tt.wantDeleted {
+			t.Errorf("%s %s deleted = %v, want %v (%v)", tt.gvr.Resource, tt.name, deleted, tt.wantDeleted, err)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+const (
+	// driftFieldManager is the field manager drifted objects are re-applied from the manifest with. It's a manager
+	// of its own, so that the fields it takes over from Helm can be told apart from the status and Tenant writes.
+	driftFieldManager = "kube8-operator-drift"
+	// driftCheckFieldManager is the field manager of the dry runs that find drift. The dry runs never persist, so
+	// it owns no fields and the manifest is compared as it is, not merged with what an earlier correction applied.
+	driftCheckFieldManager = "kube8-operator-drift-check"
+	// maxDriftPaths is the number of drifted fields listed per object in the Drifted condition.
+	maxDriftPaths = 5
+)
+
+// Drift condition and event reasons.
+const (
+	reasonNoDrift               = "NoDrift"
+	reasonDriftDetected         = "DriftDetected"
+	reasonDriftCorrected        = "DriftCorrected"
+	reasonDriftCorrectionFailed = "DriftCorrectionFailed"
+)
+
+// driftedObject is an object of the release manifest whose live state differs from the manifest.
+type driftedObject struct {
+	info *resource.Info
+	// missing is set when the object doesn't exist anymore, paths otherwise lists the fields that differ
+	missing bool
+	paths   []string
+}
+
+func (d driftedObject) String() string {
+	name := d.info.Object.GetObjectKind().GroupVersionKind().Kind + "/" + d.info.Name
+	if d.missing {
+		return name + " (missing)"
+	}
+
+	paths := d.paths
+	if len(paths) > maxDriftPaths {
+		paths = append(paths[:maxDriftPaths:maxDriftPaths], fmt.Sprintf("%d more", len(d.paths)-maxDriftPaths))
+	}
+
+	return name + " (" + strings.Join(paths, ", ") + ")"
+}
+
+// driftSummary lists the drifted objects for a condition message or an event.
+func driftSummary(drifted []driftedObject) string {
+	summary := make([]string, 0, len(drifted))
+	for _, object := range drifted {
+		summary = append(summary, object.String())
+	}
+
+	return strings.Join(summary, "; ")
+}
+
+// enqueueDriftChecks queues a drift check of every collector, it runs every drift check interval. Changes to
+// the objects of a release queue a check of their collector as they happen.
+func (c *Controller) enqueueDriftChecks() {
+	for _, collector := range c.informer.GetStore().List() {
+		key, err := cache.MetaNamespaceKeyFunc(collector)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			continue
+		}
+
+		c.driftQueue.Add(key)
+	}
+}
+
+// RunDriftWorker processes collector keys off the drift queue until it is shut down.
+func (c *Controller) RunDriftWorker(ctx context.Context) {
+	for c.processNextDriftItem(ctx) {
+	}
+}
+
+// processNextDriftItem pops a single key off the drift queue and checks the release of the collector for drift.
+func (c *Controller) processNextDriftItem(ctx context.Context) bool {
+	item, shutdown := c.driftQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.driftQueue.Done(item)
+
+	if c.driftQueue.ShuttingDown() {
+		return false
+	}
+
+	key, ok := item.(string)
+	if !ok {
+		c.driftQueue.Forget(item)
+		utilruntime.HandleError(fmt.Errorf("expected string in drift queue but got %#v", item))
+
+		return true
+	}
+
+	if err := c.syncDrift(ctx, key); err != nil {
+		c.driftQueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error checking collector %q for drift, requeuing: %w", key, err))
+
+		return true
+	}
+
+	c.driftQueue.Forget(key)
+
+	return true
+}
+
+// syncDrift compares the live objects of the collector release with the manifest of its deployed revision and
+// handles the differences according to the drift policy of the collector. Only a collector whose current
+// generation is deployed is checked, a rollout in progress or a failed one isn't drift.
+// nolint: funlen
+func (c *Controller) syncDrift(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+
+		return nil
+	}
+
+	collector, err := c.lister.Collectors(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if collector.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if collector.Spec.DriftPolicy == v1alpha.DriftPolicyIgnore {
+		// Drift reported before the policy changed isn't followed anymore
+		_, err = c.MutateStatus(ctx, collector, func(status *v1alpha.CollectorStatus) {
+			meta.RemoveStatusCondition(&status.Conditions, typeDrifted)
+		})
+
+		return err
+	}
+
+	installed := meta.FindStatusCondition(collector.Status.Conditions, typeInstalled)
+	if installed == nil || installed.Status != metav1.ConditionTrue || installed.ObservedGeneration != collector.Generation ||
+		meta.IsStatusConditionTrue(collector.Status.Conditions, typeProgressing) {
+		return nil
+	}
+
+	actionConfig, err := c.reconciler.newActionConfiguration(c.collectorNamespace(collector))
+	if err != nil {
+		return err
+	}
+
+	deployed, err := lastRelease(actionConfig, releaseName(collector))
+	if err != nil || deployed == nil || deployed.Info.Status != release.StatusDeployed {
+		return err
+	}
+
+	objects, err := actionConfig.KubeClient.Build(strings.NewReader(deployed.Manifest), false)
+	if err != nil {
+		return fmt.Errorf("could not build the manifest of release %s revision %d: %w", deployed.Name, deployed.Version, err)
+	}
+
+	c.releaseObjects.watch(objects)
+
+	drifted, err := c.detectDrift(ctx, objects)
+	if err != nil {
+		return err
+	}
+
+	if len(drifted) == 0 {
+		_, err = c.SetCondition(ctx, collector, newCondition(collector, typeDrifted, metav1.ConditionFalse, reasonNoDrift, fmt.Sprintf("Live objects match Helm release %s revision %d", deployed.Name, deployed.Version)))
+
+		return err
+	}
+
+	summary := driftSummary(drifted)
+
+	if collector.Spec.DriftPolicy != v1alpha.DriftPolicyCorrect {
+		message := fmt.Sprintf("Live objects drifted from Helm release %s revision %d: %s", deployed.Name, deployed.Version, summary)
+
+		// The same drift is only announced once, it stays in the condition until it goes away
+		previous := meta.FindStatusCondition(collector.Status.Conditions, typeDrifted)
+		if previous == nil || previous.Status != metav1.ConditionTrue || previous.Message != message {
+			c.recorder.Event(collector, corev1.EventTypeWarning, reasonDriftDetected, message)
+		}
+
+		_, err = c.SetCondition(ctx, collector, newCondition(collector, typeDrifted, metav1.ConditionTrue, reasonDriftDetected, message))
+
+		return err
+	}
+
+	if err = c.correctDrift(ctx, drifted); err != nil {
+		message := fmt.Sprintf("Live objects drifted from Helm release %s revision %d and could not be re-applied: %s: (%s)", deployed.Name, deployed.Version, summary, err)
+
+		c.recorder.Event(collector, corev1.EventTypeWarning, reasonDriftCorrectionFailed, message)
+
+		if _, statusErr := c.SetCondition(ctx, collector, newCondition(collector, typeDrifted, metav1.ConditionTrue, reasonDriftCorrectionFailed, message)); statusErr != nil {
+			return statusErr
+		}
+
+		return err
+	}
+
+	message := fmt.Sprintf("Re-applied the drifted objects from Helm release %s revision %d: %s", deployed.Name, deployed.Version, summary)
+
+	klog.Infof("Corrected drift of collector %s: %s", key, summary)
+	c.recorder.Event(collector, corev1.EventTypeNormal, reasonDriftCorrected, message)
+
+	_, err = c.SetCondition(ctx, collector, newCondition(collector, typeDrifted, metav1.ConditionFalse, reasonDriftCorrected, message))
+
+	return err
+}
+
+// detectDrift returns the objects of the manifest that are missing or whose live state differs from the manifest.
+// Each object is applied server side as a dry run, what the API server returns is the live object with the
+// manifest applied, defaulted and normalized, so that it only differs from the live object where it drifted.
+func (c *Controller) detectDrift(ctx context.Context, objects []*resource.Info) ([]driftedObject, error) {
+	var drifted []driftedObject
+
+	for _, info := range objects {
+		client := c.manifestObjectClient(info)
+
+		live, err := client.Get(ctx, info.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			drifted = append(drifted, driftedObject{info: info, missing: true})
+
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not get %s %s: %w", info.Mapping.Resource.Resource, info.Name, err)
+		}
+
+		manifest, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			return nil, fmt.Errorf("expected unstructured %s %s but got %T", info.Mapping.Resource.Resource, info.Name, info.Object)
+		}
+
+		applied, err := client.Apply(ctx, info.Name, manifest, metav1.ApplyOptions{FieldManager: driftCheckFieldManager, Force: true, DryRun: []string{metav1.DryRunAll}})
+		if err != nil {
+			return nil, fmt.Errorf("could not dry run the apply of %s %s: %w", info.Mapping.Resource.Resource, info.Name, err)
+		}
+
+		var paths []string
+
+		diffPaths(comparableObject(applied), comparableObject(live), "", &paths)
+
+		if len(paths) > 0 {
+			sort.Strings(paths)
+
+			drifted = append(drifted, driftedObject{info: info, paths: paths})
+		}
+	}
+
+	return drifted, nil
+}
+
+// correctDrift applies the drifted objects from the manifest, which recreates the missing ones. The re-applied
+// fields are owned by the drift field manager until Helm changes them: Helm upgrades and rollbacks patch the
+// objects rather than applying them, which never conflicts with the fields of another manager and takes over the
+// ones the patch changes. Fields that a later manifest drops are removed by the three-way merge of Helm.
+func (c *Controller) correctDrift(ctx context.Context, drifted []driftedObject) error {
+	for _, object := range drifted {
+		manifest, ok := object.info.Object.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("expected unstructured %s %s but got %T", object.info.Mapping.Resource.Resource, object.info.Name, object.info.Object)
+		}
+
+		_, err := c.manifestObjectClient(object.info).Apply(ctx, object.info.Name, manifest, metav1.ApplyOptions{FieldManager: driftFieldManager, Force: true})
+		if err != nil {
+			return fmt.Errorf("could not apply %s %s: %w", object.info.Mapping.Resource.Resource, object.info.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// manifestObjectClient returns the dynamic client of the resource of a manifest object, in its namespace.
+func (c *Controller) manifestObjectClient(info *resource.Info) dynamic.ResourceInterface {
+	resourceClient := c.dynamicclientset.Resource(info.Mapping.Resource)
+	if info.Mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return resourceClient
+	}
+
+	return resourceClient.Namespace(info.Namespace)
+}
+
+// comparableObject returns the content of the object without the fields that change on every write or that
+// aren't part of the manifest.
+func comparableObject(object *unstructured.Unstructured) map[string]interface{} {
+	content := object.DeepCopy().Object
+
+	for _, field := range []string{"managedFields", "resourceVersion", "generation"} {
+		unstructured.RemoveNestedField(content, "metadata", field)
+	}
+
+	delete(content, "status")
+
+	return content
+}
+
+// diffPaths appends the paths of the fields that differ between the two values, e.g. spec.replicas or
+// spec.template.spec.containers[0].image. Lists of different lengths are reported as a whole.
+func diffPaths(a interface{}, b interface{}, path string, paths *[]string) {
+	mapA, aIsMap := a.(map[string]interface{})
+	mapB, bIsMap := b.(map[string]interface{})
+
+	if aIsMap && bIsMap {
+		for key := range mapA {
+			diffPaths(mapA[key], mapB[key], joinPath(path, key), paths)
+		}
+
+		for key := range mapB {
+			if _, ok := mapA[key]; !ok {
+				*paths = append(*paths, joinPath(path, key))
+			}
+		}
+
+		return
+	}
+
+	listA, aIsList := a.([]interface{})
+	listB, bIsList := b.([]interface{})
+
+	if aIsList && bIsList && len(listA) == len(listB) {
+		for i := range listA {
+			diffPaths(listA[i], listB[i], fmt.Sprintf("%s[%d]", path, i), paths)
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*paths = append(*paths, path)
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
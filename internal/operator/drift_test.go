@@ -0,0 +1,124 @@
+package operator
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiffPaths(t *testing.T) {
+	deployment := func(replicas int64, image string) map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "fluent-bit"},
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "fluent-bit", "image": image},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		a    interface{}
+		b    interface{}
+		want []string
+	}{
+		{name: "equal objects", a: deployment(1, "fluent-bit:3.0"), b: deployment(1, "fluent-bit:3.0")},
+		{name: "changed field", a: deployment(1, "fluent-bit:3.0"), b: deployment(3, "fluent-bit:3.0"), want: []string{"spec.replicas"}},
+		{name: "changed list item", a: deployment(1, "fluent-bit:3.0"), b: deployment(1, "fluent-bit:3.1"), want: []string{"spec.template.spec.containers[0].image"}},
+		{name: "several changes", a: deployment(1, "fluent-bit:3.0"), b: deployment(3, "fluent-bit:3.1"), want: []string{"spec.replicas", "spec.template.spec.containers[0].image"}},
+		{
+			name: "removed field",
+			a:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": true}},
+			b:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			want: []string{"spec.paused"},
+		},
+		{
+			name: "added field",
+			a:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			b:    map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": true}},
+			want: []string{"spec.paused"},
+		},
+		{
+			name: "lists of different lengths",
+			a:    map[string]interface{}{"args": []interface{}{"-c", "fluent-bit.conf"}},
+			b:    map[string]interface{}{"args": []interface{}{"-c"}},
+			want: []string{"args"},
+		},
+		{
+			name: "map replaced by a scalar",
+			a:    map[string]interface{}{"resources": map[string]interface{}{"limits": "1"}},
+			b:    map[string]interface{}{"resources": nil},
+			want: []string{"resources"},
+		},
+		{name: "different scalars at the root", a: "a", b: "b", want: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			diffPaths(tt.a, tt.b, "", &paths)
+
+			// Map keys are walked in random order
+			slices.Sort(paths)
+
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("diffPaths() = %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		path string
+		key  string
+		want string
+	}{
+		{path: "", key: "spec", want: "spec"},
+		{path: "spec", key: "replicas", want: "spec.replicas"},
+		{path: "spec.containers[0]", key: "image", want: "spec.containers[0].image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := joinPath(tt.path, tt.key); got != tt.want {
+				t.Errorf("joinPath(%q, %q) = %q, want %q", tt.path, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparableObject(t *testing.T) {
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "fluent-bit",
+			"resourceVersion": "42",
+			"generation":      int64(3),
+			"managedFields":   []interface{}{map[string]interface{}{"manager": "helm"}},
+		},
+		"spec":   map[string]interface{}{"replicas": int64(1)},
+		"status": map[string]interface{}{"readyReplicas": int64(1)},
+	}}
+
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "fluent-bit"},
+		"spec":     map[string]interface{}{"replicas": int64(1)},
+	}
+
+	if got := comparableObject(object); !reflect.DeepEqual(got, want) {
+		t.Errorf("comparableObject() = %v, want %v", got, want)
+	}
+
+	if _, ok := object.Object["status"]; !ok {
+		t.Error("comparableObject() changed the object")
+	}
+}
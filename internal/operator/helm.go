@@ -0,0 +1,275 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/klog/v2"
+)
+
+// maxReleaseHistory limits the number of revisions kept for a release, so repeated failed upgrades don't grow it forever.
+const maxReleaseHistory = 10
+
+// Helm storage drivers the release state of collectors can be persisted with.
+const (
+	StorageDriverSecret    = "secret"
+	StorageDriverConfigMap = "configmap"
+	StorageDriverSQL       = "sql"
+	StorageDriverMemory    = "memory"
+)
+
+// HelmStorage configures where the Helm release state of collectors is stored.
+type HelmStorage struct {
+	// Driver is one of secret, configmap, sql or memory. Secret and configmap store the releases in the tenant namespace.
+	Driver string
+	// SQLConnectionString is the connection string of the PostgreSQL database used by the sql driver.
+	SQLConnectionString string
+
+	// drivers caches the driver of each tenant namespace for the sql and memory drivers, so that a connection isn't
+	// opened on every reconcile and the releases kept in memory outlive it
+	drivers sync.Map
+}
+
+// validate checks that the configured driver is supported.
+func (h *HelmStorage) validate() error {
+	switch h.Driver {
+	case StorageDriverSecret, StorageDriverConfigMap, StorageDriverMemory:
+		return nil
+	case StorageDriverSQL:
+		if h.SQLConnectionString == "" {
+			return fmt.Errorf("the %s helm storage driver requires a connection string", StorageDriverSQL)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown helm storage driver: %q", h.Driver)
+	}
+}
+
+// namespaceDriver returns the sql or memory driver of the given namespace, creating it on first use.
+func (h *HelmStorage) namespaceDriver(namespace string) (driver.Driver, error) {
+	if d, ok := h.drivers.Load(namespace); ok {
+		return d.(driver.Driver), nil
+	}
+
+	var d driver.Driver
+
+	if h.Driver == StorageDriverSQL {
+		sqlDriver, err := driver.NewSQL(h.SQLConnectionString, myDebugf, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to instantiate SQL driver: %w", err)
+		}
+
+		d = sqlDriver
+	} else {
+		memoryDriver := driver.NewMemory()
+		memoryDriver.SetNamespace(namespace)
+
+		d = memoryDriver
+	}
+
+	actual, _ := h.drivers.LoadOrStore(namespace, d)
+
+	return actual.(driver.Driver), nil
+}
+
+// newActionConfiguration creates a Helm action configuration scoped to the given namespace,
+// backed by the configured release storage.
+func (r *CollectorReconciler) newActionConfiguration(namespace string) (*action.Configuration, error) {
+	setting := cli.New()
+	setting.SetNamespace(namespace)
+
+	actionConfig := new(action.Configuration)
+
+	// Init panics when the SQL driver can't connect, so it's initialised with the memory driver and swapped afterwards
+	helmDriver := r.Storage.Driver
+	if helmDriver == StorageDriverSQL {
+		helmDriver = StorageDriverMemory
+	}
+
+	if err := actionConfig.Init(setting.RESTClientGetter(), setting.Namespace(), helmDriver, myDebugf); err != nil {
+		return nil, fmt.Errorf("error initializing action config: %w", err)
+	}
+
+	// Init creates a new memory driver every time, the drivers of the namespace are shared between reconciles
+	if r.Storage.Driver == StorageDriverSQL || r.Storage.Driver == StorageDriverMemory {
+		d, err := r.Storage.namespaceDriver(namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		actionConfig.Releases = storage.Init(d)
+	}
+
+	return actionConfig, nil
+}
+
+// lastRelease returns the latest revision of the named release, or nil if it has never been installed.
+func lastRelease(actionConfig *action.Configuration, name string) (*release.Release, error) {
+	history, err := actionConfig.Releases.History(name)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, fmt.Errorf("could not get history of release %s: %w", name, err)
+	}
+
+	if len(history) == 0 {
+		return nil, nil // nolint: nilnil
+	}
+
+	releaseutil.SortByRevision(history)
+
+	return history[len(history)-1], nil
+}
+
+// RollbackError is returned when an upgrade of a collector release failed and the release was rolled back.
+type RollbackError struct {
+	// FailedRevision is the revision of the upgrade that failed.
+	FailedRevision int
+	// RestoredRevision is the last good revision the release was rolled back to.
+	RestoredRevision int
+	// Revision is the revision the rollback recorded, which is deployed now.
+	Revision int
+	// Err is the error the upgrade failed with.
+	Err error
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("upgrade to revision %d failed and was rolled back to revision %d: %s", e.FailedRevision, e.RestoredRevision, e.Err)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// installOrUpgrade installs the chart as a new release, or upgrades the release if it is already installed.
+// Both run the rendered manifests through the post-renderer and wait for the release to become ready within the
+// release timeout. A failed upgrade is rolled back to the last good revision and reported as a RollbackError.
+// The namespace is created by the install unless it is provisioned for a Tenant, which owns it.
+func (r *CollectorReconciler) installOrUpgrade(actionConfig *action.Configuration, namespace string, createNamespace bool, name string, collectorChart *chart.Chart, vals map[string]interface{}, postRenderer postrender.PostRenderer) (*release.Release, error) {
+	last, err := lastRelease(actionConfig, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if last != nil && last.Info.Status.IsPending() {
+		if err = r.failStaleRelease(actionConfig, last); err != nil {
+			return nil, err
+		}
+	}
+
+	if last == nil || last.Info.Status == release.StatusUninstalled {
+		installAction := action.NewInstall(actionConfig)
+
+		installAction.ReleaseName = name
+		installAction.Namespace = namespace
+		installAction.CreateNamespace = createNamespace
+		// A release uninstalled with its history kept can only be installed again by replacing it
+		installAction.Replace = last != nil
+		installAction.Wait = true
+		installAction.Timeout = r.ReleaseTimeout
+		installAction.PostRenderer = postRenderer
+
+		return installAction.Run(collectorChart, vals)
+	}
+
+	upgradeAction := action.NewUpgrade(actionConfig)
+
+	upgradeAction.Namespace = namespace
+	upgradeAction.Wait = true
+	upgradeAction.Timeout = r.ReleaseTimeout
+	upgradeAction.MaxHistory = maxReleaseHistory
+	upgradeAction.PostRenderer = postRenderer
+
+	upgraded, err := upgradeAction.Run(name, collectorChart, vals)
+	if err == nil {
+		return upgraded, nil
+	}
+
+	// The upgrade may have failed before a new revision was recorded (e.g. a template error), in which case
+	// the deployed revision was never touched and there is nothing to roll back
+	failed, lastErr := lastRelease(actionConfig, name)
+	if lastErr != nil || failed == nil || failed.Version <= last.Version || failed.Info.Status != release.StatusFailed {
+		return nil, err
+	}
+
+	restored, rollbackErr := r.rollback(actionConfig, name, failed.Version)
+	if rollbackErr != nil {
+		return nil, fmt.Errorf("upgrade to revision %d failed: %w, and the rollback failed: %s", failed.Version, err, rollbackErr)
+	}
+
+	// The rollback records a new revision with the restored manifest, that one is deployed now
+	deployed, lastErr := lastRelease(actionConfig, name)
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	return nil, &RollbackError{FailedRevision: failed.Version, RestoredRevision: restored, Revision: deployed.Version, Err: err}
+}
+
+// failStaleRelease marks a release that is still pending after the release timeout as failed, so that it can be
+// upgraded again. An operation that was interrupted, e.g. because the operator restarted, leaves its revision pending
+// forever and Helm refuses to act on a release with a pending revision. A release that was pending for less than the
+// release timeout may still be worked on, it is returned as an error so that it's retried later.
+func (r *CollectorReconciler) failStaleRelease(actionConfig *action.Configuration, last *release.Release) error {
+	pendingFor := time.Since(last.Info.LastDeployed.Time)
+	if pendingFor < r.ReleaseTimeout {
+		return fmt.Errorf("release %s revision %d is %s since %s", last.Name, last.Version, last.Info.Status, pendingFor.Round(time.Second))
+	}
+
+	klog.Warningf("Release %s revision %d has been %s for %s, marking it as failed", last.Name, last.Version, last.Info.Status, pendingFor.Round(time.Second))
+
+	last.SetStatus(release.StatusFailed, fmt.Sprintf("%s for longer than the release timeout of %s", last.Info.Status, r.ReleaseTimeout))
+
+	if err := actionConfig.Releases.Update(last); err != nil {
+		return fmt.Errorf("could not mark release %s revision %d as failed: %w", last.Name, last.Version, err)
+	}
+
+	return nil
+}
+
+// rollback rolls the release back to the latest good revision before the failed one, and returns that revision.
+func (r *CollectorReconciler) rollback(actionConfig *action.Configuration, name string, failedRevision int) (int, error) {
+	history, err := actionConfig.Releases.History(name)
+	if err != nil {
+		return 0, fmt.Errorf("could not get history of release %s: %w", name, err)
+	}
+
+	releaseutil.Reverse(history, releaseutil.SortByRevision)
+
+	for _, rel := range history {
+		if rel.Version >= failedRevision {
+			continue
+		}
+
+		if rel.Info.Status != release.StatusDeployed && rel.Info.Status != release.StatusSuperseded {
+			continue
+		}
+
+		rollbackAction := action.NewRollback(actionConfig)
+
+		rollbackAction.Version = rel.Version
+		rollbackAction.Wait = true
+		rollbackAction.Timeout = r.ReleaseTimeout
+		rollbackAction.CleanupOnFail = true
+		rollbackAction.MaxHistory = maxReleaseHistory
+
+		if err = rollbackAction.Run(name); err != nil {
+			return 0, err
+		}
+
+		klog.Infof("Rolled back release %s from failed revision %d to revision %d", name, failedRevision, rel.Version)
+
+		return rel.Version, nil
+	}
+
+	return 0, fmt.Errorf("no good revision of release %s to roll back to", name)
+}
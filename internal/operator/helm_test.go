@@ -0,0 +1,217 @@
+package operator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func TestHelmStorageValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage *HelmStorage
+		wantErr bool
+	}{
+		{name: "secret", storage: &HelmStorage{Driver: StorageDriverSecret}},
+		{name: "configmap", storage: &HelmStorage{Driver: StorageDriverConfigMap}},
+		{name: "memory", storage: &HelmStorage{Driver: StorageDriverMemory}},
+		{name: "sql with a connection string", storage: &HelmStorage{Driver: StorageDriverSQL, SQLConnectionString: "postgres://helm@db/helm"}},
+		{name: "sql without a connection string", storage: &HelmStorage{Driver: StorageDriverSQL}, wantErr: true},
+		{name: "unknown driver", storage: &HelmStorage{Driver: "etcd"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.storage.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewActionConfiguration(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     string
+		wantDriver string
+	}{
+		{name: "secret", driver: StorageDriverSecret, wantDriver: driver.SecretsDriverName},
+		{name: "configmap", driver: StorageDriverConfigMap, wantDriver: driver.ConfigMapsDriverName},
+		{name: "memory", driver: StorageDriverMemory, wantDriver: driver.MemoryDriverName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &CollectorReconciler{Storage: &HelmStorage{Driver: tt.driver}}
+
+			actionConfig, err := reconciler.newActionConfiguration("acme")
+			if err != nil {
+				t.Fatalf("newActionConfiguration() error = %v", err)
+			}
+
+			if got := actionConfig.Releases.Name(); got != tt.wantDriver {
+				t.Errorf("driver = %s, want %s", got, tt.wantDriver)
+			}
+		})
+	}
+}
+
+func TestMemoryStorageIsShared(t *testing.T) {
+	reconciler := &CollectorReconciler{Storage: &HelmStorage{Driver: StorageDriverMemory}}
+
+	stored, err := reconciler.newActionConfiguration("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storeRelease(t, stored, 1, release.StatusDeployed, "")
+
+	tests := []struct {
+		name      string
+		namespace string
+		wantFound bool
+	}{
+		{name: "later reconcile of the namespace", namespace: "acme", wantFound: true},
+		{name: "another namespace", namespace: "globex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actionConfig, err := reconciler.newActionConfiguration(tt.namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			last, err := lastRelease(actionConfig, "fluent-bit-main")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if found := last != nil; found != tt.wantFound {
+				t.Errorf("release found = %v, want %v", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestInstallOrUpgrade(t *testing.T) {
+	timeout := errors.New("timed out waiting for the condition")
+
+	tests := []struct {
+		name          string
+		installed     bool
+		waitErrors    []error
+		wantRevision  int
+		wantRollback  *RollbackError
+		wantErr       bool
+		wantLastChart string
+	}{
+		{name: "release is installed", wantRevision: 1, wantLastChart: "1.1.0"},
+		{name: "release is upgraded", installed: true, wantRevision: 2, wantLastChart: "1.1.0"},
+		{
+			name:          "failed install isn't rolled back",
+			waitErrors:    []error{timeout},
+			wantErr:       true,
+			wantLastChart: "1.1.0",
+		},
+		{
+			name:          "failed upgrade is rolled back",
+			installed:     true,
+			waitErrors:    []error{nil, timeout},
+			wantRollback:  &RollbackError{FailedRevision: 2, RestoredRevision: 1, Revision: 3},
+			wantErr:       true,
+			wantLastChart: "1.0.0",
+		},
+		{
+			name:          "failed rollback is reported",
+			installed:     true,
+			waitErrors:    []error{nil, timeout, timeout},
+			wantErr:       true,
+			wantLastChart: "1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &CollectorReconciler{Storage: &HelmStorage{Driver: StorageDriverMemory}, ReleaseTimeout: time.Minute}
+			actionConfig := newTestActionConfiguration(t, newWaitingKubeClient(tt.waitErrors...))
+
+			if tt.installed {
+				if _, err := reconciler.installOrUpgrade(actionConfig, "acme", true, "fluent-bit-main", testChart("1.0.0"), map[string]interface{}{}, nil); err != nil {
+					t.Fatalf("install error = %v", err)
+				}
+			}
+
+			deployed, err := reconciler.installOrUpgrade(actionConfig, "acme", true, "fluent-bit-main", testChart("1.1.0"), map[string]interface{}{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("installOrUpgrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && deployed.Version != tt.wantRevision {
+				t.Errorf("installOrUpgrade() = revision %d, want %d", deployed.Version, tt.wantRevision)
+			}
+
+			var rollbackErr *RollbackError
+			if isRollback := errors.As(err, &rollbackErr); isRollback != (tt.wantRollback != nil) {
+				t.Fatalf("installOrUpgrade() error = %v, want rollback %v", err, tt.wantRollback != nil)
+			}
+
+			if tt.wantRollback != nil && (rollbackErr.FailedRevision != tt.wantRollback.FailedRevision || rollbackErr.RestoredRevision != tt.wantRollback.RestoredRevision || rollbackErr.Revision != tt.wantRollback.Revision) {
+				t.Errorf("rollback = %+v, want %+v", rollbackErr, tt.wantRollback)
+			}
+
+			last, err := lastRelease(actionConfig, "fluent-bit-main")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if last.Chart.Metadata.Version != tt.wantLastChart {
+				t.Errorf("last revision chart = %s, want %s", last.Chart.Metadata.Version, tt.wantLastChart)
+			}
+		})
+	}
+}
+
+func TestFailStaleRelease(t *testing.T) {
+	tests := []struct {
+		name       string
+		pendingFor time.Duration
+		wantErr    bool
+		wantStatus release.Status
+	}{
+		{name: "release pending for longer than the timeout is failed", pendingFor: 2 * time.Minute, wantStatus: release.StatusFailed},
+		{name: "release pending for less than the timeout is waited for", pendingFor: 10 * time.Second, wantErr: true, wantStatus: release.StatusPendingUpgrade},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &CollectorReconciler{ReleaseTimeout: time.Minute}
+			actionConfig := newTestActionConfiguration(t, newWaitingKubeClient())
+			storeRelease(t, actionConfig, 1, release.StatusPendingUpgrade, "")
+
+			last, err := lastRelease(actionConfig, "fluent-bit-main")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			last.Info.LastDeployed = helmtime.Time{Time: time.Now().Add(-tt.pendingFor)}
+
+			if err = reconciler.failStaleRelease(actionConfig, last); (err != nil) != tt.wantErr {
+				t.Errorf("failStaleRelease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			stored, err := actionConfig.Releases.Get("fluent-bit-main", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Info.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", stored.Info.Status, tt.wantStatus)
+			}
+		})
+	}
+}
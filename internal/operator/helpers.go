@@ -2,8 +2,14 @@ package operator
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
 )
 
 // getValues unmarshals the base64 encoded YAML string into a map.
@@ -24,3 +30,48 @@ func getValues(configuration string) (map[string]interface{}, error) {
 
 	return vals, nil
 }
+
+// hasFinalizer reports whether the object carries the given finalizer.
+func hasFinalizer(object metav1.Object, finalizer string) bool {
+	return slices.Contains(object.GetFinalizers(), finalizer)
+}
+
+// addFinalizer adds the given finalizer to the object if it is not already present.
+func addFinalizer(object metav1.Object, finalizer string) {
+	if hasFinalizer(object, finalizer) {
+		return
+	}
+
+	object.SetFinalizers(append(object.GetFinalizers(), finalizer))
+}
+
+// removeFinalizer removes the given finalizer from the object.
+func removeFinalizer(object metav1.Object, finalizer string) {
+	object.SetFinalizers(slices.DeleteFunc(object.GetFinalizers(), func(f string) bool {
+		return f == finalizer
+	}))
+}
+
+// finalizersPatch returns the JSON merge patch setting the finalizers of the object to its current ones. The patch
+// carries the resource version of the object, so it fails with a conflict when the object changed since it was read.
+func finalizersPatch(object metav1.Object) ([]byte, error) {
+	type metadata struct {
+		Finalizers      []string `json:"finalizers"`
+		ResourceVersion string   `json:"resourceVersion"`
+	}
+
+	return json.Marshal(struct {
+		Metadata metadata `json:"metadata"`
+	}{Metadata: metadata{Finalizers: object.GetFinalizers(), ResourceVersion: object.GetResourceVersion()}})
+}
+
+// tenantNamespace returns the namespace a collector without a Tenant is deployed to, which is the lowercased tenant
+// reference. The defaulting webhook stores the reference lowercased, collectors admitted without it are lowercased here.
+func tenantNamespace(resource *v1alpha.Collector) string {
+	return strings.ToLower(resource.Spec.Tenant.Reference)
+}
+
+// releaseName returns the name of the Helm release of the collector, {collector-name}-{tenant-instance} ex: cisco-amp-collector-main.
+func releaseName(resource *v1alpha.Collector) string {
+	return resource.Spec.Collector.Name + "-" + resource.Spec.Tenant.Instance
+}
@@ -0,0 +1,151 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultLeaseName     = "kube8-operator"
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderElection configures the Lease based leader election between replicas of the operator.
+// Only the leader reconciles collectors, the other replicas keep their informer caches in sync so that
+// they can take over as soon as the Lease of the leader expires.
+type LeaderElection struct {
+	// Enabled turns leader election on, without it every replica reconciles every collector.
+	Enabled bool
+	// LeaseName and LeaseNamespace identify the coordination.k8s.io Lease the replicas compete for.
+	// The namespace defaults to the operator namespace.
+	LeaseName      string
+	LeaseNamespace string
+	// Identity is the holder identity of this replica, it defaults to the host name with a random suffix.
+	Identity string
+	// LeaseDuration is how long followers wait after the last renewal before taking the Lease over.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader keeps trying to renew the Lease before it gives up leading.
+	RenewDeadline time.Duration
+	// RetryPeriod is how often the Lease is renewed or tried to be acquired.
+	RetryPeriod time.Duration
+}
+
+// withDefaults fills in the settings that weren't configured.
+func (l LeaderElection) withDefaults(namespace string) (LeaderElection, error) {
+	if l.LeaseName == "" {
+		l.LeaseName = defaultLeaseName
+	}
+
+	if l.LeaseNamespace == "" {
+		l.LeaseNamespace = namespace
+	}
+
+	if l.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return l, fmt.Errorf("could not get the host name for the leader election identity: %w", err)
+		}
+
+		l.Identity = hostname + "_" + string(uuid.NewUUID())
+	}
+
+	if l.LeaseDuration <= 0 {
+		l.LeaseDuration = defaultLeaseDuration
+	}
+
+	if l.RenewDeadline <= 0 {
+		l.RenewDeadline = defaultRenewDeadline
+	}
+
+	if l.RetryPeriod <= 0 {
+		l.RetryPeriod = defaultRetryPeriod
+	}
+
+	if l.Enabled && l.LeaseNamespace == "" {
+		return l, errors.New("leader election needs a lease namespace or the operator namespace")
+	}
+
+	return l, nil
+}
+
+// runWithLeaderElection runs the workers while this replica holds the Lease. It returns an error when
+// leadership is lost, since the workers can't be sure another replica isn't reconciling already.
+// When the context is cancelled the Lease is only released once the running reconciles are drained,
+// so that the next leader doesn't reconcile a collector that is still being installed. When the Lease is lost
+// the running reconciles are cancelled right away, since the next leader may already be reconciling.
+func (c *Controller) runWithLeaderElection(ctx context.Context) error {
+	electionCtx, release := context.WithCancel(context.WithoutCancel(ctx))
+	defer release()
+
+	// A follower has nothing to drain and leaves the election right away
+	var leading atomic.Bool
+
+	stopFollowing := context.AfterFunc(ctx, func() {
+		if !leading.Load() {
+			release()
+		}
+	})
+	defer stopFollowing()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: c.leaderElection.LeaseName, Namespace: c.leaderElection.LeaseNamespace},
+		Client:     c.kubeclientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: c.leaderElection.Identity},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: c.leaderElection.LeaseDuration,
+		RenewDeadline: c.leaderElection.RenewDeadline,
+		RetryPeriod:   c.leaderElection.RetryPeriod,
+		// Releasing the Lease on shutdown lets a follower take over right away instead of waiting for it to expire
+		ReleaseOnCancel: true,
+		Name:            c.leaderElection.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaseCtx context.Context) {
+				leading.Store(true)
+				klog.Infof("%s acquired lease %s/%s", c.leaderElection.Identity, c.leaderElection.LeaseNamespace, c.leaderElection.LeaseName)
+
+				// The workers stop when the lease is lost or the operator is stopped, the running reconciles are only
+				// given the grace period when the operator is stopped. The lease is renewed while they drain.
+				workersCtx, stopWorkers := context.WithCancel(leaseCtx)
+				defer context.AfterFunc(ctx, stopWorkers)()
+
+				c.runWorkers(workersCtx, leaseCtx)
+				stopWorkers()
+				release()
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("%s stopped leading", c.leaderElection.Identity)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != c.leaderElection.Identity {
+					klog.Infof("%s is the leader, waiting for the lease", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("invalid leader election configuration: %w", err)
+	}
+
+	elector.Run(electionCtx)
+
+	if ctx.Err() == nil {
+		return errors.New("lost the leader election lease")
+	}
+
+	return nil
+}
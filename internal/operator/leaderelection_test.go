@@ -0,0 +1,243 @@
+package operator
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLeaderElectionWithDefaults(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		leaderElection LeaderElection
+		namespace      string
+		want           LeaderElection
+		wantErr        bool
+	}{
+		{
+			name:           "defaults",
+			leaderElection: LeaderElection{Enabled: true},
+			namespace:      "kube8-operator",
+			want: LeaderElection{
+				Enabled: true, LeaseName: defaultLeaseName, LeaseNamespace: "kube8-operator",
+				LeaseDuration: defaultLeaseDuration, RenewDeadline: defaultRenewDeadline, RetryPeriod: defaultRetryPeriod,
+			},
+		},
+		{
+			name: "configured settings are kept",
+			leaderElection: LeaderElection{
+				Enabled: true, LeaseName: "collectors", LeaseNamespace: "operators", Identity: "replica-1",
+				LeaseDuration: time.Minute, RenewDeadline: 30 * time.Second, RetryPeriod: 5 * time.Second,
+			},
+			namespace: "kube8-operator",
+			want: LeaderElection{
+				Enabled: true, LeaseName: "collectors", LeaseNamespace: "operators", Identity: "replica-1",
+				LeaseDuration: time.Minute, RenewDeadline: 30 * time.Second, RetryPeriod: 5 * time.Second,
+			},
+		},
+		{
+			name:           "enabled without a namespace",
+			leaderElection: LeaderElection{Enabled: true},
+			wantErr:        true,
+		},
+		{
+			name:           "disabled without a namespace",
+			leaderElection: LeaderElection{},
+			want:           LeaderElection{LeaseName: defaultLeaseName, LeaseDuration: defaultLeaseDuration, RenewDeadline: defaultRenewDeadline, RetryPeriod: defaultRetryPeriod},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.leaderElection.withDefaults(tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withDefaults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			// Generated identities are unique per replica, they start with the host name
+			if tt.leaderElection.Identity == "" {
+				if !strings.HasPrefix(got.Identity, hostname+"_") {
+					t.Errorf("withDefaults() identity = %s, want the host name %s with a suffix", got.Identity, hostname)
+				}
+
+				got.Identity = ""
+			}
+
+			if got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestLeaderController returns a test controller competing for the kube8-operator Lease as replica-1, with a
+// queued key of a collector that doesn't exist, which is forgotten once the workers run.
+func newTestLeaderController(t *testing.T, lease *coordinationv1.Lease) *Controller {
+	t.Helper()
+
+	controller := newTestController(t)
+	controller.leaderElection = LeaderElection{
+		Enabled:        true,
+		LeaseName:      "kube8-operator",
+		LeaseNamespace: "kube8-operator",
+		Identity:       "replica-1",
+		LeaseDuration:  time.Second,
+		RenewDeadline:  500 * time.Millisecond,
+		RetryPeriod:    100 * time.Millisecond,
+	}
+
+	if lease != nil {
+		if _, err := controller.kubeclientset.CoordinationV1().Leases("kube8-operator").Create(context.Background(), lease, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	controller.workqueue.Add("acme/logs")
+
+	return controller
+}
+
+// heldLease returns the kube8-operator Lease held by the identity, renewed just now.
+func heldLease(identity string) *coordinationv1.Lease {
+	leaseDurationSeconds := int32(60)
+
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube8-operator", Namespace: "kube8-operator"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &identity,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			AcquireTime:          &metav1.MicroTime{Time: time.Now()},
+			RenewTime:            &metav1.MicroTime{Time: time.Now()},
+		},
+	}
+}
+
+// leaseHolder returns the holder of the kube8-operator Lease, empty if it isn't held.
+func leaseHolder(t *testing.T, controller *Controller) string {
+	t.Helper()
+
+	lease, err := controller.kubeclientset.CoordinationV1().Leases("kube8-operator").Get(context.Background(), "kube8-operator", metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+
+	return *lease.Spec.HolderIdentity
+}
+
+// eventually polls the condition until it holds or the timeout expires.
+func eventually(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+
+	return condition()
+}
+
+func TestLeaderRunsWorkersAndReleasesLease(t *testing.T) {
+	controller := newTestLeaderController(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- controller.runWithLeaderElection(ctx) }()
+
+	if !eventually(t, 5*time.Second, func() bool { return leaseHolder(t, controller) == "replica-1" && controller.workqueue.Len() == 0 }) {
+		t.Fatalf("lease holder = %q with %d queued keys, want replica-1 to lead and run the workers", leaseHolder(t, controller), controller.workqueue.Len())
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runWithLeaderElection() error = %v, want nil once stopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runWithLeaderElection() didn't return once stopped")
+	}
+
+	if holder := leaseHolder(t, controller); holder != "" {
+		t.Errorf("lease holder = %q after stopping, want the lease released", holder)
+	}
+}
+
+func TestFollowerWaitsForLease(t *testing.T) {
+	controller := newTestLeaderController(t, heldLease("replica-0"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- controller.runWithLeaderElection(ctx) }()
+
+	time.Sleep(500 * time.Millisecond)
+
+	if controller.workqueue.Len() != 1 {
+		t.Errorf("queued keys = %d, want the follower to leave the queue to the leader", controller.workqueue.Len())
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runWithLeaderElection() error = %v, want nil once stopped", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("follower didn't leave the election right away once stopped")
+	}
+
+	if holder := leaseHolder(t, controller); holder != "replica-0" {
+		t.Errorf("lease holder = %q, want the lease of replica-0 left alone", holder)
+	}
+}
+
+func TestLeaderStopsWhenLeaseIsLost(t *testing.T) {
+	controller := newTestLeaderController(t, nil)
+
+	done := make(chan error, 1)
+
+	go func() { done <- controller.runWithLeaderElection(context.Background()) }()
+
+	if !eventually(t, 5*time.Second, func() bool { return leaseHolder(t, controller) == "replica-1" }) {
+		t.Fatal("replica-1 didn't acquire the lease")
+	}
+
+	// Another replica takes the lease over, e.g. after this one was partitioned from the API server
+	for {
+		if _, err := controller.kubeclientset.CoordinationV1().Leases("kube8-operator").Update(context.Background(), heldLease("replica-2"), metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Error("runWithLeaderElection() error = nil, want an error once the lease is lost")
+			}
+
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
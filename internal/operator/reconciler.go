@@ -1,38 +1,47 @@
 package operator
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/google/go-github/v52/github"
-	"golang.org/x/oauth2"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
-	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"kube8-operator/internal/instrumentation"
 	"kube8-operator/pkg/apis/collector/v1alpha"
 )
 
 const (
-	githubToken            = ""
-	typeAvailableCollector = "Available"
+	// collectorFinalizer blocks the deletion of a Collector until the resources deployed for it are removed.
+	collectorFinalizer = v1alpha.GroupName + "/collector-finalizer"
 )
 
 type CollectorReconciler struct {
 	client.Client
 	Scheme     *runtime.Scheme
 	Controller *Controller
+	// KeepHistory keeps the Helm release record around, marked as uninstalled, when a collector is deleted.
+	KeepHistory bool
+	// Storage configures where the Helm release state of the collectors is persisted.
+	Storage *HelmStorage
+	// ReleaseTimeout is how long an install, upgrade or rollback waits for the collector to become ready.
+	ReleaseTimeout time.Duration
+	// ChartSources selects where the chart of a collector is fetched from.
+	ChartSources *ChartSources
+	// ValuesSchemas holds the values schemas of the collectors the values are validated against, along with the chart's own.
+	ValuesSchemas *ValuesSchemas
 }
 
 // myDebugf is a function that implements the Debug interface for Helm.
@@ -40,103 +49,281 @@ func myDebugf(format string, v ...interface{}) {
 	fmt.Printf(format, v...)
 }
 
-// CreateOrUpdateCollector creates or updates a Kubernetes deployment in the cluster the operator is running on
-// nolint: gocyclo, cyclop
-func (r *CollectorReconciler) CreateOrUpdateCollector(ctx context.Context, resource *v1alpha.Collector, update bool) error {
+// CreateOrUpdateCollector creates or updates a Kubernetes deployment in the cluster the operator is running on.
+// The release is installed the first time and upgraded against the stored release history afterwards.
+// Every step reports its own condition, and the status records the generation it was reconciled from.
+// nolint: gocyclo, cyclop, funlen
+func (r *CollectorReconciler) CreateOrUpdateCollector(ctx context.Context, resource *v1alpha.Collector) error {
 	var err error
-	// set the status as Unknown when no status is available (i.e. first time the resource is created)
-	// this is to prevent the status from being empty and causing errors
-	if len(resource.Status.Conditions) == 0 {
-		resource, err = r.Controller.UpdateStatus(ctx, resource, metav1.ConditionUnknown, "Reconciling", "Starting reconciliation")
+	// A new generation is Progressing until it is deployed or fails, the collector's availability is
+	// Unknown until the first reconcile finishes
+	if resource.Status.ObservedGeneration != resource.Generation || len(resource.Status.Conditions) == 0 {
+		resource, err = r.Controller.MutateStatus(ctx, resource, func(status *v1alpha.CollectorStatus) {
+			meta.SetStatusCondition(&status.Conditions, newCondition(resource, typeProgressing, metav1.ConditionTrue, reasonReconciling, fmt.Sprintf("Reconciling generation %d", resource.Generation)))
+
+			if meta.FindStatusCondition(status.Conditions, typeAvailableCollector) == nil {
+				meta.SetStatusCondition(&status.Conditions, newCondition(resource, typeAvailableCollector, metav1.ConditionUnknown, reasonReconciling, "Starting reconciliation"))
+			}
+		})
 		if err != nil {
 			return err
 		}
 	}
 
-	// Get the collector chart from the helm chart bucket in AWS
-	collectorChart, err := getCollectorChart(ctx, resource)
-	if err != nil {
-		return fmt.Errorf("could not get collector chart: %w", err)
+	// A collector of a Tenant isn't deployed until the namespace of the Tenant is provisioned, it is reconciled
+	// again once the Tenant changes
+	if resource.Spec.Tenant.Name != "" {
+		tenantReady := r.Controller.collectorTenant(resource)
+
+		resource, err = r.Controller.MutateStatus(ctx, resource, func(status *v1alpha.CollectorStatus) {
+			meta.SetStatusCondition(&status.Conditions, tenantReady)
+
+			if tenantReady.Status != metav1.ConditionTrue {
+				meta.SetStatusCondition(&status.Conditions, newCondition(resource, typeProgressing, metav1.ConditionFalse, tenantReady.Reason, tenantReady.Message))
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if tenantReady.Status != metav1.ConditionTrue {
+			klog.Infof("Collector %s/%s is waiting for its tenant: %s", resource.Namespace, resource.Name, tenantReady.Message)
+
+			return nil
+		}
+	} else if meta.FindStatusCondition(resource.Status.Conditions, typeTenantReady) != nil {
+		resource, err = r.Controller.MutateStatus(ctx, resource, func(status *v1alpha.CollectorStatus) {
+			meta.RemoveStatusCondition(&status.Conditions, typeTenantReady)
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	// Unmarshal the values file to use for the helm chart
-	vals, err := getValues(resource.Spec.Collector.Configuration)
+	// Merge the values to use for the helm chart from the ConfigMaps and Secrets and the inline configuration
+	vals, configurationHash, err := r.Controller.configurationSources.collectorValues(ctx, resource)
 	if err != nil {
-		return fmt.Errorf("could not unmarshal values file: %w", err)
+		reason := reasonInvalidConfiguration
+		if errors.Is(err, errConfigurationSourceNotFound) {
+			reason = reasonConfigurationSourceNotFound
+		}
+
+		message := fmt.Sprintf("Configuration of the custom resource (%s) is invalid: (%s)", resource.Name, err)
+
+		if statusErr := r.recordReconcile(ctx, resource, nil,
+			newCondition(resource, typeConfigValid, metav1.ConditionFalse, reason, message),
+			newCondition(resource, typeDegraded, metav1.ConditionTrue, reason, message),
+			newCondition(resource, typeProgressing, metav1.ConditionFalse, reason, message),
+		); statusErr != nil {
+			return statusErr
+		}
+
+		return fmt.Errorf("could not get the values of the collector: %w", err)
 	}
 
-	// tenant reference is used to set the namespace for the collector
-	tenantNamespace := strings.ToLower(resource.Spec.Tenant.Reference)
+	configValid := newCondition(resource, typeConfigValid, metav1.ConditionTrue, reasonConfigurationValid, "Configuration decoded to chart values "+configurationHash)
 
-	// Create a helm configuration
-	setting := cli.New()
-	setting.SetNamespace(tenantNamespace)
+	// Get the collector chart from its chart source
+	collectorChart, err := r.getCollectorChart(ctx, resource)
+	if err != nil {
+		reason := reasonChartResolutionFailed
+		message := fmt.Sprintf("Chart for the custom resource (%s) could not be resolved: (%s)", resource.Name, err)
+		conditions := []metav1.Condition{configValid}
 
-	actionConfig := new(action.Configuration)
+		// A chart that fails verification must never be installed, the failure is surfaced on the resource
+		var verificationErr *ChartVerificationError
+		if errors.As(err, &verificationErr) {
+			reason = reasonChartVerificationFailed
+			message = fmt.Sprintf("Chart for the custom resource (%s) failed verification: (%s)", resource.Name, verificationErr.Err)
 
-	if err = actionConfig.Init(setting.RESTClientGetter(), setting.Namespace(), "memory", myDebugf); err != nil {
-		return fmt.Errorf("error initializing action config: %w", err)
+			r.Controller.recorder.Event(resource, corev1.EventTypeWarning, reasonChartVerificationFailed, message)
+
+			conditions = append(conditions, newCondition(resource, typeAvailableCollector, metav1.ConditionFalse, reason, message))
+		}
+
+		conditions = append(conditions,
+			newCondition(resource, typeChartResolved, metav1.ConditionFalse, reason, message),
+			newCondition(resource, typeDegraded, metav1.ConditionTrue, reason, message),
+			newCondition(resource, typeProgressing, metav1.ConditionFalse, reason, message),
+		)
+
+		if statusErr := r.recordReconcile(ctx, resource, nil, conditions...); statusErr != nil {
+			return statusErr
+		}
+
+		return fmt.Errorf("could not get collector chart: %w", err)
 	}
 
-	// Use config to create a Helm install action and set up the install configuration
-	installAction := action.NewInstall(actionConfig)
+	chartResolved := newCondition(resource, typeChartResolved, metav1.ConditionTrue, reasonChartResolved, fmt.Sprintf("Resolved %q to chart version %s", resource.Spec.Collector.Version, collectorChart.Metadata.Version))
+
+	// The collector is deployed to the namespace of its Tenant, or to its tenant reference
+	namespace := r.Controller.collectorNamespace(resource)
+
+	// Create a helm configuration
+	actionConfig, err := r.newActionConfiguration(namespace)
+	if err != nil {
+		return err
+	}
 
-	installAction.ReleaseName = resource.Spec.Collector.Name + "-" + resource.Spec.Tenant.Instance
-	installAction.Namespace = tenantNamespace
-	installAction.CreateNamespace = true
-	installAction.IsUpgrade = update
-	installAction.Version = "latest"
 	collectorChart.Values["image"] = map[string]string{
 		"repository": "us-central1-docker.pkg.dev/ryanschick/ryanschick-container-repo/" + resource.Spec.Collector.Name,
 	}
 
-	// Render the template and install the collector chart
-	_, err = installAction.Run(collectorChart, vals)
+	// Values that don't match the schemas would deploy a broken collector, the release is left as it is
+	if err = r.ValuesSchemas.validateValues(resource.Spec.Collector.Name, collectorChart, vals); err != nil {
+		reason := reasonInvalidConfiguration
+
+		var schemaErr *ValuesSchemaError
+		if errors.As(err, &schemaErr) {
+			reason = reasonValuesSchemaViolation
+		}
+
+		message := fmt.Sprintf("Configuration of the custom resource (%s) is invalid: (%s)", resource.Name, err)
+
+		if statusErr := r.recordReconcile(ctx, resource, nil,
+			chartResolved,
+			newCondition(resource, typeConfigValid, metav1.ConditionFalse, reason, message),
+			newCondition(resource, typeDegraded, metav1.ConditionTrue, reason, message),
+			newCondition(resource, typeProgressing, metav1.ConditionFalse, reason, message),
+		); statusErr != nil {
+			return statusErr
+		}
+
+		return fmt.Errorf("could not validate the values of the collector: %w", err)
+	}
+
+	// Render the template and install or upgrade the collector chart
+	mapper, err := actionConfig.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+
+	deployed, err := r.deployRelease(actionConfig, resource, namespace, collectorChart, vals, configurationHash, newCollectorPostRenderer(resource, namespace, mapper))
+
+	var rollbackErr *RollbackError
+	if errors.As(err, &rollbackErr) {
+		return r.recordRollback(ctx, resource, rollbackErr, configValid, chartResolved)
+	}
+
 	if err != nil {
 		message := fmt.Sprintf("Failed to create/update Deployment for the custom resource (%s): (%s)", resource.Name, err)
 
-		resource, err = r.Controller.UpdateStatus(ctx, resource, metav1.ConditionFalse, "Reconciling", message)
-		if err != nil {
-			return err
+		if statusErr := r.recordReconcile(ctx, resource, nil,
+			configValid,
+			chartResolved,
+			newCondition(resource, typeInstalled, metav1.ConditionFalse, reasonReleaseFailed, message),
+			newCondition(resource, typeAvailableCollector, metav1.ConditionFalse, reasonReleaseFailed, message),
+			newCondition(resource, typeDegraded, metav1.ConditionTrue, reasonReleaseFailed, message),
+			newCondition(resource, typeProgressing, metav1.ConditionFalse, reasonReleaseFailed, message),
+		); statusErr != nil {
+			return statusErr
 		}
+
+		return err
+	}
+
+	if err = r.Controller.releaseWorkloads.record(resource.UID, deployed.Version, deployed.Manifest); err != nil {
+		klog.Warningf("Could not read the workloads of release %s: %v", deployed.Name, err)
+	}
+
+	// Changes to the objects of the release are watched from now on
+	if objects, buildErr := actionConfig.KubeClient.Build(strings.NewReader(deployed.Manifest), false); buildErr == nil {
+		r.Controller.releaseObjects.watch(objects)
+	} else {
+		klog.Warningf("Could not watch the objects of release %s: %v", deployed.Name, buildErr)
 	}
 
 	// Update the status of the custom resource to show that the deployment was created/updated successfully
-	_, err = r.Controller.UpdateStatus(ctx, resource, metav1.ConditionTrue, "Reconciling", fmt.Sprintf("Deployment for custom resource (%s) created/updated successfully", resource.Name))
+	// along with the chart version and the Helm revision that were deployed. Ready and Available follow
+	// the readiness of the workloads, which is tracked separately.
+	return r.recordReconcile(ctx, resource,
+		func(status *v1alpha.CollectorStatus) {
+			status.ChartVersion = collectorChart.Metadata.Version
+			status.HelmRevision = deployed.Version
+			status.ConfigurationHash = configurationHash
+		},
+		configValid,
+		chartResolved,
+		newCondition(resource, typeInstalled, metav1.ConditionTrue, reasonReleaseDeployed, fmt.Sprintf("Helm release %s revision %d deployed with chart version %s", deployed.Name, deployed.Version, collectorChart.Metadata.Version)),
+		newCondition(resource, typeDegraded, metav1.ConditionFalse, reasonReconcileSucceeded, "The last reconcile succeeded"),
+		newCondition(resource, typeProgressing, metav1.ConditionFalse, reasonReconcileSucceeded, fmt.Sprintf("Generation %d is deployed", resource.Generation)),
+	)
+}
+
+// recordRollback writes a failed upgrade that was rolled back to the status of the collector, along with the
+// conditions of the steps that succeeded, and returns the rollback as an error. The collector is retried with the
+// backoff of the workqueue, since the upgrade may have failed for a reason that goes away, e.g. a rollout that
+// timed out while the cluster was scaling up.
+func (r *CollectorReconciler) recordRollback(ctx context.Context, resource *v1alpha.Collector, rollbackErr *RollbackError, conditions ...metav1.Condition) error {
+	message := fmt.Sprintf("Failed to update Deployment for the custom resource (%s), rolled back to revision %d: (%s)", resource.Name, rollbackErr.RestoredRevision, rollbackErr.Err)
+
+	conditions = append(conditions,
+		newCondition(resource, typeInstalled, metav1.ConditionFalse, reasonRolledBack, message),
+		newCondition(resource, typeAvailableCollector, metav1.ConditionFalse, reasonRolledBack, message),
+		newCondition(resource, typeDegraded, metav1.ConditionTrue, reasonRolledBack, message),
+		newCondition(resource, typeProgressing, metav1.ConditionFalse, reasonRolledBack, message),
+	)
+
+	err := r.recordReconcile(ctx, resource,
+		func(status *v1alpha.CollectorStatus) {
+			status.FailedRevision = rollbackErr.FailedRevision
+			status.RestoredRevision = rollbackErr.RestoredRevision
+			status.HelmRevision = rollbackErr.Revision
+		},
+		conditions...,
+	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return fmt.Errorf("could not upgrade the collector: %w", rollbackErr)
 }
 
-// getCollectorChart retrieves the collector chart from the helm chart bucket in AWS.
-func getCollectorChart(ctx context.Context, resource *v1alpha.Collector) (*chart.Chart, error) {
-	awsCreds := aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider("", "", ""))
+// deployRelease installs or upgrades the release of the collector, unless its deployed revision already is the
+// one recorded in the status, with the same chart version and values. Resyncs, restarts and the changes to the
+// collector that don't change its release then don't write a new Helm revision, nor wait for the release.
+func (r *CollectorReconciler) deployRelease(actionConfig *action.Configuration, resource *v1alpha.Collector, namespace string, collectorChart *chart.Chart, vals map[string]interface{}, configurationHash string, postRenderer postrender.PostRenderer) (*release.Release, error) {
+	name := releaseName(resource)
 
-	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(awsCreds), config.WithRegion("us-west-2"), config.WithHTTPClient(instrumentation.InstrumentHTTPClient(&http.Client{})))
+	last, err := lastRelease(actionConfig, name)
 	if err != nil {
 		return nil, err
 	}
 
-	s3Client := s3.NewFromConfig(awsConfig)
+	status := resource.Status
 
-	chartName, bucketName, err := getLatestCollectorChartPath(ctx, resource)
+	// Releases deployed before their objects were labelled by the post-renderer are upgraded once to label them
+	if last != nil && last.Info.Status == release.StatusDeployed && last.Version == status.HelmRevision &&
+		status.ChartVersion == collectorChart.Metadata.Version && status.ConfigurationHash == configurationHash &&
+		strings.Contains(last.Manifest, LabelCollectorUID) {
+		klog.V(4).Infof("Release %s/%s revision %d is up to date, not upgrading it", namespace, name, last.Version)
+
+		return last, nil
+	}
+
+	return r.installOrUpgrade(actionConfig, namespace, resource.Spec.Tenant.Name == "", name, collectorChart, vals, postRenderer)
+}
+
+// getCollectorChart retrieves the collector chart from the chart source selected for the collector,
+// at the version resolved from Spec.Collector.Version.
+func (r *CollectorReconciler) getCollectorChart(ctx context.Context, resource *v1alpha.Collector) (*chart.Chart, error) {
+	source, err := r.ChartSources.For(resource)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get the collector chart file from aws bucket
-	object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(chartName),
-	})
+	version, err := resolveChartVersion(ctx, r.ChartSources.versions, source, resource.Spec.Collector.Name, resource.Spec.Collector.Version)
 	if err != nil {
 		return nil, err
 	}
 
-	// Load the collector chart from the aws object
-	collectorChart, err := loader.LoadArchive(object.Body)
+	archive, err := r.ChartSources.Fetch(ctx, source, resource.Spec.Collector.Name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the collector chart from the archive, every reconcile gets its own copy since the chart is modified below
+	collectorChart, err := loader.LoadArchive(bytes.NewReader(archive.Data))
 	if err != nil {
 		return nil, err
 	}
@@ -145,32 +332,3 @@ func getCollectorChart(ctx context.Context, resource *v1alpha.Collector) (*chart
 
 	return collectorChart, nil
 }
-
-// getLatestCollectorChartPath retrieves the latest collector chart path from the helm chart bucket in AWS whether it is in development or production.
-func getLatestCollectorChartPath(ctx context.Context, resource *v1alpha.Collector) (string, string, error) {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: githubToken})
-	tc := oauth2.NewClient(ctx, ts)
-
-	// Create a GitHub client using the authenticated HTTP client.
-	gitClient := github.NewClient(tc)
-
-	// Get the latest release for the collector chart based on the environment.
-	// If the environment is production, then the latest release will be the latest release tag.
-	switch resource.Spec.Cluster {
-	case "development":
-		chartName := "charts/" + resource.Spec.Collector.Name + "-0.0.1.tgz"
-		bucketName := "development-helm"
-
-		return chartName, bucketName, nil
-	default:
-		release, _, err := gitClient.Repositories.GetLatestRelease(ctx, "rmschick", resource.Spec.Collector.Name)
-		if err != nil {
-			return "", "", fmt.Errorf("failed to get latest release: %w", err)
-		}
-
-		chartName := "charts/" + resource.Spec.Collector.Name + "-" + *release.TagName + ".tgz"
-		bucketName := "production-helm"
-
-		return chartName, bucketName, nil
-	}
-}
@@ -0,0 +1,224 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// newTestActionConfiguration returns a Helm action configuration storing the releases in memory, which applies the
+// releases with the given fake client.
+func newTestActionConfiguration(t *testing.T, kubeClient kube.Interface) *action.Configuration {
+	t.Helper()
+
+	return &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   kubeClient,
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          t.Logf,
+	}
+}
+
+// waitingKubeClient is a fake client that returns the given errors from its waits for a release, one per wait,
+// and succeeds once they are used up.
+type waitingKubeClient struct {
+	kubefake.PrintingKubeClient
+	waitErrors []error
+}
+
+func newWaitingKubeClient(waitErrors ...error) *waitingKubeClient {
+	return &waitingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}, waitErrors: waitErrors}
+}
+
+func (c *waitingKubeClient) Wait(kube.ResourceList, time.Duration) error {
+	if len(c.waitErrors) == 0 {
+		return nil
+	}
+
+	err := c.waitErrors[0]
+	c.waitErrors = c.waitErrors[1:]
+
+	return err
+}
+
+// testChart returns a chart of the collector at the given version, rendering a ConfigMap labelled with the UID of
+// the collector.
+func testChart(version string) *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "fluent-bit", Version: version},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n  labels:\n    " + LabelCollectorUID + ": 4a7d\ndata:\n  level: {{ .Values.level | quote }}\n"),
+		}},
+		Values: map[string]interface{}{"level": "info"},
+	}
+}
+
+// storeRelease records a revision of the release of the test collector.
+func storeRelease(t *testing.T, actionConfig *action.Configuration, version int, status release.Status, manifest string) {
+	t.Helper()
+
+	rel := &release.Release{
+		Name:      "fluent-bit-main",
+		Namespace: "acme",
+		Version:   version,
+		Chart:     testChart("1.0.0"),
+		Config:    map[string]interface{}{},
+		Manifest:  manifest,
+		Info:      &release.Info{Status: status, LastDeployed: helmtime.Now()},
+	}
+
+	if err := actionConfig.Releases.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeployRelease(t *testing.T) {
+	labelledManifest := "metadata:\n  labels:\n    " + LabelCollectorUID + ": 4a7d\n"
+
+	tests := []struct {
+		name         string
+		revisions    []release.Status
+		manifest     string
+		status       v1.CollectorStatus
+		chartVersion string
+		wantRevision int
+	}{
+		{
+			name:         "up to date release isn't upgraded",
+			revisions:    []release.Status{release.StatusDeployed},
+			manifest:     labelledManifest,
+			status:       v1.CollectorStatus{HelmRevision: 1, ChartVersion: "1.0.0", ConfigurationHash: "abc"},
+			chartVersion: "1.0.0",
+			wantRevision: 1,
+		},
+		{
+			name:         "release that was never installed is installed",
+			status:       v1.CollectorStatus{},
+			chartVersion: "1.0.0",
+			wantRevision: 1,
+		},
+		{
+			name:         "new chart version is upgraded",
+			revisions:    []release.Status{release.StatusDeployed},
+			manifest:     labelledManifest,
+			status:       v1.CollectorStatus{HelmRevision: 1, ChartVersion: "1.0.0", ConfigurationHash: "abc"},
+			chartVersion: "1.1.0",
+			wantRevision: 2,
+		},
+		{
+			name:         "changed configuration is upgraded",
+			revisions:    []release.Status{release.StatusDeployed},
+			manifest:     labelledManifest,
+			status:       v1.CollectorStatus{HelmRevision: 1, ChartVersion: "1.0.0", ConfigurationHash: "def"},
+			chartVersion: "1.0.0",
+			wantRevision: 2,
+		},
+		{
+			name:         "revision that isn't the one in the status is upgraded",
+			revisions:    []release.Status{release.StatusSuperseded, release.StatusDeployed},
+			manifest:     labelledManifest,
+			status:       v1.CollectorStatus{HelmRevision: 1, ChartVersion: "1.0.0", ConfigurationHash: "abc"},
+			chartVersion: "1.0.0",
+			wantRevision: 3,
+		},
+		{
+			name:         "release deployed before its objects were labelled is upgraded",
+			revisions:    []release.Status{release.StatusDeployed},
+			manifest:     "metadata:\n  name: fluent-bit-main\n",
+			status:       v1.CollectorStatus{HelmRevision: 1, ChartVersion: "1.0.0", ConfigurationHash: "abc"},
+			chartVersion: "1.0.0",
+			wantRevision: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actionConfig := newTestActionConfiguration(t, newWaitingKubeClient())
+			for i, status := range tt.revisions {
+				storeRelease(t, actionConfig, i+1, status, tt.manifest)
+			}
+
+			reconciler := &CollectorReconciler{Storage: &HelmStorage{Driver: StorageDriverMemory}, ReleaseTimeout: time.Minute}
+			collector := testCollector()
+			collector.Status = tt.status
+
+			deployed, err := reconciler.deployRelease(actionConfig, collector, "acme", testChart(tt.chartVersion), map[string]interface{}{}, "abc", nil)
+			if err != nil {
+				t.Fatalf("deployRelease() error = %v", err)
+			}
+
+			if deployed.Version != tt.wantRevision || deployed.Info.Status != release.StatusDeployed {
+				t.Errorf("deployRelease() = revision %d %s, want revision %d deployed", deployed.Version, deployed.Info.Status, tt.wantRevision)
+			}
+		})
+	}
+}
+
+func TestFailedUpgradeIsRolledBack(t *testing.T) {
+	collector := testCollector(collectorFinalizer)
+	controller := newTestController(t, collector)
+	reconciler := controller.reconciler
+	actionConfig := newTestActionConfiguration(t, newWaitingKubeClient(nil, errors.New("timed out waiting for the condition")))
+
+	if _, err := reconciler.installOrUpgrade(actionConfig, "acme", true, "fluent-bit-main", testChart("1.0.0"), map[string]interface{}{}, nil); err != nil {
+		t.Fatalf("install error = %v", err)
+	}
+
+	_, err := reconciler.installOrUpgrade(actionConfig, "acme", true, "fluent-bit-main", testChart("1.1.0"), map[string]interface{}{}, nil)
+
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("upgrade error = %v, want a RollbackError", err)
+	}
+
+	err = reconciler.recordRollback(context.Background(), collector, rollbackErr, newCondition(collector, typeChartResolved, metav1.ConditionTrue, reasonChartResolved, "Resolved"))
+	if !errors.As(err, &rollbackErr) {
+		t.Errorf("recordRollback() error = %v, want the RollbackError so that the collector is retried", err)
+	}
+
+	stored, err := controller.resourceclientset.ExampleV1alpha().Collectors("acme").Get(context.Background(), "logs", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status.FailedRevision != 2 || stored.Status.RestoredRevision != 1 || stored.Status.HelmRevision != 3 {
+		t.Errorf("status revisions = failed %d, restored %d, helm %d, want 2, 1, 3", stored.Status.FailedRevision, stored.Status.RestoredRevision, stored.Status.HelmRevision)
+	}
+
+	for _, conditionType := range []string{typeInstalled, typeAvailableCollector, typeDegraded, typeProgressing} {
+		condition := meta.FindStatusCondition(stored.Status.Conditions, conditionType)
+		if condition == nil || condition.Reason != reasonRolledBack {
+			t.Errorf("%s condition = %+v, want reason %s", conditionType, condition, reasonRolledBack)
+		}
+	}
+
+	if !meta.IsStatusConditionTrue(stored.Status.Conditions, typeChartResolved) || !meta.IsStatusConditionTrue(stored.Status.Conditions, typeDegraded) {
+		t.Errorf("conditions = %+v, want ChartResolved and Degraded", stored.Status.Conditions)
+	}
+
+	last, err := lastRelease(actionConfig, "fluent-bit-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.Version != 3 || last.Info.Status != release.StatusDeployed || last.Chart.Metadata.Version != "1.0.0" {
+		t.Errorf("last release = revision %d %s of chart %s, want revision 3 deployed of chart 1.0.0", last.Version, last.Info.Status, last.Chart.Metadata.Version)
+	}
+}
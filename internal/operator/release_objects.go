@@ -0,0 +1,310 @@
+package operator
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// Labels the operator puts on every object rendered for a collector, so that the object can be mapped back to
+// the Collector it was deployed for.
+const (
+	LabelCollectorUID       = v1alpha.GroupName + "/collector-uid"
+	LabelCollectorName      = v1alpha.GroupName + "/collector-name"
+	LabelCollectorNamespace = v1alpha.GroupName + "/collector-namespace"
+)
+
+// uidIndex indexes collectors by their UID.
+const uidIndex = "uid"
+
+// collectorPostRenderer labels the objects rendered for a collector with the Collector they belong to, and sets
+// the Collector as their owner when they are in its namespace. It runs as the Helm post-renderer, so the labels
+// and owner references are part of the release manifest and every install, upgrade and rollback applies them.
+type collectorPostRenderer struct {
+	collector *v1alpha.Collector
+	// namespace is the namespace of the release, the objects without a namespace are deployed to it
+	namespace string
+	mapper    meta.RESTMapper
+}
+
+// newCollectorPostRenderer creates the post-renderer of the collector release. The mapper tells namespaced
+// objects from cluster scoped ones, which can't be owned by a Collector.
+func newCollectorPostRenderer(resource *v1alpha.Collector, namespace string, mapper meta.RESTMapper) *collectorPostRenderer {
+	return &collectorPostRenderer{collector: resource, namespace: namespace, mapper: mapper}
+}
+
+// Run labels and owner-references the rendered manifests, keeping their order and their # Source comments.
+func (p *collectorPostRenderer) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	manifests := releaseutil.SplitManifests(rendered.String())
+
+	keys := make([]string, 0, len(manifests))
+	for key := range manifests {
+		keys = append(keys, key)
+	}
+
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	result := &bytes.Buffer{}
+
+	for _, key := range keys {
+		var comments []string
+
+		for _, line := range strings.Split(manifests[key], "\n") {
+			if !strings.HasPrefix(line, "#") {
+				break
+			}
+
+			comments = append(comments, line)
+		}
+
+		object := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(manifests[key]), &object.Object); err != nil {
+			return nil, fmt.Errorf("could not decode rendered manifest: %w", err)
+		}
+
+		// Documents holding only comments are dropped, just like Helm does
+		if len(object.Object) == 0 {
+			continue
+		}
+
+		p.label(object)
+
+		data, err := yaml.Marshal(object.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		result.WriteString("---\n")
+
+		for _, comment := range comments {
+			result.WriteString(comment + "\n")
+		}
+
+		result.Write(data)
+	}
+
+	return result, nil
+}
+
+// label sets the collector labels on the object, and the Collector as its owner when they share a namespace.
+func (p *collectorPostRenderer) label(object *unstructured.Unstructured) {
+	labels := object.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	for label, value := range map[string]string{
+		LabelCollectorUID:       string(p.collector.UID),
+		LabelCollectorName:      p.collector.Name,
+		LabelCollectorNamespace: p.collector.Namespace,
+	} {
+		// Names longer than a label value are left out, the UID label is enough to find the Collector
+		if len(validation.IsValidLabelValue(value)) == 0 {
+			labels[label] = value
+		}
+	}
+
+	object.SetLabels(labels)
+
+	// The pods of the workloads carry the UID label as well, so that only the pods of collectors are watched
+	if workloadKinds[object.GroupVersionKind().GroupKind()] {
+		templateLabels, _, _ := unstructured.NestedStringMap(object.Object, "spec", "template", "metadata", "labels")
+		if templateLabels == nil {
+			templateLabels = map[string]string{}
+		}
+
+		templateLabels[LabelCollectorUID] = string(p.collector.UID)
+
+		if err := unstructured.SetNestedStringMap(object.Object, templateLabels, "spec", "template", "metadata", "labels"); err != nil {
+			utilruntime.HandleError(fmt.Errorf("could not label the pod template of %s %s: %w", object.GetKind(), object.GetName(), err))
+		}
+	}
+
+	namespace := object.GetNamespace()
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	// Objects the chart asks Helm to keep must outlive the Collector, so they aren't owned by it
+	if namespace != p.collector.Namespace || object.GetAnnotations()[resourcePolicyAnnotation] == resourcePolicyKeep || !p.namespaced(object) {
+		return
+	}
+
+	// An object already controlled by something else, as set by its chart, is only owned
+	controller := true
+
+	for _, owner := range object.GetOwnerReferences() {
+		if owner.UID == p.collector.UID {
+			return
+		}
+
+		if owner.Controller != nil && *owner.Controller {
+			controller = false
+		}
+	}
+
+	object.SetOwnerReferences(append(object.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion: v1alpha.SchemeGroupVersion.String(),
+		Kind:       v1alpha.Kind,
+		Name:       p.collector.Name,
+		UID:        p.collector.UID,
+		Controller: &controller,
+	}))
+}
+
+// namespaced reports whether the object is namespaced. Kinds the API server doesn't know about yet are assumed
+// not to be, they are only labelled.
+func (p *collectorPostRenderer) namespaced(object *unstructured.Unstructured) bool {
+	gvk := object.GroupVersionKind()
+
+	mapping, err := p.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		klog.V(4).Infof("Could not map %s, not setting its owner: %v", gvk.String(), err)
+
+		return false
+	}
+
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
+
+// releaseObjectInformers watches every kind of object the collector releases are made of, limited to the
+// objects labelled with a Collector. An informer is started for a kind the first time a release holding it is
+// seen, since which kinds the charts render isn't known up front.
+type releaseObjectInformers struct {
+	factory dynamicinformer.DynamicSharedInformerFactory
+	handler cache.ResourceEventHandler
+
+	mu      sync.Mutex
+	watched map[schema.GroupVersionResource]bool
+	stop    <-chan struct{}
+}
+
+// newReleaseObjectInformers creates the informers of the release objects, their events are sent to the handler.
+func newReleaseObjectInformers(client dynamic.Interface, handler cache.ResourceEventHandler) *releaseObjectInformers {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resyncePeriod, metav1.NamespaceAll, func(options *metav1.ListOptions) {
+		options.LabelSelector = LabelCollectorUID
+	})
+
+	return &releaseObjectInformers{factory: factory, handler: handler, watched: map[schema.GroupVersionResource]bool{}}
+}
+
+// start runs the informers of the kinds watched so far, and of those watched later on, until stop is closed.
+func (i *releaseObjectInformers) start(stop <-chan struct{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.stop = stop
+	i.factory.Start(stop)
+}
+
+// watch starts watching the kinds of the objects that aren't watched yet.
+func (i *releaseObjectInformers) watch(objects []*resource.Info) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	added := false
+
+	for _, object := range objects {
+		if object.Mapping == nil || i.watched[object.Mapping.Resource] {
+			continue
+		}
+
+		if _, err := i.factory.ForResource(object.Mapping.Resource).Informer().AddEventHandler(i.handler); err != nil {
+			utilruntime.HandleError(fmt.Errorf("could not watch %s: %w", object.Mapping.Resource.String(), err))
+
+			continue
+		}
+
+		klog.Infof("Watching %s of collector releases", object.Mapping.Resource.String())
+
+		i.watched[object.Mapping.Resource] = true
+		added = true
+	}
+
+	// Informers added once the factory runs are started right away
+	if added && i.stop != nil {
+		i.factory.Start(i.stop)
+	}
+}
+
+// collectorUIDIndex indexes a collector by its UID.
+func collectorUIDIndex(obj interface{}) ([]string, error) {
+	collector, ok := obj.(*v1alpha.Collector)
+	if !ok {
+		return nil, fmt.Errorf("expected Collector but got %T", obj)
+	}
+
+	return []string{string(collector.UID)}, nil
+}
+
+// enqueueReleaseObjectCollector queues a drift check of the Collector the release object is labelled with.
+// A drift check, rather than a reconcile, compares the object with the release manifest without upgrading
+// the release, and its drift policy decides what happens.
+func (c *Controller) enqueueReleaseObjectCollector(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+
+		return
+	}
+
+	collectors, err := c.informer.GetIndexer().ByIndex(uidIndex, object.GetLabels()[LabelCollectorUID])
+	if err != nil {
+		utilruntime.HandleError(err)
+
+		return
+	}
+
+	for _, collector := range collectors {
+		key, err := cache.MetaNamespaceKeyFunc(collector)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			continue
+		}
+
+		c.driftQueue.Add(key)
+	}
+}
+
+// releaseObjectEventHandler queues a drift check of the Collector of a release object whenever the object is
+// changed or deleted. Status updates, e.g. of a rollout, aren't changes to the object.
+func (c *Controller) releaseObjectEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueReleaseObjectCollector,
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			oldUnstructured, oldOK := oldObject.(*unstructured.Unstructured)
+			newUnstructured, newOK := newObject.(*unstructured.Unstructured)
+
+			if oldOK && newOK && reflect.DeepEqual(comparableObject(oldUnstructured), comparableObject(newUnstructured)) {
+				return
+			}
+
+			c.enqueueReleaseObjectCollector(newObject)
+		},
+		DeleteFunc: c.enqueueReleaseObjectCollector,
+	}
+}
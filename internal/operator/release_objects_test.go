@@ -0,0 +1,257 @@
+package operator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// newTestRESTMapper maps ConfigMaps and Deployments as namespaced kinds and ClusterRoles as a cluster scoped one.
+func newTestRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+
+	return mapper
+}
+
+func TestCollectorPostRendererLabel(t *testing.T) {
+	controlled := true
+	otherController := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "fluent-bit", UID: "9c1e", Controller: &controlled}
+
+	tests := []struct {
+		name           string
+		object         *unstructured.Unstructured
+		owners         []metav1.OwnerReference
+		wantOwned      bool
+		wantController bool
+		wantOwners     int
+	}{
+		{
+			name:           "object in the namespace of the collector",
+			object:         testObject("v1", "ConfigMap", "", "config", nil, nil),
+			wantOwned:      true,
+			wantController: true,
+			wantOwners:     1,
+		},
+		{
+			name:   "object in another namespace",
+			object: testObject("v1", "ConfigMap", "monitoring", "config", nil, nil),
+		},
+		{
+			name:   "cluster scoped object",
+			object: testObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "fluent-bit", nil, nil),
+		},
+		{
+			name:   "object of a kind the API server doesn't know",
+			object: testObject("example.com/v1", "Widget", "", "widget", nil, nil),
+		},
+		{
+			name:   "object Helm keeps",
+			object: testObject("v1", "ConfigMap", "", "config", nil, map[string]string{resourcePolicyAnnotation: resourcePolicyKeep}),
+		},
+		{
+			name:       "object controlled by something else",
+			object:     testObject("v1", "ConfigMap", "acme", "config", map[string]string{"app": "fluent-bit"}, nil),
+			owners:     []metav1.OwnerReference{otherController},
+			wantOwned:  true,
+			wantOwners: 2,
+		},
+		{
+			name:           "object already owned by the collector",
+			object:         testObject("v1", "ConfigMap", "acme", "config", nil, nil),
+			owners:         []metav1.OwnerReference{{APIVersion: v1.SchemeGroupVersion.String(), Kind: v1.Kind, Name: "logs", UID: "4a7d", Controller: &controlled}},
+			wantOwned:      true,
+			wantController: true,
+			wantOwners:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.object.SetOwnerReferences(tt.owners)
+
+			newCollectorPostRenderer(testCollector(), "acme", newTestRESTMapper()).label(tt.object)
+
+			labels := tt.object.GetLabels()
+			if labels[LabelCollectorUID] != "4a7d" || labels[LabelCollectorName] != "logs" || labels[LabelCollectorNamespace] != "acme" {
+				t.Errorf("labels = %v, want the collector labels", labels)
+			}
+
+			var collectorOwners []metav1.OwnerReference
+
+			for _, owner := range tt.object.GetOwnerReferences() {
+				if owner.UID == "4a7d" {
+					collectorOwners = append(collectorOwners, owner)
+				}
+			}
+
+			if !tt.wantOwned {
+				if len(collectorOwners) != 0 {
+					t.Errorf("owner references = %+v, want none of the collector", collectorOwners)
+				}
+
+				return
+			}
+
+			if len(collectorOwners) != 1 || collectorOwners[0].Kind != v1.Kind || collectorOwners[0].Name != "logs" {
+				t.Fatalf("owner references = %+v, want the collector once", tt.object.GetOwnerReferences())
+			}
+
+			if controller := collectorOwners[0].Controller; controller == nil || *controller != tt.wantController {
+				t.Errorf("collector owner reference controller = %v, want %v", controller, tt.wantController)
+			}
+
+			if len(tt.object.GetOwnerReferences()) != tt.wantOwners {
+				t.Errorf("owner references = %+v, want %d with the owners of the chart kept", tt.object.GetOwnerReferences(), tt.wantOwners)
+			}
+		})
+	}
+}
+
+func TestCollectorPostRendererLabelsPodTemplate(t *testing.T) {
+	deployment := testObject("apps/v1", "Deployment", "", "fluent-bit", nil, nil)
+	if err := unstructured.SetNestedStringMap(deployment.Object, map[string]string{"app": "fluent-bit"}, "spec", "template", "metadata", "labels"); err != nil {
+		t.Fatal(err)
+	}
+
+	newCollectorPostRenderer(testCollector(), "acme", newTestRESTMapper()).label(deployment)
+
+	labels, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "metadata", "labels")
+	if labels["app"] != "fluent-bit" || labels[LabelCollectorUID] != "4a7d" {
+		t.Errorf("pod template labels = %v, want the chart labels and the UID label", labels)
+	}
+
+	// Only workloads have pod templates to label
+	configMap := testObject("v1", "ConfigMap", "", "config", nil, nil)
+	newCollectorPostRenderer(testCollector(), "acme", newTestRESTMapper()).label(configMap)
+
+	if _, found, _ := unstructured.NestedFieldNoCopy(configMap.Object, "spec"); found {
+		t.Errorf("ConfigMap = %v, want no pod template", configMap.Object)
+	}
+}
+
+func TestCollectorPostRendererRun(t *testing.T) {
+	rendered := strings.Join([]string{
+		"---\n# Source: fluent-bit/templates/notes.yaml\n# only comments\n",
+		"---\n# Source: fluent-bit/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: fluent-bit\n",
+		"---\n# Source: fluent-bit/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+	}, "")
+
+	result, err := newCollectorPostRenderer(testCollector(), "acme", newTestRESTMapper()).Run(bytes.NewBufferString(rendered))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	manifests := releaseutil.SplitManifests(result.String())
+	if len(manifests) != 2 {
+		t.Fatalf("Run() = %q, want the Deployment and the ConfigMap", result.String())
+	}
+
+	for _, source := range []string{"# Source: fluent-bit/templates/deployment.yaml", "# Source: fluent-bit/templates/configmap.yaml"} {
+		if !strings.Contains(result.String(), source+"\n") {
+			t.Errorf("Run() = %q, want the %q comment kept", result.String(), source)
+		}
+	}
+
+	if strings.Index(result.String(), "kind: Deployment") > strings.Index(result.String(), "kind: ConfigMap") {
+		t.Errorf("Run() = %q, want the order of the manifests kept", result.String())
+	}
+
+	for _, manifest := range manifests {
+		object := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(manifest), &object.Object); err != nil {
+			t.Fatal(err)
+		}
+
+		if object.GetLabels()[LabelCollectorUID] != "4a7d" || len(object.GetOwnerReferences()) != 1 {
+			t.Errorf("%s labels = %v, owner references = %+v, want labelled and owned by the collector", object.GetKind(), object.GetLabels(), object.GetOwnerReferences())
+		}
+	}
+
+	if _, err := newCollectorPostRenderer(testCollector(), "acme", newTestRESTMapper()).Run(bytes.NewBufferString("---\nkind: [\n")); err == nil {
+		t.Error("Run() of an invalid manifest error = nil, want an error")
+	}
+}
+
+func TestReleaseObjectEventHandler(t *testing.T) {
+	object := func(uid string, data string, resourceVersion string) *unstructured.Unstructured {
+		configMap := testObject("v1", "ConfigMap", "acme", "config", map[string]string{LabelCollectorUID: uid}, nil)
+		configMap.SetResourceVersion(resourceVersion)
+		configMap.Object["data"] = map[string]interface{}{"level": data}
+
+		return configMap
+	}
+
+	tests := []struct {
+		name   string
+		handle func(handler cache.ResourceEventHandler)
+		want   bool
+	}{
+		{
+			name:   "added object of a collector",
+			handle: func(handler cache.ResourceEventHandler) { handler.OnAdd(object("4a7d", "info", "1"), false) },
+			want:   true,
+		},
+		{
+			name:   "added object of an unknown collector",
+			handle: func(handler cache.ResourceEventHandler) { handler.OnAdd(object("9c1e", "info", "1"), false) },
+		},
+		{
+			name: "changed object",
+			handle: func(handler cache.ResourceEventHandler) {
+				handler.OnUpdate(object("4a7d", "info", "1"), object("4a7d", "debug", "2"))
+			},
+			want: true,
+		},
+		{
+			name: "status update",
+			handle: func(handler cache.ResourceEventHandler) {
+				updated := object("4a7d", "info", "2")
+				updated.Object["status"] = map[string]interface{}{"ready": true}
+				handler.OnUpdate(object("4a7d", "info", "1"), updated)
+			},
+		},
+		{
+			name:   "deleted object",
+			handle: func(handler cache.ResourceEventHandler) { handler.OnDelete(object("4a7d", "info", "1")) },
+			want:   true,
+		},
+		{
+			name: "object deleted while disconnected",
+			handle: func(handler cache.ResourceEventHandler) {
+				handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "acme/config", Obj: object("4a7d", "info", "1")})
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestController(t, testCollector())
+
+			tt.handle(controller.releaseObjectEventHandler())
+
+			if queued := controller.driftQueue.Len() == 1; queued != tt.want {
+				t.Fatalf("drift queue length = %d, want the collector queued %v", controller.driftQueue.Len(), tt.want)
+			}
+
+			if tt.want {
+				if key, _ := controller.driftQueue.Get(); key != "acme/logs" {
+					t.Errorf("queued %v, want acme/logs", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+	applyv1 "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+)
+
+// statusFieldManager is the field manager the operator applies the status of the collectors and collector sets with.
+const statusFieldManager = "kube8-operator"
+
+// SetCondition sets a status condition on the Collector resource in the API server.
+func (c *Controller) SetCondition(ctx context.Context, resource *v1.Collector, condition metav1.Condition) (*v1.Collector, error) {
+	return c.MutateStatus(ctx, resource, func(status *v1.CollectorStatus) {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
+
+// MutateStatus applies the mutation to the status of the Collector resource in the API server. The whole mutated
+// status is server-side applied to the status subresource, guarded by the resource version the mutation was computed
+// from. On a conflict, e.g. the readiness worker wrote the status of the same Collector, the latest Collector is
+// read and the mutation is applied to its status again, so that the other write isn't undone. Nothing is written
+// when the mutation doesn't change the status. The Collector returned by the apply is returned, so no extra GET is
+// needed.
+func (c *Controller) MutateStatus(ctx context.Context, resource *v1.Collector, mutate func(status *v1.CollectorStatus)) (*v1.Collector, error) {
+	current := resource
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		status := current.Status.DeepCopy()
+		mutate(status)
+
+		if equality.Semantic.DeepEqual(status, &current.Status) {
+			return nil
+		}
+
+		applyConfiguration := applyv1.Collector(current.Name, current.Namespace).WithResourceVersion(current.ResourceVersion).WithStatus(collectorStatusApplyConfiguration(status))
+
+		updated, err := c.resourceclientset.ExampleV1alpha().Collectors(current.Namespace).ApplyStatus(ctx, applyConfiguration, metav1.ApplyOptions{FieldManager: statusFieldManager, Force: true})
+		if apierrors.IsConflict(err) {
+			latest, getErr := c.resourceclientset.ExampleV1alpha().Collectors(current.Namespace).Get(ctx, current.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+
+			current = latest
+
+			return err
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to update Collector status: %w", err)
+		}
+
+		current = updated
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return current, nil
+}
+
+// collectorStatusApplyConfiguration returns the apply configuration of the whole status. Since the status is always
+// applied as a whole by the same field manager, a condition or field left out of it, e.g. a cleared lastPodFailure,
+// is removed. Conditions are merged by their type, so those that didn't change keep their lastTransitionTime.
+func collectorStatusApplyConfiguration(status *v1.CollectorStatus) *applyv1.CollectorStatusApplyConfiguration {
+	applyConfiguration := applyv1.CollectorStatus().WithConditions(status.Conditions...)
+
+	if status.ObservedGeneration != 0 {
+		applyConfiguration.WithObservedGeneration(status.ObservedGeneration)
+	}
+
+	if status.ChartVersion != "" {
+		applyConfiguration.WithChartVersion(status.ChartVersion)
+	}
+
+	if status.HelmRevision != 0 {
+		applyConfiguration.WithHelmRevision(status.HelmRevision)
+	}
+
+	if status.ConfigurationHash != "" {
+		applyConfiguration.WithConfigurationHash(status.ConfigurationHash)
+	}
+
+	if status.LastReconcileTime != nil {
+		applyConfiguration.WithLastReconcileTime(*status.LastReconcileTime)
+	}
+
+	if status.FailedRevision != 0 {
+		applyConfiguration.WithFailedRevision(status.FailedRevision)
+	}
+
+	if status.RestoredRevision != 0 {
+		applyConfiguration.WithRestoredRevision(status.RestoredRevision)
+	}
+
+	if status.ReadyReplicas != 0 {
+		applyConfiguration.WithReadyReplicas(status.ReadyReplicas)
+	}
+
+	if status.DesiredReplicas != 0 {
+		applyConfiguration.WithDesiredReplicas(status.DesiredReplicas)
+	}
+
+	for _, workload := range status.Workloads {
+		workloadConfiguration := applyv1.WorkloadStatus().
+			WithKind(workload.Kind).
+			WithName(workload.Name).
+			WithDesiredReplicas(workload.DesiredReplicas).
+			WithReadyReplicas(workload.ReadyReplicas).
+			WithUpdatedReplicas(workload.UpdatedReplicas).
+			WithReady(workload.Ready)
+
+		if workload.Message != "" {
+			workloadConfiguration.WithMessage(workload.Message)
+		}
+
+		applyConfiguration.WithWorkloads(workloadConfiguration)
+	}
+
+	if status.LastPodFailure != "" {
+		applyConfiguration.WithLastPodFailure(status.LastPodFailure)
+	}
+
+	return applyConfiguration
+}
@@ -0,0 +1,161 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+	collectorfake "kube8-operator/pkg/generated/clientset/versioned/fake"
+)
+
+func TestMutateStatus(t *testing.T) {
+	installed := metav1.Condition{Type: typeInstalled, Status: metav1.ConditionTrue, Reason: reasonReleaseDeployed, ObservedGeneration: 1, LastTransitionTime: metav1.Now()}
+	ready := metav1.Condition{Type: typeReady, Status: metav1.ConditionTrue, Reason: reasonWorkloadsReady, ObservedGeneration: 1, LastTransitionTime: metav1.Now()}
+
+	tests := []struct {
+		name             string
+		mutate           func(status *v1.CollectorStatus)
+		conflicts        int
+		wantApplies      int
+		wantMutations    int
+		wantConditions   []string
+		wantChartVersion string
+	}{
+		{
+			name:          "unchanged status isn't written",
+			mutate:        func(status *v1.CollectorStatus) { status.ChartVersion = "1.0.0" },
+			wantMutations: 1,
+		},
+		{
+			name:             "changed status is applied",
+			mutate:           func(status *v1.CollectorStatus) { meta.SetStatusCondition(&status.Conditions, installed) },
+			wantApplies:      1,
+			wantMutations:    1,
+			wantConditions:   []string{typeInstalled},
+			wantChartVersion: "1.0.0",
+		},
+		{
+			name:             "conflict is retried on the latest status",
+			mutate:           func(status *v1.CollectorStatus) { meta.SetStatusCondition(&status.Conditions, installed) },
+			conflicts:        1,
+			wantApplies:      2,
+			wantMutations:    2,
+			wantConditions:   []string{typeReady, typeInstalled},
+			wantChartVersion: "1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := testCollector(collectorFinalizer)
+			collector.Status.ChartVersion = "1.0.0"
+			controller := newTestController(t, collector)
+			clientset := controller.resourceclientset.(*collectorfake.Clientset)
+
+			applies := 0
+			clientset.PrependReactor("patch", "collectors", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				applies++
+
+				patch := action.(clienttesting.PatchAction)
+				// Applies after a conflict are guarded by the resource version of the latest Collector
+				resourceVersion := "1"
+				if applies > 1 {
+					resourceVersion = "2"
+				}
+
+				if patch.GetPatchType() != types.ApplyPatchType || patch.GetSubresource() != "status" || !strings.Contains(string(patch.GetPatch()), `"resourceVersion":"`+resourceVersion+`"`) {
+					t.Errorf("patch = %s of %s %s, want a status apply guarded by resource version %s", patch.GetPatchType(), patch.GetSubresource(), patch.GetPatch(), resourceVersion)
+				}
+
+				if applies > tt.conflicts {
+					return false, nil, nil
+				}
+
+				// The readiness worker wrote the status in the meantime
+				latest := collector.DeepCopy()
+				latest.ResourceVersion = "2"
+				meta.SetStatusCondition(&latest.Status.Conditions, ready)
+
+				if err := clientset.Tracker().Update(v1.SchemeGroupVersion.WithResource(v1.Plural), latest, latest.Namespace); err != nil {
+					t.Fatal(err)
+				}
+
+				return true, nil, apierrors.NewConflict(v1.Resource(v1.Plural), collector.Name, errors.New("the object has been modified"))
+			})
+
+			mutations := 0
+
+			updated, err := controller.MutateStatus(context.Background(), collector, func(status *v1.CollectorStatus) {
+				mutations++
+
+				tt.mutate(status)
+			})
+			if err != nil {
+				t.Fatalf("MutateStatus() error = %v", err)
+			}
+
+			if applies != tt.wantApplies || mutations != tt.wantMutations {
+				t.Errorf("applies = %d, mutations = %d, want %d and %d", applies, mutations, tt.wantApplies, tt.wantMutations)
+			}
+
+			for _, conditionType := range tt.wantConditions {
+				if !meta.IsStatusConditionTrue(updated.Status.Conditions, conditionType) {
+					t.Errorf("conditions = %+v, want %s", updated.Status.Conditions, conditionType)
+				}
+			}
+
+			if tt.wantApplies > 0 && updated.Status.ChartVersion != tt.wantChartVersion {
+				t.Errorf("chart version = %s, want %s", updated.Status.ChartVersion, tt.wantChartVersion)
+			}
+		})
+	}
+}
+
+func TestCollectorStatusApplyConfiguration(t *testing.T) {
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	status := &v1.CollectorStatus{
+		Conditions:         []metav1.Condition{{Type: typeReady, Status: metav1.ConditionFalse, Reason: reasonPodFailing, LastTransitionTime: now}},
+		ObservedGeneration: 2,
+		ChartVersion:       "1.1.0",
+		HelmRevision:       3,
+		ConfigurationHash:  "abc",
+		LastReconcileTime:  &now,
+		FailedRevision:     2,
+		RestoredRevision:   1,
+		ReadyReplicas:      1,
+		DesiredReplicas:    2,
+		Workloads:          []v1.WorkloadStatus{{Kind: "DaemonSet", Name: "fluent-bit", DesiredReplicas: 2, ReadyReplicas: 1, Message: "1/2 pods available"}},
+		LastPodFailure:     "pod fluent-bit-x7k2p container fluent-bit: CrashLoopBackOff",
+	}
+
+	applied, err := json.Marshal(collectorStatusApplyConfiguration(status))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every field of the status is applied, since a field left out is removed
+	got := &v1.CollectorStatus{}
+	if err = json.Unmarshal(applied, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equality.Semantic.DeepEqual(got, status) {
+		t.Errorf("collectorStatusApplyConfiguration() = %s, want every field of %+v", applied, status)
+	}
+
+	if applied, _ = json.Marshal(collectorStatusApplyConfiguration(&v1.CollectorStatus{})); string(applied) != "{}" {
+		t.Errorf("collectorStatusApplyConfiguration() of an empty status = %s, want no fields", applied)
+	}
+}
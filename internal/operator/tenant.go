@@ -0,0 +1,496 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+	applyv1 "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+)
+
+const (
+	// tenantFinalizer blocks the deletion of a Tenant until no Collector is deployed for it anymore.
+	tenantFinalizer = v1alpha.GroupName + "/tenant-finalizer"
+	// tenantFieldManager is the field manager the objects of the tenants and their status are applied with.
+	tenantFieldManager = "kube8-operator"
+	// tenantObjectName is the name of the ResourceQuota, LimitRange, ServiceAccount, Role and RoleBinding of a tenant.
+	tenantObjectName = "tenant"
+	// tenantNetworkPolicyName is the name of the default-deny NetworkPolicy of a tenant.
+	tenantNetworkPolicyName = "tenant-default-deny"
+	// tenantIndex indexes collectors by the name of the Tenant they are deployed for.
+	tenantIndex = "tenant"
+)
+
+// LabelTenant is put on every object provisioned for a Tenant, with the name of the Tenant.
+const LabelTenant = v1alpha.GroupName + "/tenant"
+
+// Tenant condition reasons.
+const (
+	reasonTenantProvisioned       = "Provisioned"
+	reasonTenantProvisioningError = "ProvisioningFailed"
+	reasonTenantCollectorsRemain  = "CollectorsRemain"
+)
+
+// Collector TenantReady condition reasons.
+const (
+	reasonTenantReady    = "TenantReady"
+	reasonTenantNotFound = "TenantNotFound"
+	reasonTenantNotReady = "TenantNotReady"
+)
+
+// tenantObjectResources are the resources of the objects provisioned in the namespace of a tenant.
+var tenantObjectResources = map[string]schema.GroupVersionResource{ // nolint: gochecknoglobals
+	"ResourceQuota":  corev1.SchemeGroupVersion.WithResource("resourcequotas"),
+	"LimitRange":     corev1.SchemeGroupVersion.WithResource("limitranges"),
+	"NetworkPolicy":  networkingv1.SchemeGroupVersion.WithResource("networkpolicies"),
+	"ServiceAccount": corev1.SchemeGroupVersion.WithResource("serviceaccounts"),
+	"Role":           rbacv1.SchemeGroupVersion.WithResource("roles"),
+	"RoleBinding":    rbacv1.SchemeGroupVersion.WithResource("rolebindings"),
+}
+
+// tenantNamespaceName returns the namespace of the tenant, its name unless Spec.Namespace is set.
+func tenantNamespaceName(tenant *v1alpha.Tenant) string {
+	if tenant.Spec.Namespace != "" {
+		return tenant.Spec.Namespace
+	}
+
+	return tenant.Name
+}
+
+// collectorTenantIndex indexes a collector by the name of its Tenant, collectors without one aren't indexed.
+func collectorTenantIndex(obj interface{}) ([]string, error) {
+	collector, ok := obj.(*v1alpha.Collector)
+	if !ok {
+		return nil, fmt.Errorf("expected Collector but got %T", obj)
+	}
+
+	if collector.Spec.Tenant.Name == "" {
+		return nil, nil
+	}
+
+	return []string{collector.Spec.Tenant.Name}, nil
+}
+
+// tenantEventHandler queues a Tenant whenever it changes, along with the collectors that wait for it to be ready.
+// Periodic resyncs are queued as well, so that the provisioned objects are applied again.
+func (c *Controller) tenantEventHandler() cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+
+		tenant, ok := obj.(*v1alpha.Tenant)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("expected Tenant but got %T", obj))
+
+			return
+		}
+
+		c.tenantQueue.Add(tenant.Name)
+
+		collectors, err := c.informer.GetIndexer().ByIndex(tenantIndex, tenant.Name)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			return
+		}
+
+		// Collectors that are deployed already aren't reconciled, that would upgrade their release
+		for _, collector := range collectors {
+			if !meta.IsStatusConditionTrue(collector.(*v1alpha.Collector).Status.Conditions, typeTenantReady) {
+				c.enqueue(collector)
+			}
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, newObject interface{}) { enqueue(newObject) },
+		DeleteFunc: enqueue,
+	}
+}
+
+// RunTenantWorker processes Tenant names off the tenant queue until it is shut down.
+func (c *Controller) RunTenantWorker(ctx context.Context) {
+	for c.processNextTenantItem(ctx) {
+	}
+}
+
+// processNextTenantItem pops a single Tenant name off the tenant queue and provisions the tenant.
+func (c *Controller) processNextTenantItem(ctx context.Context) bool {
+	item, shutdown := c.tenantQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.tenantQueue.Done(item)
+
+	if c.tenantQueue.ShuttingDown() {
+		return false
+	}
+
+	name, ok := item.(string)
+	if !ok {
+		c.tenantQueue.Forget(item)
+		utilruntime.HandleError(fmt.Errorf("expected string in tenant queue but got %#v", item))
+
+		return true
+	}
+
+	if err := c.syncTenant(ctx, name); err != nil {
+		c.tenantQueue.AddRateLimited(name)
+		utilruntime.HandleError(fmt.Errorf("error syncing tenant %q, requeuing: %w", name, err))
+
+		return true
+	}
+
+	c.tenantQueue.Forget(name)
+
+	return true
+}
+
+// syncTenant provisions the namespace of the Tenant and the objects in it, and reports the outcome in its Ready
+// condition. A Tenant being deleted waits for its collectors to be deleted first, its namespace is then garbage
+// collected along with it.
+func (c *Controller) syncTenant(ctx context.Context, name string) error {
+	tenant, err := c.tenantLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	tenant = tenant.DeepCopy()
+
+	if tenant.DeletionTimestamp != nil {
+		return c.finalizeTenant(ctx, tenant)
+	}
+
+	if !hasFinalizer(tenant, tenantFinalizer) {
+		addFinalizer(tenant, tenantFinalizer)
+
+		tenant, err = c.resourceclientset.ExampleV1alpha().Tenants().Update(ctx, tenant, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to add finalizer to Tenant: %w", err)
+		}
+	}
+
+	namespace := tenantNamespaceName(tenant)
+
+	err = c.provisionTenant(ctx, tenant, namespace)
+	if err != nil {
+		message := fmt.Sprintf("Could not provision namespace %s of the tenant: (%s)", namespace, err)
+
+		if statusErr := c.setTenantStatus(ctx, tenant, tenant.Status.Namespace, tenantCondition(tenant, metav1.ConditionFalse, reasonTenantProvisioningError, message)); statusErr != nil {
+			return statusErr
+		}
+
+		return err
+	}
+
+	klog.V(4).Infof("Provisioned tenant %s in namespace %s", name, namespace)
+
+	return c.setTenantStatus(ctx, tenant, namespace, tenantCondition(tenant, metav1.ConditionTrue, reasonTenantProvisioned, "Provisioned namespace "+namespace))
+}
+
+// provisionTenant server side applies the namespace of the tenant and the objects in it. The objects the
+// tenant doesn't ask for anymore, a ResourceQuota or a LimitRange, are deleted.
+// nolint: funlen
+func (c *Controller) provisionTenant(ctx context.Context, tenant *v1alpha.Tenant, namespace string) error {
+	if tenant.Status.Namespace != "" && tenant.Status.Namespace != namespace {
+		return fmt.Errorf("the namespace of the tenant is %s and can't be changed to %s", tenant.Status.Namespace, namespace)
+	}
+
+	if messages := validation.IsDNS1123Label(namespace); len(messages) > 0 {
+		return fmt.Errorf("%s is not a valid namespace name: %s", namespace, strings.Join(messages, ", "))
+	}
+
+	// The CRD rejects the reserved namespaces as well, the namespace of the operator is only known here
+	if strings.HasPrefix(namespace, "kube-") || namespace == metav1.NamespaceDefault || namespace == c.namespace {
+		return fmt.Errorf("namespace %s is reserved", namespace)
+	}
+
+	// A namespace that wasn't created for the tenant isn't adopted, it would be deleted along with the Tenant
+	existing, err := c.kubeclientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not get namespace %s: %w", namespace, err)
+	}
+
+	if err == nil && !tenantOwnsNamespace(tenant, existing) {
+		return fmt.Errorf("namespace %s already exists and wasn't created for the tenant", namespace)
+	}
+
+	controller := true
+	objectMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{LabelTenant: tenant.Name},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha.SchemeGroupVersion.String(),
+				Kind:       v1alpha.TenantKind,
+				Name:       tenant.Name,
+				UID:        tenant.UID,
+				Controller: &controller,
+			}},
+		}
+	}
+
+	namespaceObject := &corev1.Namespace{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"}, ObjectMeta: objectMeta(namespace)}
+	namespaceObject.Namespace = ""
+
+	if tenant.Spec.ID != "" && len(validation.IsValidLabelValue(tenant.Spec.ID)) == 0 {
+		namespaceObject.Labels[LabelTenantID] = tenant.Spec.ID
+	}
+
+	data, err := json.Marshal(namespaceObject)
+	if err != nil {
+		return err
+	}
+
+	force := true
+
+	applied, err := c.kubeclientset.CoreV1().Namespaces().Patch(ctx, namespace, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: tenantFieldManager, Force: &force})
+	if err != nil {
+		return fmt.Errorf("could not apply namespace %s: %w", namespace, err)
+	}
+
+	if applied.Status.Phase == corev1.NamespaceTerminating {
+		return fmt.Errorf("namespace %s is terminating", namespace)
+	}
+
+	objects := map[string]interface{}{
+		"NetworkPolicy": &networkingv1.NetworkPolicy{
+			TypeMeta:   metav1.TypeMeta{APIVersion: networkingv1.SchemeGroupVersion.String(), Kind: "NetworkPolicy"},
+			ObjectMeta: objectMeta(tenantNetworkPolicyName),
+			// Every pod of the namespace is selected, only the traffic of the rules is allowed
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+				Ingress:     tenant.Spec.NetworkPolicy.Ingress,
+				Egress:      tenant.Spec.NetworkPolicy.Egress,
+			},
+		},
+		"ServiceAccount": &corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: objectMeta(tenantObjectName),
+		},
+		"Role": &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+			ObjectMeta: objectMeta(tenantObjectName),
+			Rules:      append([]rbacv1.PolicyRule{}, tenant.Spec.Rules...),
+		},
+		"RoleBinding": &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+			ObjectMeta: objectMeta(tenantObjectName),
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: tenantObjectName, Namespace: namespace}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: tenantObjectName},
+		},
+	}
+
+	var unwanted []string
+
+	if len(tenant.Spec.ResourceQuota) > 0 {
+		objects["ResourceQuota"] = &corev1.ResourceQuota{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ResourceQuota"},
+			ObjectMeta: objectMeta(tenantObjectName),
+			Spec:       corev1.ResourceQuotaSpec{Hard: tenant.Spec.ResourceQuota},
+		}
+	} else {
+		unwanted = append(unwanted, "ResourceQuota")
+	}
+
+	if len(tenant.Spec.LimitRange) > 0 {
+		objects["LimitRange"] = &corev1.LimitRange{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "LimitRange"},
+			ObjectMeta: objectMeta(tenantObjectName),
+			Spec:       corev1.LimitRangeSpec{Limits: tenant.Spec.LimitRange},
+		}
+	} else {
+		unwanted = append(unwanted, "LimitRange")
+	}
+
+	for kind, object := range objects {
+		data, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+
+		name := object.(metav1.Object).GetName()
+
+		_, err = c.dynamicclientset.Resource(tenantObjectResources[kind]).Namespace(namespace).Patch(ctx, name, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: tenantFieldManager, Force: &force})
+		if err != nil {
+			return fmt.Errorf("could not apply %s %s/%s: %w", kind, namespace, name, err)
+		}
+	}
+
+	for _, kind := range unwanted {
+		err = c.dynamicclientset.Resource(tenantObjectResources[kind]).Namespace(namespace).Delete(ctx, tenantObjectName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete %s %s/%s: %w", kind, namespace, tenantObjectName, err)
+		}
+	}
+
+	return nil
+}
+
+// tenantOwnsNamespace reports whether the namespace was created for the tenant: it is controlled by the Tenant, or
+// labelled with its name and not controlled by anything, as left behind by a Tenant of the same name deleted with
+// the orphan propagation policy.
+func tenantOwnsNamespace(tenant *v1alpha.Tenant, namespace *corev1.Namespace) bool {
+	controller := metav1.GetControllerOf(namespace)
+	if controller != nil {
+		return controller.UID == tenant.UID
+	}
+
+	return namespace.Labels[LabelTenant] == tenant.Name
+}
+
+// finalizeTenant removes the tenant finalizer once no Collector is deployed for the Tenant anymore. Its namespace
+// and the objects in it are owned by the Tenant, they are garbage collected once it is gone.
+func (c *Controller) finalizeTenant(ctx context.Context, tenant *v1alpha.Tenant) error {
+	if !hasFinalizer(tenant, tenantFinalizer) {
+		return nil
+	}
+
+	collectors, err := c.informer.GetIndexer().ByIndex(tenantIndex, tenant.Name)
+	if err != nil {
+		return err
+	}
+
+	if len(collectors) > 0 {
+		remaining := make([]string, 0, len(collectors))
+		for _, collector := range collectors {
+			remaining = append(remaining, collector.(*v1alpha.Collector).Namespace+"/"+collector.(*v1alpha.Collector).Name)
+		}
+
+		message := "Waiting for the collectors of the tenant to be deleted: " + strings.Join(remaining, ", ")
+
+		if err = c.setTenantStatus(ctx, tenant, tenant.Status.Namespace, tenantCondition(tenant, metav1.ConditionFalse, reasonTenantCollectorsRemain, message)); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("tenant %s is being deleted: %s", tenant.Name, message)
+	}
+
+	removeFinalizer(tenant, tenantFinalizer)
+
+	_, err = c.resourceclientset.ExampleV1alpha().Tenants().Update(ctx, tenant, metav1.UpdateOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove finalizer from Tenant: %w", err)
+	}
+
+	klog.Infof("Deleted tenant %s", tenant.Name)
+
+	return nil
+}
+
+// tenantCondition returns the Ready condition of the Tenant, observed for its current generation.
+func tenantCondition(tenant *v1alpha.Tenant, status metav1.ConditionStatus, reason string, message string) metav1.Condition {
+	return metav1.Condition{Type: typeReady, Status: status, Reason: reason, Message: message, ObservedGeneration: tenant.Generation}
+}
+
+// setTenantStatus applies the status of the Tenant to its status subresource, like MutateStatus does for the
+// collectors. Nothing is written when the status didn't change.
+func (c *Controller) setTenantStatus(ctx context.Context, tenant *v1alpha.Tenant, namespace string, condition metav1.Condition) error {
+	current := tenant
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		status := current.Status.DeepCopy()
+		meta.SetStatusCondition(&status.Conditions, condition)
+		status.ObservedGeneration = current.Generation
+		status.Namespace = namespace
+
+		if equality.Semantic.DeepEqual(status, &current.Status) {
+			return nil
+		}
+
+		applyStatus := applyv1.TenantStatus().WithConditions(status.Conditions...).WithObservedGeneration(status.ObservedGeneration)
+		if status.Namespace != "" {
+			applyStatus.WithNamespace(status.Namespace)
+		}
+
+		applyConfiguration := applyv1.Tenant(current.Name).WithResourceVersion(current.ResourceVersion).WithStatus(applyStatus)
+
+		_, err := c.resourceclientset.ExampleV1alpha().Tenants().ApplyStatus(ctx, applyConfiguration, metav1.ApplyOptions{FieldManager: tenantFieldManager, Force: true})
+		if apierrors.IsConflict(err) {
+			latest, getErr := c.resourceclientset.ExampleV1alpha().Tenants().Get(ctx, current.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+
+			current = latest
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to update Tenant status: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// collectorTenant checks the Tenant of a collector. It returns the TenantReady condition of the collector, which
+// is True once the Tenant is Ready and its namespace is provisioned for the collector to be deployed to.
+func (c *Controller) collectorTenant(resource *v1alpha.Collector) metav1.Condition {
+	name := resource.Spec.Tenant.Name
+
+	if !c.tenantsServed {
+		return newCondition(resource, typeTenantReady, metav1.ConditionFalse, reasonTenantNotFound, fmt.Sprintf("Tenant %s not found, the Tenant CRD is not installed", name))
+	}
+
+	tenant, err := c.tenantLister.Get(name)
+	if err != nil {
+		return newCondition(resource, typeTenantReady, metav1.ConditionFalse, reasonTenantNotFound, fmt.Sprintf("Tenant %s not found: %s", name, err))
+	}
+
+	ready := meta.FindStatusCondition(tenant.Status.Conditions, typeReady)
+
+	switch {
+	case tenant.DeletionTimestamp != nil:
+		return newCondition(resource, typeTenantReady, metav1.ConditionFalse, reasonTenantNotReady, fmt.Sprintf("Tenant %s is being deleted", name))
+	case ready == nil || ready.Status != metav1.ConditionTrue || ready.ObservedGeneration != tenant.Generation:
+		message := fmt.Sprintf("Tenant %s is not ready", name)
+		if ready != nil {
+			message += ": " + ready.Message
+		}
+
+		return newCondition(resource, typeTenantReady, metav1.ConditionFalse, reasonTenantNotReady, message)
+	default:
+		return newCondition(resource, typeTenantReady, metav1.ConditionTrue, reasonTenantReady, fmt.Sprintf("Tenant %s is ready in namespace %s", name, tenant.Status.Namespace))
+	}
+}
+
+// collectorNamespace returns the namespace the collector is deployed to. A collector of a Tenant is deployed to
+// the namespace provisioned for the Tenant, which can't change once provisioned, and the Tenant outlives its
+// collectors. Other collectors, and those whose Tenant isn't provisioned, use their lowercased tenant reference.
+func (c *Controller) collectorNamespace(resource *v1alpha.Collector) string {
+	if resource.Spec.Tenant.Name == "" || !c.tenantsServed {
+		return tenantNamespace(resource)
+	}
+
+	tenant, err := c.tenantLister.Get(resource.Spec.Tenant.Name)
+	if err != nil || tenant.Status.Namespace == "" {
+		return tenantNamespace(resource)
+	}
+
+	return tenant.Status.Namespace
+}
@@ -0,0 +1,427 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// testTenant returns the acme Tenant, provisioned in the namespace given as its status namespace.
+func testTenant(namespace string, finalizers ...string) *v1.Tenant {
+	tenant := &v1.Tenant{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme", UID: "77b2", Generation: 1, ResourceVersion: "1", Finalizers: finalizers},
+		Spec:       v1.TenantSpec{ID: "a-1"},
+	}
+
+	if namespace != "" {
+		tenant.Status = v1.TenantStatus{
+			Namespace:          namespace,
+			ObservedGeneration: 1,
+			Conditions:         []metav1.Condition{{Type: typeReady, Status: metav1.ConditionTrue, Reason: reasonTenantProvisioned, ObservedGeneration: 1}},
+		}
+	}
+
+	return tenant
+}
+
+// testTenantCollector returns the test collector deployed for the acme Tenant.
+func testTenantCollector() *v1.Collector {
+	collector := testCollector(collectorFinalizer)
+	collector.Spec.Tenant.Name = "acme"
+
+	return collector
+}
+
+// recordTenantApplies answers the server side applies of the tenant namespace, with the given phase, and of the
+// objects in it, and returns the applied objects keyed by their kind, namespace and name.
+func recordTenantApplies(t *testing.T, controller *Controller, phase corev1.NamespacePhase) map[string]*unstructured.Unstructured {
+	t.Helper()
+
+	applied := map[string]*unstructured.Unstructured{}
+
+	controller.kubeclientset.(*kubefake.Clientset).PrependReactor("patch", "namespaces", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		namespace := &corev1.Namespace{}
+		if err := json.Unmarshal(action.(clienttesting.PatchAction).GetPatch(), namespace); err != nil {
+			t.Fatal(err)
+		}
+
+		object := &unstructured.Unstructured{}
+		if err := json.Unmarshal(action.(clienttesting.PatchAction).GetPatch(), &object.Object); err != nil {
+			t.Fatal(err)
+		}
+
+		applied["Namespace/"+namespace.Name] = object
+		namespace.Status.Phase = phase
+
+		return true, namespace, nil
+	})
+
+	controller.dynamicclientset.(*dynamicfake.FakeDynamicClient).PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		object := &unstructured.Unstructured{}
+		if err := json.Unmarshal(action.(clienttesting.PatchAction).GetPatch(), &object.Object); err != nil {
+			t.Fatal(err)
+		}
+
+		applied[object.GetKind()+"/"+object.GetNamespace()+"/"+object.GetName()] = object
+
+		return true, object, nil
+	})
+
+	return applied
+}
+
+// deletedTenantObjects returns the resources of the tenant objects that were deleted.
+func deletedTenantObjects(controller *Controller) []string {
+	var deleted []string
+
+	for _, action := range controller.dynamicclientset.(*dynamicfake.FakeDynamicClient).Actions() {
+		if action.GetVerb() == "delete" {
+			deleted = append(deleted, action.GetResource().Resource)
+		}
+	}
+
+	slices.Sort(deleted)
+
+	return deleted
+}
+
+func TestSyncTenant(t *testing.T) {
+	tests := []struct {
+		name          string
+		tenant        func(tenant *v1.Tenant)
+		namespace     *corev1.Namespace
+		phase         corev1.NamespacePhase
+		wantErr       bool
+		wantNamespace string
+		wantApplied   []string
+		wantDeleted   []string
+	}{
+		{
+			name:          "tenant provisioned in a namespace of its name",
+			wantNamespace: "acme",
+			wantApplied:   []string{"Namespace/acme", "NetworkPolicy/acme/tenant-default-deny", "Role/acme/tenant", "RoleBinding/acme/tenant", "ServiceAccount/acme/tenant"},
+			wantDeleted:   []string{"limitranges", "resourcequotas"},
+		},
+		{
+			name: "tenant provisioned in the namespace it names, with a quota and limits",
+			tenant: func(tenant *v1.Tenant) {
+				tenant.Spec.Namespace = "acme-logs"
+				tenant.Spec.ResourceQuota = corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}
+				tenant.Spec.LimitRange = []corev1.LimitRangeItem{{Type: corev1.LimitTypeContainer, Max: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}}
+			},
+			wantNamespace: "acme-logs",
+			wantApplied: []string{
+				"LimitRange/acme-logs/tenant", "Namespace/acme-logs", "NetworkPolicy/acme-logs/tenant-default-deny", "ResourceQuota/acme-logs/tenant",
+				"Role/acme-logs/tenant", "RoleBinding/acme-logs/tenant", "ServiceAccount/acme-logs/tenant",
+			},
+		},
+		{
+			name:          "namespace left behind by a Tenant of the same name",
+			namespace:     &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme", Labels: map[string]string{LabelTenant: "acme"}}},
+			wantNamespace: "acme",
+			wantApplied:   []string{"Namespace/acme", "NetworkPolicy/acme/tenant-default-deny", "Role/acme/tenant", "RoleBinding/acme/tenant", "ServiceAccount/acme/tenant"},
+			wantDeleted:   []string{"limitranges", "resourcequotas"},
+		},
+		{
+			name:      "namespace that wasn't created for the tenant",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme"}},
+			wantErr:   true,
+		},
+		{
+			name:    "reserved namespace",
+			tenant:  func(tenant *v1.Tenant) { tenant.Spec.Namespace = "kube-system" },
+			wantErr: true,
+		},
+		{
+			name:          "namespace that changed once provisioned",
+			tenant:        func(tenant *v1.Tenant) { tenant.Spec.Namespace = "acme-logs"; tenant.Status.Namespace = "acme" },
+			wantErr:       true,
+			wantNamespace: "acme",
+		},
+		{
+			name:        "terminating namespace",
+			phase:       corev1.NamespaceTerminating,
+			wantErr:     true,
+			wantApplied: []string{"Namespace/acme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := testTenant("")
+			if tt.tenant != nil {
+				tt.tenant(tenant)
+			}
+
+			objects := []runtime.Object{tenant}
+			if tt.namespace != nil {
+				objects = append(objects, tt.namespace)
+			}
+
+			controller := newTestController(t, objects...)
+			applied := recordTenantApplies(t, controller, tt.phase)
+
+			err := controller.syncTenant(context.Background(), "acme")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncTenant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			stored, err := controller.resourceclientset.ExampleV1alpha().Tenants().Get(context.Background(), "acme", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Contains(stored.Finalizers, tenantFinalizer) {
+				t.Errorf("finalizers = %v, want %s", stored.Finalizers, tenantFinalizer)
+			}
+
+			wantStatus, wantReason := metav1.ConditionTrue, reasonTenantProvisioned
+			if tt.wantErr {
+				wantStatus, wantReason = metav1.ConditionFalse, reasonTenantProvisioningError
+			}
+
+			ready := meta.FindStatusCondition(stored.Status.Conditions, typeReady)
+			if ready == nil || ready.Status != wantStatus || ready.Reason != wantReason || ready.ObservedGeneration != 1 {
+				t.Errorf("Ready condition = %+v, want %s with reason %s", ready, wantStatus, wantReason)
+			}
+
+			if stored.Status.Namespace != tt.wantNamespace {
+				t.Errorf("status namespace = %q, want %q", stored.Status.Namespace, tt.wantNamespace)
+			}
+
+			var kinds []string
+
+			for key, object := range applied {
+				kinds = append(kinds, key)
+
+				if object.GetLabels()[LabelTenant] != "acme" {
+					t.Errorf("%s labels = %v, want %s=acme", key, object.GetLabels(), LabelTenant)
+				}
+
+				if owners := object.GetOwnerReferences(); len(owners) != 1 || owners[0].UID != "77b2" || owners[0].Controller == nil || !*owners[0].Controller {
+					t.Errorf("%s owner references = %+v, want controlled by the Tenant", key, owners)
+				}
+			}
+
+			slices.Sort(kinds)
+
+			if !slices.Equal(kinds, tt.wantApplied) {
+				t.Errorf("applied %v, want %v", kinds, tt.wantApplied)
+			}
+
+			if deleted := deletedTenantObjects(controller); !slices.Equal(deleted, tt.wantDeleted) {
+				t.Errorf("deleted %v, want %v", deleted, tt.wantDeleted)
+			}
+
+			if namespace, ok := applied["Namespace/"+tt.wantNamespace]; ok && namespace.GetLabels()[LabelTenantID] != "a-1" {
+				t.Errorf("namespace labels = %v, want %s=a-1", namespace.GetLabels(), LabelTenantID)
+			}
+		})
+	}
+}
+
+func TestSyncDeletedTenant(t *testing.T) {
+	tests := []struct {
+		name          string
+		collectors    []runtime.Object
+		wantErr       bool
+		wantFinalizer bool
+	}{
+		{
+			name:          "tenant whose collectors remain",
+			collectors:    []runtime.Object{testTenantCollector()},
+			wantErr:       true,
+			wantFinalizer: true,
+		},
+		{
+			name: "tenant without collectors",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := testTenant("acme", tenantFinalizer)
+			now := metav1.Now()
+			tenant.DeletionTimestamp = &now
+
+			controller := newTestController(t, append(tt.collectors, tenant)...)
+
+			err := controller.syncTenant(context.Background(), "acme")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncTenant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			stored, err := controller.resourceclientset.ExampleV1alpha().Tenants().Get(context.Background(), "acme", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if slices.Contains(stored.Finalizers, tenantFinalizer) != tt.wantFinalizer {
+				t.Errorf("finalizers = %v, want the tenant finalizer %v", stored.Finalizers, tt.wantFinalizer)
+			}
+
+			if tt.wantErr {
+				ready := meta.FindStatusCondition(stored.Status.Conditions, typeReady)
+				if ready == nil || ready.Reason != reasonTenantCollectorsRemain || !strings.Contains(ready.Message, "acme/logs") {
+					t.Errorf("Ready condition = %+v, want reason %s naming acme/logs", ready, reasonTenantCollectorsRemain)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectorTenant(t *testing.T) {
+	now := metav1.Now()
+
+	tests := []struct {
+		name       string
+		tenant     func(tenant *v1.Tenant)
+		missing    bool
+		notServed  bool
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{name: "ready tenant", wantStatus: metav1.ConditionTrue, wantReason: reasonTenantReady},
+		{name: "tenant CRD that isn't installed", notServed: true, wantStatus: metav1.ConditionFalse, wantReason: reasonTenantNotFound},
+		{name: "tenant that doesn't exist", missing: true, wantStatus: metav1.ConditionFalse, wantReason: reasonTenantNotFound},
+		{
+			name:       "tenant being deleted",
+			tenant:     func(tenant *v1.Tenant) { tenant.DeletionTimestamp = &now },
+			wantStatus: metav1.ConditionFalse,
+			wantReason: reasonTenantNotReady,
+		},
+		{
+			name:       "tenant that failed to be provisioned",
+			tenant:     func(tenant *v1.Tenant) { tenant.Status.Conditions[0].Status = metav1.ConditionFalse },
+			wantStatus: metav1.ConditionFalse,
+			wantReason: reasonTenantNotReady,
+		},
+		{
+			name:       "tenant whose new generation isn't provisioned yet",
+			tenant:     func(tenant *v1.Tenant) { tenant.Generation = 2 },
+			wantStatus: metav1.ConditionFalse,
+			wantReason: reasonTenantNotReady,
+		},
+		{
+			name:       "tenant provisioned in a namespace other than the tenant reference",
+			tenant:     func(tenant *v1.Tenant) { tenant.Status.Namespace = "acme-logs" },
+			wantStatus: metav1.ConditionTrue,
+			wantReason: reasonTenantReady,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{testTenantCollector()}
+			if !tt.missing {
+				tenant := testTenant("acme")
+				if tt.tenant != nil {
+					tt.tenant(tenant)
+				}
+
+				objects = append(objects, tenant)
+			}
+
+			controller := newTestController(t, objects...)
+			controller.tenantsServed = !tt.notServed
+
+			condition := controller.collectorTenant(testTenantCollector())
+			if condition.Type != typeTenantReady || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("collectorTenant() = %+v, want %s with reason %s", condition, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCollectorNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		collector *v1.Collector
+		tenant    *v1.Tenant
+		notServed bool
+		want      string
+	}{
+		{name: "collector without a tenant", collector: testCollector(), want: "acme"},
+		{name: "collector of a provisioned tenant", collector: testTenantCollector(), tenant: testTenant("acme-logs"), want: "acme-logs"},
+		{name: "collector of a tenant that isn't provisioned", collector: testTenantCollector(), tenant: testTenant(""), want: "acme"},
+		{name: "collector of a tenant that doesn't exist", collector: testTenantCollector(), want: "acme"},
+		{name: "collector of a tenant whose CRD isn't installed", collector: testTenantCollector(), tenant: testTenant("acme-logs"), notServed: true, want: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{tt.collector}
+			if tt.tenant != nil {
+				objects = append(objects, tt.tenant)
+			}
+
+			controller := newTestController(t, objects...)
+			controller.tenantsServed = !tt.notServed
+
+			if namespace := controller.collectorNamespace(tt.collector); namespace != tt.want {
+				t.Errorf("collectorNamespace() = %q, want %q", namespace, tt.want)
+			}
+
+			index, err := controller.collectorReleaseIndex(tt.collector)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := tt.want + "/" + releaseName(tt.collector); len(index) != 1 || index[0] != want {
+				t.Errorf("collectorReleaseIndex() = %v, want %s", index, want)
+			}
+		})
+	}
+}
+
+// namespaceKubeClient is a fake client that records the namespaces built to be created by an install.
+type namespaceKubeClient struct {
+	*waitingKubeClient
+	namespaces int
+}
+
+func (c *namespaceKubeClient) Build(reader io.Reader, validate bool) (kube.ResourceList, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Contains(data, []byte("kind: Namespace")) {
+		c.namespaces++
+	}
+
+	return c.waitingKubeClient.Build(bytes.NewReader(data), validate)
+}
+
+func TestInstallCreatesNamespace(t *testing.T) {
+	for _, createNamespace := range []bool{true, false} {
+		kubeClient := &namespaceKubeClient{waitingKubeClient: newWaitingKubeClient()}
+		actionConfig := newTestActionConfiguration(t, kubeClient)
+		reconciler := &CollectorReconciler{Storage: &HelmStorage{Driver: StorageDriverMemory}}
+
+		if _, err := reconciler.installOrUpgrade(actionConfig, "acme", createNamespace, "fluent-bit-main", testChart("1.0.0"), map[string]interface{}{}, nil); err != nil {
+			t.Fatalf("installOrUpgrade() error = %v", err)
+		}
+
+		if created := kubeClient.namespaces > 0; created != createNamespace {
+			t.Errorf("installOrUpgrade() with createNamespace %v created the namespace %v", createNamespace, created)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package operator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// valuesSchemaSuffix is the suffix of the files of the values schema registry, {collector name}.schema.json.
+const valuesSchemaSuffix = ".schema.json"
+
+// ValuesSchemaViolation is a field of the values of a collector that doesn't match a values schema.
+type ValuesSchemaViolation struct {
+	// Pointer is the JSON pointer of the offending field in the values, "" being the values themselves.
+	Pointer string
+	// Description is why the field doesn't match the schema.
+	Description string
+}
+
+func (v ValuesSchemaViolation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "(root)"
+	}
+
+	return pointer + ": " + v.Description
+}
+
+// ValuesSchemaError is returned for values that don't match the values.schema.json of the chart or the
+// registry schema of the collector.
+type ValuesSchemaError struct {
+	// Schema names the schema the values were validated against.
+	Schema     string
+	Violations []ValuesSchemaViolation
+}
+
+func (e *ValuesSchemaError) Error() string {
+	violations := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		violations = append(violations, violation.String())
+	}
+
+	return fmt.Sprintf("values don't match the %s: %s", e.Schema, strings.Join(violations, "; "))
+}
+
+// ValuesSchemas is the registry of the values schemas the operator holds for the collectors, on top of the
+// values.schema.json their charts ship. The schema of a collector is the {name}.schema.json file of the
+// directory, it is read on every validation so that an updated schema is used by the next reconcile.
+type ValuesSchemas struct {
+	// Dir is the directory of the schemas, when empty the registry holds no schema.
+	Dir string
+}
+
+// schema returns the registry schema of the collector, or nil when it has none.
+func (s *ValuesSchemas) schema(collector string) ([]byte, error) {
+	if s == nil || s.Dir == "" {
+		return nil, nil
+	}
+
+	// Collector names are DNS labels, they can't leave the directory
+	schema, err := os.ReadFile(filepath.Join(s.Dir, filepath.Base(collector)+valuesSchemaSuffix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read the values schema of collector %s: %w", collector, err)
+	}
+
+	return schema, nil
+}
+
+// validateValues validates the values the collector release would be rendered with, those of the chart
+// coalesced with the collector values, against the values.schema.json of the chart and the registry schema
+// of the collector. A *ValuesSchemaError lists the offending fields. The schemas of the dependencies of the
+// chart are left to Helm, which only knows which of them are enabled once it processes them.
+func (s *ValuesSchemas) validateValues(collector string, collectorChart *chart.Chart, vals map[string]interface{}) error {
+	coalesced, err := chartutil.CoalesceValues(collectorChart, vals)
+	if err != nil {
+		return fmt.Errorf("could not coalesce the values with those of the chart: %w", err)
+	}
+
+	if collectorChart.Schema != nil {
+		if err = validateAgainstSchema(fmt.Sprintf("values.schema.json of chart %s", collectorChart.Name()), collectorChart.Schema, coalesced); err != nil {
+			return err
+		}
+	}
+
+	schema, err := s.schema(collector)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	return validateAgainstSchema(fmt.Sprintf("values schema of collector %s", collector), schema, coalesced)
+}
+
+// validateAgainstSchema validates the values against the JSON schema.
+func validateAgainstSchema(name string, schema []byte, vals map[string]interface{}) error {
+	document, err := json.Marshal(vals)
+	if err != nil {
+		return fmt.Errorf("could not encode the values: %w", err)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewBytesLoader(document))
+	if err != nil {
+		return fmt.Errorf("could not validate the values against the %s: %w", name, err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	schemaErr := &ValuesSchemaError{Schema: name}
+
+	for _, resultErr := range result.Errors() {
+		violationPointer := contextJSONPointer(resultErr.Context())
+
+		// A missing required field or an unknown one is pointed at, rather than the object holding it
+		property, ok := resultErr.Details()["property"].(string)
+		if ok && (resultErr.Type() == "required" || resultErr.Type() == "additional_property_not_allowed") {
+			violationPointer += "/" + escapeJSONPointer(property)
+		}
+
+		schemaErr.Violations = append(schemaErr.Violations, ValuesSchemaViolation{Pointer: violationPointer, Description: resultErr.Description()})
+	}
+
+	return schemaErr
+}
+
+// contextJSONPointer returns the JSON pointer of the field of a validation error. The context is rendered
+// with a NUL delimiter, rather than the slash keys may hold, so that they are escaped.
+func contextJSONPointer(context *gojsonschema.JsonContext) string {
+	if context == nil {
+		return ""
+	}
+
+	// The first token is the (root) of the document
+	tokens := strings.Split(context.String("\x00"), "\x00")[1:]
+
+	var pointer strings.Builder
+
+	for _, token := range tokens {
+		pointer.WriteString("/" + escapeJSONPointer(token))
+	}
+
+	return pointer.String()
+}
+
+// escapeJSONPointer escapes a reference token of a JSON pointer, RFC 6901.
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,120 @@
+package operator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestEscapeJSONPointer(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "output", want: "output"},
+		{token: "", want: ""},
+		{token: "a/b", want: "a~1b"},
+		{token: "m~n", want: "m~0n"},
+		{token: "~1", want: "~01"},
+		{token: "example.com/tier", want: "example.com~1tier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if got := escapeJSONPointer(tt.token); got != tt.want {
+				t.Errorf("escapeJSONPointer(%q) = %q, want %q", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextJSONPointer(t *testing.T) {
+	context := func(tokens ...string) *gojsonschema.JsonContext {
+		context := gojsonschema.NewJsonContext("(root)", nil)
+		for _, token := range tokens {
+			context = gojsonschema.NewJsonContext(token, context)
+		}
+
+		return context
+	}
+
+	tests := []struct {
+		name    string
+		context *gojsonschema.JsonContext
+		want    string
+	}{
+		{name: "no context", context: nil, want: ""},
+		{name: "root", context: context(), want: ""},
+		{name: "nested field", context: context("output", "host"), want: "/output/host"},
+		{name: "list item", context: context("inputs", "0"), want: "/inputs/0"},
+		{name: "key with a slash", context: context("podLabels", "example.com/tier"), want: "/podLabels/example.com~1tier"},
+		{name: "key with a tilde", context: context("m~n"), want: "/m~0n"},
+		{name: "key with a dot", context: context("a.b"), want: "/a.b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contextJSONPointer(tt.context); got != tt.want {
+				t.Errorf("contextJSONPointer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"replicas": {"type": "integer"},
+			"podLabels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"output": {
+				"type": "object",
+				"required": ["host"],
+				"additionalProperties": false,
+				"properties": {"host": {"type": "string"}, "port": {"type": "integer"}}
+			}
+		}
+	}`)
+
+	tests := []struct {
+		name string
+		vals map[string]interface{}
+		want []string
+	}{
+		{name: "valid values", vals: map[string]interface{}{"replicas": 2, "output": map[string]interface{}{"host": "logs.example.com"}}},
+		{name: "wrong type", vals: map[string]interface{}{"replicas": "two"}, want: []string{"/replicas"}},
+		{name: "missing required field", vals: map[string]interface{}{"output": map[string]interface{}{"port": 24224}}, want: []string{"/output/host"}},
+		{name: "unknown field", vals: map[string]interface{}{"output": map[string]interface{}{"host": "logs.example.com", "tls": true}}, want: []string{"/output/tls"}},
+		{name: "escaped key", vals: map[string]interface{}{"podLabels": map[string]interface{}{"example.com/tier": 1}}, want: []string{"/podLabels/example.com~1tier"}},
+		{name: "values that aren't an object", vals: nil, want: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAgainstSchema("values schema of collector fluent-bit", schema, tt.vals)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("validateAgainstSchema() error = %v", err)
+				}
+
+				return
+			}
+
+			var schemaErr *ValuesSchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("validateAgainstSchema() error = %v, want a *ValuesSchemaError", err)
+			}
+
+			var pointers []string
+			for _, violation := range schemaErr.Violations {
+				pointers = append(pointers, violation.Pointer)
+			}
+
+			if !reflect.DeepEqual(pointers, tt.want) {
+				t.Errorf("violations at %v, want %v", pointers, tt.want)
+			}
+		})
+	}
+}
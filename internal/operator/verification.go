@@ -0,0 +1,260 @@
+package operator
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/provenance"
+	"oras.land/oras-go/pkg/registry/remote/auth"
+
+	"kube8-operator/internal/instrumentation"
+)
+
+const (
+	// provenanceExtension is the extension of Helm provenance files, appended to the chart archive name.
+	provenanceExtension = ".prov"
+	// cosignSignatureAnnotation is the layer annotation cosign stores the base64 encoded signature of the payload in.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// maxSignatureBytes bounds the size of the signature manifests and payloads that are downloaded.
+	maxSignatureBytes = 1 << 20
+)
+
+// ChartVerification configures how the charts of a source are verified before they are installed.
+type ChartVerification struct {
+	// Keyring is the path of a GPG keyring that the Helm provenance (.prov) file of every chart must be signed with.
+	Keyring string `yaml:"keyring"`
+	// CosignPublicKey is the path of a PEM encoded public key that the cosign signature of every chart must
+	// verify with. Only OCI sources support cosign signatures.
+	CosignPublicKey string `yaml:"cosignPublicKey"`
+}
+
+// ChartVerificationError is returned when a chart fails provenance or signature verification.
+type ChartVerificationError struct {
+	Chart string
+	Err   error
+}
+
+func (e *ChartVerificationError) Error() string {
+	return fmt.Sprintf("verification of chart %s failed: %s", e.Chart, e.Err)
+}
+
+func (e *ChartVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verify checks the chart archive against the verification configured for the source.
+func (c *configuredChartSource) verify(ctx context.Context, name string, version string, archive *ChartArchive) error {
+	if c.verification == nil {
+		return nil
+	}
+
+	if c.verification.Keyring != "" {
+		if err := c.verifyProvenance(ctx, name, version, archive); err != nil {
+			return &ChartVerificationError{Chart: name + "-" + version, Err: err}
+		}
+	}
+
+	if c.verification.CosignPublicKey != "" {
+		ociSource, ok := c.ChartSource.(*OCIChartSource)
+		if !ok {
+			return &ChartVerificationError{Chart: name + "-" + version, Err: errors.New("cosign signatures are only supported for OCI chart sources")}
+		}
+
+		if err := ociSource.verifyCosign(ctx, name, archive, c.verification.CosignPublicKey); err != nil {
+			return &ChartVerificationError{Chart: name + "-" + version, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// verifyProvenance verifies the provenance file of the chart against the keyring, which checks that it is signed
+// by a key in the keyring and that it records the digest of the archive.
+func (c *configuredChartSource) verifyProvenance(ctx context.Context, name string, version string, archive *ChartArchive) error {
+	prov, err := c.Provenance(ctx, name, version)
+	if err != nil {
+		return err
+	}
+
+	signatory, err := provenance.NewFromKeyring(c.verification.Keyring, "")
+	if err != nil {
+		return fmt.Errorf("could not load keyring: %w", err)
+	}
+
+	// The provenance library verifies files, and looks the digest up by the name of the archive
+	dir, err := os.MkdirTemp("", "chart-verification-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	chartPath := filepath.Join(dir, filepath.Base(name+"-"+version+chartArchiveExtension))
+
+	if err = os.WriteFile(chartPath, archive.Data, 0o600); err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(chartPath+provenanceExtension, prov, 0o600); err != nil {
+		return err
+	}
+
+	if _, err = signatory.Verify(chartPath, chartPath+provenanceExtension); err != nil {
+		return fmt.Errorf("invalid provenance: %w", err)
+	}
+
+	return nil
+}
+
+// cosignManifest is the part of an OCI image manifest holding the cosign signature layers.
+type cosignManifest struct {
+	Layers []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// cosignPayload is the simple signing payload cosign signs, which names the digest of the signed manifest.
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"` // nolint: tagliatelle
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifyCosign checks that the chart manifest has a cosign signature made with the public key.
+// Signatures are looked up with the cosign tag convention, {repository}:sha256-{digest}.sig, with the registry
+// credentials the chart is pulled with.
+func (o *OCIChartSource) verifyCosign(ctx context.Context, name string, archive *ChartArchive, publicKeyPath string) error {
+	publicKey, err := loadPublicKey(publicKeyPath)
+	if err != nil {
+		return err
+	}
+
+	if archive.ManifestDigest == "" {
+		return errors.New("the chart manifest digest is unknown")
+	}
+
+	host, repository, _ := strings.Cut(o.reference(name), "/")
+	client := &auth.Client{Client: instrumentation.InstrumentHTTPClient(&http.Client{}), Cache: auth.NewCache(), Credential: o.credential}
+	signatureTag := strings.Replace(archive.ManifestDigest, ":", "-", 1) + ".sig"
+
+	data, err := registryGet(ctx, client, "https://"+host+"/v2/"+repository+"/manifests/"+signatureTag, "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json")
+	if err != nil {
+		return fmt.Errorf("could not get cosign signature: %w", err)
+	}
+
+	manifest := cosignManifest{}
+
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid cosign signature manifest: %w", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[cosignSignatureAnnotation])
+		if err != nil || len(signature) == 0 {
+			continue
+		}
+
+		payload, err := registryGet(ctx, client, "https://"+host+"/v2/"+repository+"/blobs/"+layer.Digest, "")
+		if err != nil {
+			return fmt.Errorf("could not get cosign signature payload: %w", err)
+		}
+
+		sum := sha256.Sum256(payload)
+		if "sha256:"+hex.EncodeToString(sum[:]) != layer.Digest {
+			continue
+		}
+
+		if verifySignature(publicKey, payload, signature) != nil {
+			continue
+		}
+
+		signed := cosignPayload{}
+		if json.Unmarshal(payload, &signed) == nil && signed.Critical.Image.DockerManifestDigest == archive.ManifestDigest {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no cosign signature of %s verifies with the public key", archive.ManifestDigest)
+}
+
+// registryGet gets a manifest or blob from a registry, authenticating through the registry's challenge.
+func registryGet(ctx context.Context, client *auth.Client, url string, accept string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, response.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxSignatureBytes))
+}
+
+// loadPublicKey reads a PEM encoded PKIX public key.
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key in %s", path)
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// verifySignature verifies the signature of the payload the way cosign signs it: ECDSA and RSA keys
+// sign the SHA-256 digest of the payload, ed25519 keys the payload itself.
+func verifySignature(publicKey crypto.PublicKey, payload []byte, signature []byte) error {
+	digest := sha256.Sum256(payload)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid ECDSA signature")
+		}
+
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return errors.New("invalid ed25519 signature")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
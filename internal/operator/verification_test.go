@@ -0,0 +1,253 @@
+package operator
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"critical":{"image":{"docker-manifest-digest":"sha256:0"}}}`)
+	tampered := []byte(`{"critical":{"image":{"docker-manifest-digest":"sha256:1"}}}`)
+	digest := sha256.Sum256(payload)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaSignature, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519PublicKey, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Signature := ed25519.Sign(ed25519Key, payload)
+
+	tests := []struct {
+		name      string
+		publicKey crypto.PublicKey
+		payload   []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{name: "ECDSA", publicKey: &ecdsaKey.PublicKey, payload: payload, signature: ecdsaSignature},
+		{name: "ECDSA tampered payload", publicKey: &ecdsaKey.PublicKey, payload: tampered, signature: ecdsaSignature, wantErr: true},
+		{name: "RSA", publicKey: &rsaKey.PublicKey, payload: payload, signature: rsaSignature},
+		{name: "RSA tampered payload", publicKey: &rsaKey.PublicKey, payload: tampered, signature: rsaSignature, wantErr: true},
+		{name: "ed25519", publicKey: ed25519PublicKey, payload: payload, signature: ed25519Signature},
+		{name: "ed25519 tampered payload", publicKey: ed25519PublicKey, payload: tampered, signature: ed25519Signature, wantErr: true},
+		{name: "signature of another key type", publicKey: &rsaKey.PublicKey, payload: payload, signature: ecdsaSignature, wantErr: true},
+		{name: "unsupported key type", publicKey: "not a key", payload: payload, signature: ecdsaSignature, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifySignature(tt.publicKey, tt.payload, tt.signature); (err != nil) != tt.wantErr {
+				t.Errorf("verifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		contents []byte
+		wantErr  bool
+	}{
+		{name: "PEM encoded PKIX key", contents: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+		{name: "not PEM encoded", contents: der, wantErr: true},
+		{name: "not a public key", contents: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")}), wantErr: true},
+		{name: "missing file", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cosign.pub")
+			if tt.contents != nil {
+				if err := os.WriteFile(path, tt.contents, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			publicKey, err := loadPublicKey(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPublicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && !key.PublicKey.Equal(publicKey) {
+				t.Errorf("loadPublicKey() returned another key")
+			}
+		})
+	}
+}
+
+func TestChartSourceVerify(t *testing.T) {
+	tests := []struct {
+		name         string
+		verification *ChartVerification
+		wantErr      bool
+	}{
+		{name: "no verification configured"},
+		{name: "empty verification", verification: &ChartVerification{}},
+		{name: "cosign on a source that isn't OCI", verification: &ChartVerification{CosignPublicKey: "cosign.pub"}, wantErr: true},
+		{name: "provenance can't be fetched", verification: &ChartVerification{Keyring: "pubring.gpg"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &configuredChartSource{ChartSource: &fakeChartSource{}, name: "production", verification: tt.verification}
+
+			err := source.verify(context.Background(), "collector", "1.4.3", &ChartArchive{Data: []byte("chart")})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var verificationErr *ChartVerificationError
+			if err != nil && (!errors.As(err, &verificationErr) || verificationErr.Chart != "collector-1.4.3") {
+				t.Errorf("verify() error = %v, want a verification error of collector-1.4.3", err)
+			}
+		})
+	}
+}
+
+func TestVerifyCosignWithRegistryCredentials(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKeyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	if err = os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifestDigest := "sha256:" + strings.Repeat("ab", sha256.Size)
+	payload := []byte(`{"critical":{"image":{"docker-manifest-digest":"` + manifestDigest + `"}}}`)
+	payloadSum := sha256.Sum256(payload)
+	payloadDigest := "sha256:" + hex.EncodeToString(payloadSum[:])
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, payloadSum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signatureManifest, err := json.Marshal(map[string]interface{}{
+		"layers": []map[string]interface{}{{
+			"digest":      payloadDigest,
+			"annotations": map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The registry only serves the signature to the user with its credentials
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "robot" || password != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/charts/fluent-bit/manifests/" + strings.Replace(manifestDigest, ":", "-", 1) + ".sig":
+			_, _ = w.Write(signatureManifest)
+		case "/v2/charts/fluent-bit/blobs/" + payloadDigest:
+			_, _ = w.Write(payload)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	// The Docker config of the user running the tests isn't used as a fallback
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		name        string
+		credentials string
+		wantErr     bool
+	}{
+		{name: "credentials of the registry", credentials: "robot:s3cret"},
+		{name: "wrong credentials", credentials: "robot:guess", wantErr: true},
+		{name: "no credentials", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentialsFile := filepath.Join(t.TempDir(), "config.json")
+
+			config := `{"auths":{}}`
+			if tt.credentials != "" {
+				config = `{"auths":{"` + host + `":{"auth":"` + base64.StdEncoding.EncodeToString([]byte(tt.credentials)) + `"}}}`
+			}
+
+			if err := os.WriteFile(credentialsFile, []byte(config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			source := &OCIChartSource{Repository: "oci://" + host + "/charts", CredentialsFile: credentialsFile}
+
+			err := source.verifyCosign(context.Background(), "fluent-bit", &ChartArchive{ManifestDigest: manifestDigest}, publicKeyPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyCosign() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
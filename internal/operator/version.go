@@ -0,0 +1,158 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v52/github"
+	"golang.org/x/oauth2"
+)
+
+// latestVersion is the Spec.Collector.Version that installs the newest stable chart, it's also used when no version is set.
+const latestVersion = "latest"
+
+// VersionCache is an in-memory cache of the versions published by the chart sources, keyed by source and chart name,
+// so that resolving a constraint or latest doesn't list the source on every reconcile.
+type VersionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]versionCacheEntry
+}
+
+// versionCacheEntry is a cached list of versions.
+type versionCacheEntry struct {
+	versions []string
+	added    time.Time
+}
+
+// NewVersionCache creates a version cache whose entries are listed again after ttl. A ttl of zero disables the cache.
+func NewVersionCache(ttl time.Duration) *VersionCache {
+	return &VersionCache{ttl: ttl, entries: map[string]versionCacheEntry{}}
+}
+
+// versionCacheKey returns the cache key of the versions of a chart in a source, or of its latest GitHub release.
+func versionCacheKey(source string, name string, latestRelease bool) string {
+	if latestRelease {
+		return source + "/" + name + "@" + latestVersion
+	}
+
+	return source + "/" + name
+}
+
+// get returns the cached versions of the key, or lists them and caches them if they are missing or expired.
+// Listing errors aren't cached.
+func (c *VersionCache) get(key string, list func() ([]string, error)) ([]string, error) {
+	if c == nil || c.ttl <= 0 {
+		return list()
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.added) <= c.ttl {
+		return entry.versions, nil
+	}
+
+	versions, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = versionCacheEntry{versions: versions, added: time.Now()}
+	c.mu.Unlock()
+
+	return versions, nil
+}
+
+// resolveChartVersion resolves the requested collector version against the chart source. The requested version is
+// either an exact version (1.4.2), a semver constraint (~1.4, >=2.0 <3) or latest. The published versions and the
+// latest GitHub release are read through the version cache.
+func resolveChartVersion(ctx context.Context, cache *VersionCache, source *configuredChartSource, name string, requested string) (string, error) {
+	requested = strings.TrimSpace(requested)
+
+	// An exact version is used as is, there is no need to list what has been published
+	if _, err := semver.StrictNewVersion(requested); err == nil {
+		return requested, nil
+	}
+
+	if (requested == "" || requested == latestVersion) && source.githubReleases != nil {
+		latest, err := cache.get(versionCacheKey(source.name, name, true), func() ([]string, error) {
+			tag, err := latestGitHubRelease(ctx, source.githubReleases, source.secrets, name)
+
+			return []string{tag}, err
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return latest[0], nil
+	}
+
+	// latest is the newest version without a prerelease suffix, which is what a constraint matches by default
+	if requested == "" || requested == latestVersion {
+		requested = "*"
+	}
+
+	constraint, err := semver.NewConstraint(requested)
+	if err != nil {
+		return "", fmt.Errorf("invalid collector version %q: %w", requested, err)
+	}
+
+	published, err := cache.get(versionCacheKey(source.name, name, false), func() ([]string, error) {
+		return source.Versions(ctx, name)
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not list versions of chart %s in source %s: %w", name, source.name, err)
+	}
+
+	var resolved *semver.Version
+
+	for _, version := range published {
+		parsed, err := semver.NewVersion(version)
+		// Anything that isn't a semver version can't be matched against a constraint
+		if err != nil {
+			continue
+		}
+
+		if constraint.Check(parsed) && (resolved == nil || parsed.GreaterThan(resolved)) {
+			resolved = parsed
+		}
+	}
+
+	if resolved == nil {
+		return "", fmt.Errorf("no version of chart %s in source %s matches %q", name, source.name, requested)
+	}
+
+	return resolved.Original(), nil
+}
+
+// latestGitHubRelease returns the tag of the latest GitHub release of the owner's repository.
+// The token is read for every request, so a rotated token is used right away.
+func latestGitHubRelease(ctx context.Context, releases *GitHubReleases, secrets *SecretStore, repository string) (string, error) {
+	token, err := releases.Token.value(secrets)
+	if err != nil {
+		return "", fmt.Errorf("could not read GitHub token: %w", err)
+	}
+
+	// Without a token the requests are anonymous
+	var httpClient *http.Client
+	if token != "" {
+		httpClient = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+
+	gitClient := github.NewClient(httpClient)
+
+	release, _, err := gitClient.Repositories.GetLatestRelease(ctx, releases.Owner, repository)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest release: %w", err)
+	}
+
+	return release.GetTagName(), nil
+}
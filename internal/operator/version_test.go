@@ -0,0 +1,144 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeChartSource is a chart source publishing a fixed list of versions, counting how often they are listed.
+type fakeChartSource struct {
+	versions []string
+	err      error
+	listed   int
+}
+
+func (s *fakeChartSource) Fetch(context.Context, string, string) (*ChartArchive, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *fakeChartSource) Versions(context.Context, string) ([]string, error) {
+	s.listed++
+
+	return s.versions, s.err
+}
+
+func (s *fakeChartSource) Provenance(context.Context, string, string) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestResolveChartVersion(t *testing.T) {
+	published := []string{"1.3.0", "1.4.0", "1.4.3", "v1.5.0", "1.6.0-rc.1", "nightly"}
+
+	tests := []struct {
+		name      string
+		requested string
+		versions  []string
+		listErr   error
+		want      string
+		wantErr   bool
+		wantLists int
+	}{
+		{name: "exact version isn't listed", requested: "1.4.2", versions: published, want: "1.4.2"},
+		{name: "exact version is trimmed", requested: " 1.4.2 ", versions: published, want: "1.4.2"},
+		{name: "tilde constraint", requested: "~1.4", versions: published, want: "1.4.3", wantLists: 1},
+		{name: "range constraint", requested: ">=1.3 <1.4", versions: published, want: "1.3.0", wantLists: 1},
+		{name: "latest skips prereleases", requested: "latest", versions: published, want: "v1.5.0", wantLists: 1},
+		{name: "empty is latest", requested: "", versions: published, want: "v1.5.0", wantLists: 1},
+		{name: "prerelease constraint", requested: ">=1.6.0-0", versions: published, want: "1.6.0-rc.1", wantLists: 1},
+		{name: "no matching version", requested: ">=2.0 <3", versions: published, wantErr: true, wantLists: 1},
+		{name: "nothing published", requested: "latest", wantErr: true, wantLists: 1},
+		{name: "invalid constraint", requested: "one point four", versions: published, wantErr: true},
+		{name: "listing fails", requested: "~1.4", listErr: errors.New("access denied"), wantErr: true, wantLists: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeChartSource{versions: tt.versions, err: tt.listErr}
+			source := &configuredChartSource{ChartSource: fake, name: "production"}
+
+			got, err := resolveChartVersion(context.Background(), nil, source, "collector", tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveChartVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("resolveChartVersion() = %q, want %q", got, tt.want)
+			}
+
+			if fake.listed != tt.wantLists {
+				t.Errorf("versions listed %d times, want %d", fake.listed, tt.wantLists)
+			}
+		})
+	}
+}
+
+func TestVersionCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		cache     *VersionCache
+		listErr   error
+		wantLists int
+	}{
+		{name: "versions are listed once within the ttl", cache: NewVersionCache(time.Minute), wantLists: 1},
+		{name: "nil cache lists every time", cache: nil, wantLists: 2},
+		{name: "zero ttl lists every time", cache: NewVersionCache(0), wantLists: 2},
+		{name: "errors aren't cached", cache: NewVersionCache(time.Minute), listErr: errors.New("access denied"), wantLists: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := 0
+			list := func() ([]string, error) {
+				lists++
+
+				return []string{"1.4.3"}, tt.listErr
+			}
+
+			key := versionCacheKey("production", "collector", false)
+
+			for range 2 {
+				versions, err := tt.cache.get(key, list)
+				if (err != nil) != (tt.listErr != nil) {
+					t.Fatalf("get() error = %v, want %v", err, tt.listErr)
+				}
+
+				if err == nil && (len(versions) != 1 || versions[0] != "1.4.3") {
+					t.Errorf("get() = %v, want [1.4.3]", versions)
+				}
+			}
+
+			if lists != tt.wantLists {
+				t.Errorf("versions listed %d times, want %d", lists, tt.wantLists)
+			}
+		})
+	}
+}
+
+func TestVersionCacheExpiry(t *testing.T) {
+	cache := NewVersionCache(time.Minute)
+	key := versionCacheKey("production", "collector", false)
+	lists := 0
+	list := func() ([]string, error) {
+		lists++
+
+		return []string{"1.4.3"}, nil
+	}
+
+	if _, err := cache.get(key, list); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := cache.entries[key]
+	entry.added = time.Now().Add(-2 * time.Minute)
+	cache.entries[key] = entry
+
+	if _, err := cache.get(key, list); err != nil {
+		t.Fatal(err)
+	}
+
+	if lists != 2 {
+		t.Errorf("versions listed %d times after expiry, want 2", lists)
+	}
+}
@@ -0,0 +1,352 @@
+package operator
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chartutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+const (
+	// ValidateCollectorPath is the path the validating webhook of the Collectors is served on.
+	ValidateCollectorPath = "/validate-collector"
+	// maxAdmissionReviewBytes bounds the size of the admission reviews that are read.
+	maxAdmissionReviewBytes = 3 << 20
+	// maxReleaseNameLength is the length limit Helm puts on release names.
+	maxReleaseNameLength = 53
+	// webhookShutdownTimeout is how long the webhook server waits for running requests when it is stopped.
+	webhookShutdownTimeout = 5 * time.Second
+)
+
+// CollectorValidator validates Collectors against what the reconciler needs to install them.
+type CollectorValidator struct {
+	clusters     map[string]bool
+	chartSources map[string]bool
+}
+
+// NewCollectorValidator creates a validator that accepts the clusters and chart sources of the configuration.
+func NewCollectorValidator(config ChartSourcesConfig) *CollectorValidator {
+	validator := &CollectorValidator{clusters: map[string]bool{}, chartSources: map[string]bool{}}
+
+	for cluster := range config.Clusters {
+		validator.clusters[cluster] = true
+	}
+
+	for name := range config.Sources {
+		validator.chartSources[name] = true
+	}
+
+	return validator
+}
+
+// Validate returns everything that is wrong with the Collector.
+func (v *CollectorValidator) Validate(resource *v1alpha.Collector) field.ErrorList {
+	var errs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	collectorPath := specPath.Child("collector")
+	tenantPath := specPath.Child("tenant")
+
+	if resource.Spec.Collector.Name == "" {
+		errs = append(errs, field.Required(collectorPath.Child("name"), "the collector name selects the chart to install"))
+	}
+
+	if _, err := getValues(resource.Spec.Collector.Configuration); err != nil {
+		errs = append(errs, field.Invalid(collectorPath.Child("configuration"), "<redacted>", "must be base64 encoded YAML values: "+err.Error()))
+	}
+
+	for i, source := range resource.Spec.Collector.ConfigurationFrom {
+		sourcePath := collectorPath.Child("configurationFrom").Index(i)
+
+		switch {
+		case (source.ConfigMapKeyRef == nil) == (source.SecretKeyRef == nil):
+			errs = append(errs, field.Invalid(sourcePath, "", "exactly one of configMapKeyRef and secretKeyRef must be set"))
+		case source.ConfigMapKeyRef != nil:
+			errs = append(errs, validateKeyRef(sourcePath.Child("configMapKeyRef"), source.ConfigMapKeyRef.Name, source.ConfigMapKeyRef.Key)...)
+		default:
+			errs = append(errs, validateKeyRef(sourcePath.Child("secretKeyRef"), source.SecretKeyRef.Name, source.SecretKeyRef.Key)...)
+		}
+	}
+
+	if version := strings.TrimSpace(resource.Spec.Collector.Version); version != "" && version != latestVersion {
+		if _, err := semver.NewConstraint(version); err != nil {
+			errs = append(errs, field.Invalid(collectorPath.Child("version"), resource.Spec.Collector.Version, "must be an exact version, a semver constraint or latest"))
+		}
+	}
+
+	if resource.Spec.Tenant.Reference == "" {
+		errs = append(errs, field.Required(tenantPath.Child("reference"), "the tenant reference is the namespace the collector is deployed to"))
+	} else {
+		for _, message := range validation.IsDNS1123Label(tenantNamespace(resource)) {
+			errs = append(errs, field.Invalid(tenantPath.Child("reference"), resource.Spec.Tenant.Reference, "must be a valid namespace name: "+message))
+		}
+	}
+
+	if resource.Spec.Tenant.Instance == "" {
+		errs = append(errs, field.Required(tenantPath.Child("instance"), "the tenant instance is part of the Helm release name"))
+	}
+
+	if resource.Spec.Collector.Name != "" && resource.Spec.Tenant.Instance != "" {
+		name := releaseName(resource)
+		if len(name) > maxReleaseNameLength {
+			errs = append(errs, field.Invalid(tenantPath.Child("instance"), resource.Spec.Tenant.Instance,
+				fmt.Sprintf("the Helm release name %q is %d characters long, Helm allows at most %d", name, len(name), maxReleaseNameLength)))
+		} else if err := chartutil.ValidateReleaseName(name); err != nil {
+			errs = append(errs, field.Invalid(tenantPath.Child("instance"), resource.Spec.Tenant.Instance, fmt.Sprintf("the Helm release name %q is invalid: %s", name, err)))
+		}
+	}
+
+	// The cluster only selects the chart source of a collector that doesn't name one
+	if resource.Spec.ChartSource == "" && !v.clusters[resource.Spec.Cluster] {
+		errs = append(errs, field.NotSupported(specPath.Child("cluster"), resource.Spec.Cluster, slices.Sorted(maps.Keys(v.clusters))))
+	}
+
+	if resource.Spec.ChartSource != "" && !v.chartSources[resource.Spec.ChartSource] {
+		errs = append(errs, field.NotSupported(specPath.Child("chartSource"), resource.Spec.ChartSource, slices.Sorted(maps.Keys(v.chartSources))))
+	}
+
+	switch resource.Spec.DriftPolicy {
+	case "", v1alpha.DriftPolicyIgnore, v1alpha.DriftPolicyReport, v1alpha.DriftPolicyCorrect:
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("driftPolicy"), resource.Spec.DriftPolicy,
+			[]string{string(v1alpha.DriftPolicyIgnore), string(v1alpha.DriftPolicyReport), string(v1alpha.DriftPolicyCorrect)}))
+	}
+
+	return errs
+}
+
+// validateKeyRef checks the name and key of a ConfigMap or Secret key reference.
+func validateKeyRef(path *field.Path, name string, key string) field.ErrorList {
+	var errs field.ErrorList
+
+	if name == "" {
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	}
+
+	if key == "" {
+		errs = append(errs, field.Required(path.Child("key"), ""))
+	}
+
+	return errs
+}
+
+// WebhookOptions configures the admission webhook server.
+type WebhookOptions struct {
+	// Port is the port the HTTPS server listens on.
+	Port int
+	// CertDir is the directory holding the tls.crt and tls.key serving certificate, which is reloaded when it changes.
+	CertDir string
+}
+
+// WebhookServer serves the admission webhooks of the Collectors.
+type WebhookServer struct {
+	options     WebhookOptions
+	validator   *CollectorValidator
+	defaulter   *CollectorDefaulter
+	certificate *certificateReloader
+}
+
+// NewWebhookServer creates the admission webhook server.
+func NewWebhookServer(options WebhookOptions, validator *CollectorValidator, defaulter *CollectorDefaulter) *WebhookServer {
+	return &WebhookServer{
+		options:     options,
+		validator:   validator,
+		defaulter:   defaulter,
+		certificate: &certificateReloader{certFile: filepath.Join(options.CertDir, "tls.crt"), keyFile: filepath.Join(options.CertDir, "tls.key")},
+	}
+}
+
+// Start serves the webhooks until the context is cancelled. Every replica serves them, whether it leads or not.
+func (s *WebhookServer) Start(ctx context.Context) error {
+	// Fail right away on a missing certificate rather than on the first request
+	if _, err := s.certificate.GetCertificate(nil); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidateCollectorPath, s.serveValidate)
+	mux.HandleFunc(MutateCollectorPath, s.serveMutate)
+	mux.HandleFunc(ConvertCollectorPath, s.serveConvert)
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(s.options.Port)),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: s.certificate.GetCertificate},
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Webhook server shutdown: %v", err)
+		}
+	}()
+
+	klog.Infof("Serving webhooks on :%d", s.options.Port)
+
+	if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server: %w", err)
+	}
+
+	return nil
+}
+
+// serveValidate admits the Collectors that pass validation. Deletes, Collectors being deleted and updates that
+// leave the spec alone are always admitted, so that finalizers can be removed from Collectors created before the webhook.
+func (s *WebhookServer) serveValidate(w http.ResponseWriter, r *http.Request) {
+	review, err := readAdmissionReview(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	response := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+
+	if review.Request.Operation == admissionv1.Delete {
+		writeAdmissionReview(w, review, response)
+
+		return
+	}
+
+	collector := &v1alpha.Collector{}
+	if err = json.Unmarshal(review.Request.Object.Raw, collector); err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest, Message: "could not decode Collector: " + err.Error()}
+
+		writeAdmissionReview(w, review, response)
+
+		return
+	}
+
+	if collector.DeletionTimestamp != nil || (review.Request.Operation == admissionv1.Update && s.specUnchanged(review.Request, collector)) {
+		writeAdmissionReview(w, review, response)
+
+		return
+	}
+
+	if errs := s.validator.Validate(collector); len(errs) > 0 {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: fmt.Sprintf("Collector %q is invalid: %s", collector.Name, errs.ToAggregate()),
+		}
+	}
+
+	writeAdmissionReview(w, review, response)
+}
+
+// specUnchanged reports whether an update leaves the spec of the Collector as it was.
+func (s *WebhookServer) specUnchanged(request *admissionv1.AdmissionRequest, collector *v1alpha.Collector) bool {
+	old := &v1alpha.Collector{}
+	if err := json.Unmarshal(request.OldObject.Raw, old); err != nil {
+		return false
+	}
+
+	return equality.Semantic.DeepEqual(old.Spec, collector.Spec)
+}
+
+// readAdmissionReview decodes the admission review of the request.
+func readAdmissionReview(r *http.Request) (*admissionv1.AdmissionReview, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("unsupported method %s", r.Method)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionReviewBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err = json.Unmarshal(body, review); err != nil {
+		return nil, fmt.Errorf("could not decode admission review: %w", err)
+	}
+
+	if review.Request == nil {
+		return nil, errors.New("admission review without a request")
+	}
+
+	return review, nil
+}
+
+// writeAdmissionReview answers the admission review with the response.
+func writeAdmissionReview(w http.ResponseWriter, review *admissionv1.AdmissionReview, response *admissionv1.AdmissionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(&admissionv1.AdmissionReview{TypeMeta: review.TypeMeta, Response: response}); err != nil {
+		klog.Errorf("Could not write admission review: %v", err)
+	}
+}
+
+// certificateReloader serves the certificate in the files, loading it again when they change so that a rotated
+// certificate is picked up without a restart.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	modified    time.Time
+}
+
+// GetCertificate returns the current certificate, it is used as tls.Config.GetCertificate.
+func (c *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	modified := time.Time{}
+
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read webhook certificate: %w", err)
+		}
+
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+
+	if c.certificate != nil && modified.Equal(c.modified) {
+		return c.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load webhook certificate: %w", err)
+	}
+
+	c.certificate, c.modified = &certificate, modified
+
+	return c.certificate, nil
+}
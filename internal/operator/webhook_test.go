@@ -0,0 +1,422 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	v1 "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// newTestValidator returns a validator accepting the production cluster and the oci chart source.
+func newTestValidator() *CollectorValidator {
+	return NewCollectorValidator(ChartSourcesConfig{
+		Sources:  map[string]ChartSourceConfig{"oci": {Type: "oci"}},
+		Clusters: map[string]string{"production": "oci"},
+	})
+}
+
+// validCollector returns the test collector for the production cluster, which passes validation.
+func validCollector() *v1.Collector {
+	collector := testCollector()
+	collector.Spec.Cluster = "production"
+
+	return collector
+}
+
+func TestCollectorValidatorValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(collector *v1.Collector)
+		// wantFields are the paths of the fields that are invalid
+		wantFields []string
+	}{
+		{name: "valid collector", edit: func(*v1.Collector) {}},
+		{
+			name: "base64 encoded YAML configuration",
+			edit: func(c *v1.Collector) {
+				c.Spec.Collector.Configuration = base64.StdEncoding.EncodeToString([]byte("level: debug\n"))
+			},
+		},
+		{
+			name:       "configuration that isn't base64",
+			edit:       func(c *v1.Collector) { c.Spec.Collector.Configuration = "level: debug" },
+			wantFields: []string{"spec.collector.configuration"},
+		},
+		{
+			name: "configuration that isn't YAML values",
+			edit: func(c *v1.Collector) {
+				c.Spec.Collector.Configuration = base64.StdEncoding.EncodeToString([]byte("- debug\n"))
+			},
+			wantFields: []string{"spec.collector.configuration"},
+		},
+		{
+			name:       "missing collector name",
+			edit:       func(c *v1.Collector) { c.Spec.Collector.Name = "" },
+			wantFields: []string{"spec.collector.name"},
+		},
+		{
+			name: "configuration source with a ConfigMap and a Secret",
+			edit: func(c *v1.Collector) {
+				c.Spec.Collector.ConfigurationFrom = []v1.ConfigurationSource{{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "values"}, Key: "values.yaml"},
+					SecretKeyRef:    &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "keys"}, Key: "values.yaml"},
+				}}
+			},
+			wantFields: []string{"spec.collector.configurationFrom[0]"},
+		},
+		{
+			name:       "configuration source without a ConfigMap or a Secret",
+			edit:       func(c *v1.Collector) { c.Spec.Collector.ConfigurationFrom = []v1.ConfigurationSource{{}} },
+			wantFields: []string{"spec.collector.configurationFrom[0]"},
+		},
+		{
+			name: "configuration source without a key",
+			edit: func(c *v1.Collector) {
+				c.Spec.Collector.ConfigurationFrom = []v1.ConfigurationSource{
+					{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "values"}, Key: "values.yaml"}},
+					{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "keys"}}},
+				}
+			},
+			wantFields: []string{"spec.collector.configurationFrom[1].secretKeyRef.key"},
+		},
+		{
+			name: "version constraint",
+			edit: func(c *v1.Collector) { c.Spec.Collector.Version = ">= 1.2, < 2" },
+		},
+		{
+			name:       "version that isn't semver",
+			edit:       func(c *v1.Collector) { c.Spec.Collector.Version = "newest" },
+			wantFields: []string{"spec.collector.version"},
+		},
+		{
+			name: "tenant reference that is lowercased to a namespace name",
+			edit: func(c *v1.Collector) { c.Spec.Tenant.Reference = "ACME" },
+		},
+		{
+			name:       "tenant reference that isn't a DNS-1123 label",
+			edit:       func(c *v1.Collector) { c.Spec.Tenant.Reference = "acme_corp" },
+			wantFields: []string{"spec.tenant.reference"},
+		},
+		{
+			name:       "missing tenant reference and instance",
+			edit:       func(c *v1.Collector) { c.Spec.Tenant.Reference, c.Spec.Tenant.Instance = "", "" },
+			wantFields: []string{"spec.tenant.reference", "spec.tenant.instance"},
+		},
+		{
+			name: "release name of 53 characters",
+			edit: func(c *v1.Collector) { c.Spec.Tenant.Instance = strings.Repeat("a", 53-len("fluent-bit-")) },
+		},
+		{
+			name:       "release name longer than 53 characters",
+			edit:       func(c *v1.Collector) { c.Spec.Tenant.Instance = strings.Repeat("a", 54-len("fluent-bit-")) },
+			wantFields: []string{"spec.tenant.instance"},
+		},
+		{
+			name:       "release name that isn't valid",
+			edit:       func(c *v1.Collector) { c.Spec.Tenant.Instance = "Main" },
+			wantFields: []string{"spec.tenant.instance"},
+		},
+		{
+			name:       "unknown cluster",
+			edit:       func(c *v1.Collector) { c.Spec.Cluster = "staging" },
+			wantFields: []string{"spec.cluster"},
+		},
+		{
+			name: "unknown cluster with a chart source",
+			edit: func(c *v1.Collector) { c.Spec.Cluster, c.Spec.ChartSource = "staging", "oci" },
+		},
+		{
+			name:       "unknown chart source",
+			edit:       func(c *v1.Collector) { c.Spec.ChartSource = "s3" },
+			wantFields: []string{"spec.chartSource"},
+		},
+		{
+			name:       "unknown drift policy",
+			edit:       func(c *v1.Collector) { c.Spec.DriftPolicy = "revert" },
+			wantFields: []string{"spec.driftPolicy"},
+		},
+	}
+
+	validator := newTestValidator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := validCollector()
+			tt.edit(collector)
+
+			var fields []string
+			for _, err := range validator.Validate(collector) {
+				fields = append(fields, err.Field)
+			}
+
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("Validate() fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestServeValidate(t *testing.T) {
+	valid := validCollector()
+	invalid := validCollector()
+	invalid.Spec.Cluster = "staging"
+
+	deleting := invalid.DeepCopy()
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+
+	relabelled := invalid.DeepCopy()
+	relabelled.Labels = map[string]string{"team": "observability"}
+
+	tests := []struct {
+		name       string
+		method     string
+		review     *admissionv1.AdmissionReview
+		wantCode   int
+		wantAllow  bool
+		wantStatus int32
+	}{
+		{
+			name:      "valid collector is admitted",
+			review:    admissionReview(admissionv1.Create, valid, nil),
+			wantCode:  http.StatusOK,
+			wantAllow: true,
+		},
+		{
+			name:       "invalid collector is rejected",
+			review:     admissionReview(admissionv1.Create, invalid, nil),
+			wantCode:   http.StatusOK,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "update of an invalid spec is rejected",
+			review:     admissionReview(admissionv1.Update, invalid, valid),
+			wantCode:   http.StatusOK,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "update that leaves an invalid spec alone is admitted",
+			review:    admissionReview(admissionv1.Update, relabelled, invalid),
+			wantCode:  http.StatusOK,
+			wantAllow: true,
+		},
+		{
+			name:      "collector being deleted is admitted",
+			review:    admissionReview(admissionv1.Update, deleting, valid),
+			wantCode:  http.StatusOK,
+			wantAllow: true,
+		},
+		{
+			name:      "delete is admitted",
+			review:    admissionReview(admissionv1.Delete, nil, invalid),
+			wantCode:  http.StatusOK,
+			wantAllow: true,
+		},
+		{
+			name: "object that isn't a collector is rejected",
+			review: &admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+				UID: "7f3c", Operation: admissionv1.Create, Object: runtime.RawExtension{Raw: []byte(`{"spec":{"cluster":42}}`)},
+			}},
+			wantCode:   http.StatusOK,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "review without a request is a bad request",
+			review:   &admissionv1.AdmissionReview{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "GET is a bad request",
+			method:   http.MethodGet,
+			review:   admissionReview(admissionv1.Create, valid, nil),
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	server := NewWebhookServer(WebhookOptions{}, newTestValidator(), NewCollectorDefaulter("production", latestVersion))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.review)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			recorder := httptest.NewRecorder()
+			server.serveValidate(recorder, httptest.NewRequest(method, ValidateCollectorPath, bytes.NewReader(body)))
+
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			response := &admissionv1.AdmissionReview{}
+			if err = json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Response.UID != tt.review.Request.UID || response.Response.Allowed != tt.wantAllow {
+				t.Errorf("response = UID %s, allowed %v, want UID %s, allowed %v", response.Response.UID, response.Response.Allowed, tt.review.Request.UID, tt.wantAllow)
+			}
+
+			if !tt.wantAllow && (response.Response.Result == nil || response.Response.Result.Code != tt.wantStatus) {
+				t.Errorf("response result = %+v, want code %d", response.Response.Result, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// admissionReview returns the admission review of the operation on the collector, which replaces the old one.
+func admissionReview(operation admissionv1.Operation, collector *v1.Collector, old *v1.Collector) *admissionv1.AdmissionReview {
+	request := &admissionv1.AdmissionRequest{UID: types.UID("uid-" + strings.ToLower(string(operation))), Operation: operation}
+
+	if collector != nil {
+		request.Object.Raw, _ = json.Marshal(collector)
+	}
+
+	if old != nil {
+		request.OldObject.Raw, _ = json.Marshal(old)
+	}
+
+	return &admissionv1.AdmissionReview{TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"}, Request: request}
+}
+
+func TestInstallWebhooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		conversion  CRDConversion
+		caBundle    string
+		wantErr     bool
+		wantApplied []string
+	}{
+		{
+			name:        "CA bundle is left to be injected",
+			conversion:  CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators", WebhookPort: 9443},
+			wantApplied: []string{"Service operators/collectors-webhook", "MutatingWebhookConfiguration kube8-operator", "ValidatingWebhookConfiguration kube8-operator"},
+		},
+		{
+			name:        "CA bundle is set",
+			conversion:  CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators", WebhookPort: 9443},
+			caBundle:    "-----BEGIN CERTIFICATE-----",
+			wantApplied: []string{"Service operators/collectors-webhook", "MutatingWebhookConfiguration kube8-operator", "ValidatingWebhookConfiguration kube8-operator"},
+		},
+		{
+			name:       "webhook port is required",
+			conversion: CRDConversion{ServiceName: "collectors-webhook", ServiceNamespace: "operators"},
+			wantErr:    true,
+		},
+		{
+			name:       "operator namespace is required",
+			conversion: CRDConversion{ServiceName: "collectors-webhook", WebhookPort: 9443},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conversion.CABundleFile = filepath.Join(t.TempDir(), "ca.crt")
+			if tt.caBundle != "" {
+				if err := os.WriteFile(tt.conversion.CABundleFile, []byte(tt.caBundle), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+			var applied []string
+
+			objects := map[string]*unstructured.Unstructured{}
+
+			client.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				patch := action.(clienttesting.PatchAction)
+				if patch.GetPatchType() != types.ApplyPatchType {
+					t.Errorf("patch type = %s, want apply", patch.GetPatchType())
+				}
+
+				object := &unstructured.Unstructured{}
+				if err := json.Unmarshal(patch.GetPatch(), &object.Object); err != nil {
+					t.Fatal(err)
+				}
+
+				name := object.GetKind() + " " + strings.TrimPrefix(patch.GetNamespace()+"/"+patch.GetName(), "/")
+				applied = append(applied, name)
+				objects[object.GetKind()] = object
+
+				return true, object, nil
+			})
+
+			err := installWebhooks(context.Background(), client, tt.conversion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("installWebhooks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(applied, tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if targetPort, _, _ := unstructured.NestedFieldNoCopy(objects["Service"].Object, "spec", "ports"); !strings.Contains(toJSON(t, targetPort), `"targetPort":9443`) {
+				t.Errorf("Service ports = %s, want target port 9443", toJSON(t, targetPort))
+			}
+
+			for _, kind := range []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"} {
+				rendered := toJSON(t, objects[kind].Object)
+				if strings.Contains(rendered, "${") || !strings.Contains(rendered, `"name":"collectors-webhook","namespace":"operators"`) {
+					t.Errorf("%s = %s, want the collectors-webhook Service of the operators namespace", kind, rendered)
+				}
+
+				injected := objects[kind].GetAnnotations()[injectCAFromAnnotation]
+				hasCABundle := strings.Contains(rendered, `"caBundle":"`+base64.StdEncoding.EncodeToString([]byte(tt.caBundle))+`"`)
+
+				if tt.caBundle == "" && (injected != "operators/collectors-webhook" || hasCABundle) {
+					t.Errorf("%s = %s, want the CA bundle injected from operators/collectors-webhook", kind, rendered)
+				}
+
+				if tt.caBundle != "" && (injected != "" || !hasCABundle) {
+					t.Errorf("%s = %s, want the CA bundle set and not injected", kind, rendered)
+				}
+			}
+		})
+	}
+}
+
+// toJSON returns the value as JSON.
+func toJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
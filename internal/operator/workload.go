@@ -0,0 +1,729 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+const (
+	// releaseIndex indexes collectors and workloads by the {namespace}/{name} of their Helm release.
+	releaseIndex = "release"
+	// releaseNamespaceAnnotation is the annotation Helm puts on every object it creates with the namespace of the release.
+	releaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	// deploymentProgressDeadlineExceeded is the reason of the Progressing condition of a Deployment whose rollout is stuck.
+	deploymentProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+)
+
+// Readiness condition reasons.
+const (
+	reasonWorkloadsReady    = "WorkloadsReady"
+	reasonWorkloadsNotReady = "WorkloadsNotReady"
+	reasonPodFailing        = "PodFailing"
+	reasonNotInstalled      = "NotInstalled"
+)
+
+// podWaitingFailures are the waiting reasons of a container that won't start without a change.
+var podWaitingFailures = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// workloadKinds are the kinds of the workloads whose readiness is rolled up.
+var workloadKinds = map[schema.GroupKind]bool{
+	{Group: appsv1.GroupName, Kind: "Deployment"}:  true,
+	{Group: appsv1.GroupName, Kind: "StatefulSet"}: true,
+	{Group: appsv1.GroupName, Kind: "DaemonSet"}:   true,
+}
+
+// newWorkloadInformers creates the informers of the Deployments, StatefulSets and DaemonSets of the collectors, which
+// carry the UID label of their Collector, indexed by the Helm release they belong to. Other workloads aren't watched,
+// releases deployed before their objects were labelled are upgraded by the next reconcile.
+func newWorkloadInformers(client kubernetes.Interface) []cache.SharedIndexInformer {
+	workloads := []struct {
+		resource string
+		object   runtime.Object
+	}{
+		{resource: "deployments", object: &appsv1.Deployment{}},
+		{resource: "statefulsets", object: &appsv1.StatefulSet{}},
+		{resource: "daemonsets", object: &appsv1.DaemonSet{}},
+	}
+
+	informers := make([]cache.SharedIndexInformer, 0, len(workloads))
+
+	for _, workload := range workloads {
+		listWatch := cache.NewFilteredListWatchFromClient(client.AppsV1().RESTClient(), workload.resource, metav1.NamespaceAll, func(options *metav1.ListOptions) {
+			options.LabelSelector = LabelCollectorUID
+		})
+		informers = append(informers, cache.NewSharedIndexInformer(listWatch, workload.object, resyncePeriod, cache.Indexers{releaseIndex: workloadReleaseIndex}))
+	}
+
+	return informers
+}
+
+// newPodInformer creates the informer of the pods of the collector workloads, which carry the UID label of their
+// Collector, indexed by that UID. Other pods aren't watched.
+func newPodInformer(client kubernetes.Interface) cache.SharedIndexInformer {
+	listWatch := cache.NewFilteredListWatchFromClient(client.CoreV1().RESTClient(), "pods", metav1.NamespaceAll, func(options *metav1.ListOptions) {
+		options.LabelSelector = LabelCollectorUID
+	})
+
+	return cache.NewSharedIndexInformer(listWatch, &corev1.Pod{}, resyncePeriod, cache.Indexers{uidIndex: podCollectorUIDIndex})
+}
+
+// podCollectorUIDIndex indexes a pod by the UID label of its Collector.
+func podCollectorUIDIndex(obj interface{}) ([]string, error) {
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if uid := object.GetLabels()[LabelCollectorUID]; uid != "" {
+		return []string{uid}, nil
+	}
+
+	return nil, nil
+}
+
+// enqueuePodCollector queues the readiness of the collector the pod belongs to, so that a container that starts
+// crash looping is reported even though the status of its workload doesn't change.
+func (c *Controller) enqueuePodCollector(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	uids, err := podCollectorUIDIndex(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+
+		return
+	}
+
+	for _, uid := range uids {
+		collectors, err := c.informer.GetIndexer().ByIndex(uidIndex, uid)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			continue
+		}
+
+		for _, collector := range collectors {
+			c.enqueueReadiness(collector)
+		}
+	}
+}
+
+// podEventHandler queues the readiness of the collector of a pod whenever the pod changes.
+func (c *Controller) podEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueuePodCollector,
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			oldMeta, oldErr := meta.Accessor(oldObject)
+			newMeta, newErr := meta.Accessor(newObject)
+			// Periodic resyncs don't change anything
+			if oldErr == nil && newErr == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+
+			c.enqueuePodCollector(newObject)
+		},
+		DeleteFunc: c.enqueuePodCollector,
+	}
+}
+
+// releaseWorkloads holds the workloads of the manifest of the deployed revision of every collector release, by the
+// UID of the Collector, so that a workload that doesn't exist (yet) isn't taken as ready.
+type releaseWorkloads struct {
+	mu        sync.Mutex
+	revisions map[types.UID]manifestWorkloads
+}
+
+// manifestWorkloads are the workloads of the manifest of a release revision, by kind and name.
+type manifestWorkloads struct {
+	revision  int
+	workloads []v1alpha.WorkloadStatus
+}
+
+// newReleaseWorkloads creates an empty set of release workloads.
+func newReleaseWorkloads() *releaseWorkloads {
+	return &releaseWorkloads{revisions: map[types.UID]manifestWorkloads{}}
+}
+
+// get returns the workloads of the revision of the release of the Collector, if they are known.
+func (w *releaseWorkloads) get(uid types.UID, revision int) ([]v1alpha.WorkloadStatus, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	workloads, ok := w.revisions[uid]
+	if !ok || workloads.revision != revision {
+		return nil, false
+	}
+
+	return workloads.workloads, true
+}
+
+// record keeps the workloads of the manifest of the revision of the release of the Collector.
+func (w *releaseWorkloads) record(uid types.UID, revision int, manifest string) error {
+	workloads, err := workloadsOfManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.revisions[uid] = manifestWorkloads{revision: revision, workloads: workloads}
+
+	return nil
+}
+
+// forget drops the workloads of the release of the Collector.
+func (w *releaseWorkloads) forget(uid types.UID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.revisions, uid)
+}
+
+// workloadsOfManifest returns the Deployments, StatefulSets and DaemonSets of a release manifest.
+func workloadsOfManifest(manifest string) ([]v1alpha.WorkloadStatus, error) {
+	var workloads []v1alpha.WorkloadStatus
+
+	for _, document := range releaseutil.SplitManifests(manifest) {
+		object := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(document), &object.Object); err != nil {
+			return nil, fmt.Errorf("could not decode release manifest: %w", err)
+		}
+
+		if len(object.Object) != 0 && workloadKinds[object.GroupVersionKind().GroupKind()] {
+			workloads = append(workloads, v1alpha.WorkloadStatus{Kind: object.GetKind(), Name: object.GetName()})
+		}
+	}
+
+	return workloads, nil
+}
+
+// workloadReleaseIndex indexes a workload by the Helm release annotations, objects that weren't deployed by Helm aren't indexed.
+func workloadReleaseIndex(obj interface{}) ([]string, error) {
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	name, namespace := object.GetAnnotations()[releaseNameAnnotation], object.GetAnnotations()[releaseNamespaceAnnotation]
+	if name == "" || namespace == "" {
+		return nil, nil
+	}
+
+	return []string{namespace + "/" + name}, nil
+}
+
+// collectorReleaseIndex indexes a collector by its Helm release.
+func (c *Controller) collectorReleaseIndex(obj interface{}) ([]string, error) {
+	collector, ok := obj.(*v1alpha.Collector)
+	if !ok {
+		return nil, fmt.Errorf("expected Collector but got %T", obj)
+	}
+
+	return []string{c.collectorNamespace(collector) + "/" + releaseName(collector)}, nil
+}
+
+// enqueueWorkloadCollectors queues the readiness of the collectors whose release the workload belongs to.
+func (c *Controller) enqueueWorkloadCollectors(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	releases, err := workloadReleaseIndex(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+
+		return
+	}
+
+	for _, release := range releases {
+		collectors, err := c.informer.GetIndexer().ByIndex(releaseIndex, release)
+		if err != nil {
+			utilruntime.HandleError(err)
+
+			continue
+		}
+
+		for _, collector := range collectors {
+			key, err := cache.MetaNamespaceKeyFunc(collector)
+			if err != nil {
+				utilruntime.HandleError(err)
+
+				continue
+			}
+
+			c.readinessQueue.Add(key)
+		}
+	}
+}
+
+// workloadEventHandler queues the readiness of the collectors of a workload whenever it changes.
+func (c *Controller) workloadEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueWorkloadCollectors,
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			oldMeta, oldErr := meta.Accessor(oldObject)
+			newMeta, newErr := meta.Accessor(newObject)
+			// Periodic resyncs don't change anything
+			if oldErr == nil && newErr == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+
+			c.enqueueWorkloadCollectors(newObject)
+		},
+		DeleteFunc: c.enqueueWorkloadCollectors,
+	}
+}
+
+// collectorReadiness is the readiness of the workloads of a collector release.
+type collectorReadiness struct {
+	workloads  []v1alpha.WorkloadStatus
+	ready      int32
+	desired    int32
+	podFailure string
+}
+
+// isReady reports whether every workload of the release is ready.
+func (r *collectorReadiness) isReady() bool {
+	for _, workload := range r.workloads {
+		if !workload.Ready {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply writes the readiness to the status. The collector is Available when its release is installed and
+// every workload is ready.
+func (r *collectorReadiness) apply(resource *v1alpha.Collector, status *v1alpha.CollectorStatus) {
+	status.Workloads = r.workloads
+	status.ReadyReplicas = r.ready
+	status.DesiredReplicas = r.desired
+
+	// Nothing is reported as ready before the first install or upgrade was attempted
+	installed := meta.FindStatusCondition(status.Conditions, typeInstalled)
+	if installed == nil {
+		return
+	}
+
+	ready := r.isReady()
+
+	switch {
+	case ready:
+		status.LastPodFailure = ""
+	case r.podFailure != "":
+		status.LastPodFailure = r.podFailure
+	}
+
+	readyCondition := newCondition(resource, typeReady, metav1.ConditionTrue, reasonWorkloadsReady, fmt.Sprintf("%d/%d replicas ready", r.ready, r.desired))
+
+	if !ready {
+		var pending []string
+
+		for _, workload := range r.workloads {
+			if !workload.Ready {
+				pending = append(pending, workload.Kind+"/"+workload.Name+": "+workload.Message)
+			}
+		}
+
+		readyCondition = newCondition(resource, typeReady, metav1.ConditionFalse, reasonWorkloadsNotReady, strings.Join(pending, "; "))
+
+		if r.podFailure != "" {
+			readyCondition.Reason = reasonPodFailing
+			readyCondition.Message += "; " + r.podFailure
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, readyCondition)
+
+	// The availability of a release that failed or was rolled back is reported by the reconcile
+	if installed.Status != metav1.ConditionTrue {
+		if meta.FindStatusCondition(status.Conditions, typeAvailableCollector) == nil {
+			meta.SetStatusCondition(&status.Conditions, newCondition(resource, typeAvailableCollector, metav1.ConditionFalse, reasonNotInstalled, installed.Message))
+		}
+
+		return
+	}
+
+	if ready {
+		meta.SetStatusCondition(&status.Conditions, newCondition(resource, typeAvailableCollector, metav1.ConditionTrue, reasonWorkloadsReady, fmt.Sprintf("Collector is serving with %d/%d replicas ready", r.ready, r.desired)))
+	} else {
+		meta.SetStatusCondition(&status.Conditions, newCondition(resource, typeAvailableCollector, metav1.ConditionFalse, readyCondition.Reason, readyCondition.Message))
+	}
+}
+
+// workloadReadiness rolls the readiness of the Deployments, StatefulSets and DaemonSets of the collector release up.
+// The workloads of the manifest of the deployed revision that aren't observed are reported as not ready. When a
+// workload isn't ready its pods are looked up to find out why they fail.
+func (c *Controller) workloadReadiness(resource *v1alpha.Collector) (*collectorReadiness, error) {
+	namespace := c.collectorNamespace(resource)
+	readiness := &collectorReadiness{}
+
+	var failing []*metav1.LabelSelector
+
+	for _, informer := range c.workloadInformers {
+		objects, err := informer.GetIndexer().ByIndex(releaseIndex, namespace+"/"+releaseName(resource))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range objects {
+			workload, selector := workloadStatus(object)
+			if workload == nil {
+				continue
+			}
+
+			readiness.workloads = append(readiness.workloads, *workload)
+			readiness.ready += workload.ReadyReplicas
+			readiness.desired += workload.DesiredReplicas
+
+			if !workload.Ready {
+				failing = append(failing, selector)
+			}
+		}
+	}
+
+	expected, err := c.expectedWorkloads(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, workload := range expected {
+		observed := false
+
+		for i := range readiness.workloads {
+			if readiness.workloads[i].Kind == workload.Kind && readiness.workloads[i].Name == workload.Name {
+				observed = true
+
+				break
+			}
+		}
+
+		if !observed {
+			workload.Message = "not found"
+			readiness.workloads = append(readiness.workloads, workload)
+		}
+	}
+
+	// The informers don't guarantee an order, a stable one avoids needless status writes
+	sort.Slice(readiness.workloads, func(i, j int) bool {
+		if readiness.workloads[i].Kind != readiness.workloads[j].Kind {
+			return readiness.workloads[i].Kind < readiness.workloads[j].Kind
+		}
+
+		return readiness.workloads[i].Name < readiness.workloads[j].Name
+	})
+
+	for _, selector := range failing {
+		failure, err := c.podFailure(resource, namespace, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		if failure != "" {
+			readiness.podFailure = failure
+
+			break
+		}
+	}
+
+	return readiness, nil
+}
+
+// workloadStatus returns the readiness of a Deployment, StatefulSet or DaemonSet along with its pod selector.
+// nolint: gocyclo, cyclop
+func workloadStatus(object interface{}) (*v1alpha.WorkloadStatus, *metav1.LabelSelector) {
+	switch workload := object.(type) {
+	case *appsv1.Deployment:
+		desired := replicas(workload.Spec.Replicas)
+		status := &v1alpha.WorkloadStatus{
+			Kind:            "Deployment",
+			Name:            workload.Name,
+			DesiredReplicas: desired,
+			ReadyReplicas:   workload.Status.ReadyReplicas,
+			UpdatedReplicas: workload.Status.UpdatedReplicas,
+		}
+
+		progressing := deploymentCondition(workload, appsv1.DeploymentProgressing)
+
+		switch {
+		case workload.Status.ObservedGeneration < workload.Generation:
+			status.Message = "waiting for the rollout to be observed"
+		case progressing != nil && progressing.Reason == deploymentProgressDeadlineExceeded:
+			status.Message = progressing.Message
+		case workload.Status.UpdatedReplicas < desired:
+			status.Message = fmt.Sprintf("%d/%d replicas updated", workload.Status.UpdatedReplicas, desired)
+		case workload.Status.Replicas > workload.Status.UpdatedReplicas:
+			status.Message = fmt.Sprintf("%d old replicas pending termination", workload.Status.Replicas-workload.Status.UpdatedReplicas)
+		case workload.Status.AvailableReplicas < desired:
+			status.Message = fmt.Sprintf("%d/%d replicas available", workload.Status.AvailableReplicas, desired)
+		default:
+			status.Ready = true
+		}
+
+		return status, workload.Spec.Selector
+	case *appsv1.StatefulSet:
+		desired := replicas(workload.Spec.Replicas)
+		status := &v1alpha.WorkloadStatus{
+			Kind:            "StatefulSet",
+			Name:            workload.Name,
+			DesiredReplicas: desired,
+			ReadyReplicas:   workload.Status.ReadyReplicas,
+			UpdatedReplicas: workload.Status.UpdatedReplicas,
+		}
+
+		switch {
+		case workload.Status.ObservedGeneration < workload.Generation:
+			status.Message = "waiting for the rollout to be observed"
+		case workload.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType && workload.Status.UpdateRevision != workload.Status.CurrentRevision:
+			status.Message = fmt.Sprintf("%d/%d replicas updated", workload.Status.UpdatedReplicas, desired)
+		case workload.Status.ReadyReplicas < desired:
+			status.Message = fmt.Sprintf("%d/%d replicas ready", workload.Status.ReadyReplicas, desired)
+		default:
+			status.Ready = true
+		}
+
+		return status, workload.Spec.Selector
+	case *appsv1.DaemonSet:
+		desired := workload.Status.DesiredNumberScheduled
+		status := &v1alpha.WorkloadStatus{
+			Kind:            "DaemonSet",
+			Name:            workload.Name,
+			DesiredReplicas: desired,
+			ReadyReplicas:   workload.Status.NumberReady,
+			UpdatedReplicas: workload.Status.UpdatedNumberScheduled,
+		}
+
+		switch {
+		case workload.Status.ObservedGeneration < workload.Generation:
+			status.Message = "waiting for the rollout to be observed"
+		case workload.Status.UpdatedNumberScheduled < desired:
+			status.Message = fmt.Sprintf("%d/%d pods updated", workload.Status.UpdatedNumberScheduled, desired)
+		case workload.Status.NumberAvailable < desired:
+			status.Message = fmt.Sprintf("%d/%d pods available", workload.Status.NumberAvailable, desired)
+		default:
+			status.Ready = true
+		}
+
+		return status, workload.Spec.Selector
+	default:
+		return nil, nil
+	}
+}
+
+// replicas returns the desired replicas of a workload, which default to one.
+func replicas(desired *int32) int32 {
+	if desired == nil {
+		return 1
+	}
+
+	return *desired
+}
+
+// deploymentCondition returns the condition of the given type of the Deployment.
+func deploymentCondition(deployment *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[i].Type == conditionType {
+			return &deployment.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// expectedWorkloads returns the workloads of the manifest of the deployed revision of the collector release. They
+// are recorded when the release is deployed, and read from the release otherwise, e.g. after a restart.
+func (c *Controller) expectedWorkloads(resource *v1alpha.Collector) ([]v1alpha.WorkloadStatus, error) {
+	if resource.Status.HelmRevision == 0 {
+		return nil, nil
+	}
+
+	if workloads, ok := c.releaseWorkloads.get(resource.UID, resource.Status.HelmRevision); ok {
+		return workloads, nil
+	}
+
+	actionConfig, err := c.reconciler.newActionConfiguration(c.collectorNamespace(resource))
+	if err != nil {
+		return nil, err
+	}
+
+	deployed, err := actionConfig.Releases.Get(releaseName(resource), resource.Status.HelmRevision)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get revision %d of release %s: %w", resource.Status.HelmRevision, releaseName(resource), err)
+	}
+
+	if err = c.releaseWorkloads.record(resource.UID, deployed.Version, deployed.Manifest); err != nil {
+		return nil, err
+	}
+
+	workloads, _ := c.releaseWorkloads.get(resource.UID, deployed.Version)
+
+	return workloads, nil
+}
+
+// podFailure returns why a pod of the collector selected by the workload selector fails, or an empty string when
+// none does. The pods are read from the pod informer.
+func (c *Controller) podFailure(resource *v1alpha.Collector, namespace string, selector *metav1.LabelSelector) (string, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", err
+	}
+
+	pods, err := c.podInformer.GetIndexer().ByIndex(uidIndex, string(resource.UID))
+	if err != nil {
+		return "", err
+	}
+
+	for _, obj := range pods {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Namespace != namespace || !labelSelector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		if failure := podFailureReason(pod); failure != "" {
+			return failure, nil
+		}
+	}
+
+	return "", nil
+}
+
+// podFailureReason returns why the pod fails: a container that can't start, a container that was killed or
+// a pod that can't be scheduled.
+func podFailureReason(pod *corev1.Pod) string {
+	for _, container := range pod.Status.ContainerStatuses {
+		if waiting := container.State.Waiting; waiting != nil && podWaitingFailures[waiting.Reason] {
+			failure := fmt.Sprintf("pod %s container %s: %s", pod.Name, container.Name, waiting.Reason)
+
+			// The termination of the last attempt tells why a container crash loops, e.g. OOMKilled
+			if terminated := container.LastTerminationState.Terminated; terminated != nil && terminated.Reason != "" {
+				failure += fmt.Sprintf(" (last terminated: %s, exit code %d)", terminated.Reason, terminated.ExitCode)
+			}
+
+			return failure
+		}
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason != "" {
+			return fmt.Sprintf("pod %s: %s: %s", pod.Name, condition.Reason, condition.Message)
+		}
+	}
+
+	return ""
+}
+
+// RunReadinessWorker processes collector keys off the readiness queue until it is shut down.
+func (c *Controller) RunReadinessWorker(ctx context.Context) {
+	for c.processNextReadinessItem(ctx) {
+	}
+}
+
+// processNextReadinessItem pops a single key off the readiness queue and updates the readiness of the collector.
+func (c *Controller) processNextReadinessItem(ctx context.Context) bool {
+	item, shutdown := c.readinessQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.readinessQueue.Done(item)
+
+	if c.readinessQueue.ShuttingDown() {
+		return false
+	}
+
+	key, ok := item.(string)
+	if !ok {
+		c.readinessQueue.Forget(item)
+		utilruntime.HandleError(fmt.Errorf("expected string in readiness queue but got %#v", item))
+
+		return true
+	}
+
+	if err := c.syncReadiness(ctx, key); err != nil {
+		c.readinessQueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing readiness of collector %q, requeuing: %w", key, err))
+
+		return true
+	}
+
+	c.readinessQueue.Forget(key)
+
+	return true
+}
+
+// syncReadiness rolls the readiness of the collector workloads up into its status.
+func (c *Controller) syncReadiness(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+
+		return nil
+	}
+
+	collector, err := c.lister.Collectors(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// A collector being deleted reports Terminating rather than its readiness
+	if collector.DeletionTimestamp != nil {
+		return nil
+	}
+
+	readiness, err := c.workloadReadiness(collector)
+	if err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("Readiness of collector %s is %d/%d replicas ready", key, readiness.ready, readiness.desired)
+
+	// Nothing is written when the readiness didn't change
+	_, err = c.MutateStatus(ctx, collector, func(status *v1alpha.CollectorStatus) {
+		readiness.apply(collector, status)
+	})
+
+	return err
+}
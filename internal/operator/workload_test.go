@@ -0,0 +1,204 @@
+package operator
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// testDeployment returns the fluent-bit Deployment at generation 2 with the replicas and status.
+func testDeployment(replicas *int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit", Namespace: "acme", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: replicas},
+		Status:     status,
+	}
+}
+
+// testStatefulSet returns the fluent-bit StatefulSet at generation 2 with the update strategy and status.
+func testStatefulSet(strategy appsv1.StatefulSetUpdateStrategyType, status appsv1.StatefulSetStatus) *appsv1.StatefulSet {
+	replicas := int32(2)
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit", Namespace: "acme", Generation: 2},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: strategy}},
+		Status:     status,
+	}
+}
+
+// testDaemonSet returns the fluent-bit DaemonSet at generation 2 with the status.
+func testDaemonSet(status appsv1.DaemonSetStatus) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit", Namespace: "acme", Generation: 2},
+		Status:     status,
+	}
+}
+
+func TestWorkloadStatus(t *testing.T) {
+	three := int32(3)
+
+	tests := []struct {
+		name   string
+		object interface{}
+		want   *v1alpha.WorkloadStatus
+	}{
+		{
+			name:   "deployment whose rollout isn't observed yet",
+			object: testDeployment(&three, appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}),
+			want:   &v1alpha.WorkloadStatus{Kind: "Deployment", Name: "fluent-bit", DesiredReplicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, Message: "waiting for the rollout to be observed"},
+		},
+		{
+			name: "deployment past its progress deadline",
+			object: testDeployment(&three, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 4, ReadyReplicas: 3, UpdatedReplicas: 1, AvailableReplicas: 3,
+				Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: deploymentProgressDeadlineExceeded, Message: `ReplicaSet "fluent-bit-7d4b" has timed out progressing.`}},
+			}),
+			want: &v1alpha.WorkloadStatus{Kind: "Deployment", Name: "fluent-bit", DesiredReplicas: 3, ReadyReplicas: 3, UpdatedReplicas: 1, Message: `ReplicaSet "fluent-bit-7d4b" has timed out progressing.`},
+		},
+		{
+			name:   "deployment rolling out",
+			object: testDeployment(&three, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, ReadyReplicas: 3, UpdatedReplicas: 1, AvailableReplicas: 3}),
+			want:   &v1alpha.WorkloadStatus{Kind: "Deployment", Name: "fluent-bit", DesiredReplicas: 3, ReadyReplicas: 3, UpdatedReplicas: 1, Message: "1/3 replicas updated"},
+		},
+		{
+			name:   "deployment with old replicas terminating",
+			object: testDeployment(&three, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, ReadyReplicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}),
+			want:   &v1alpha.WorkloadStatus{Kind: "Deployment", Name: "fluent-bit", DesiredReplicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, Message: "1 old replicas pending termination"},
+		},
+		{
+			name:   "deployment with unavailable replicas",
+			object: testDeployment(&three, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, ReadyReplicas: 2, UpdatedReplicas: 3, AvailableReplicas: 2}),
+			want:   &v1alpha.WorkloadStatus{Kind: "Deployment", Name: "fluent-bit", DesiredReplicas: 3, ReadyReplicas: 2, UpdatedReplicas: 3, Message: "2/3 replicas available"},
+		},
+		{
+			name:   "deployment without replicas defaults to one",
+			object: testDeployment(nil, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, ReadyReplicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}),
+			want:   &v1alpha.WorkloadStatus{Kind: "Deployment", Name: "fluent-bit", DesiredReplicas: 1, ReadyReplicas: 1, UpdatedReplicas: 1, Ready: true},
+		},
+		{
+			name:   "statefulset whose rollout isn't observed yet",
+			object: testStatefulSet(appsv1.RollingUpdateStatefulSetStrategyType, appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: 2, UpdatedReplicas: 2}),
+			want:   &v1alpha.WorkloadStatus{Kind: "StatefulSet", Name: "fluent-bit", DesiredReplicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2, Message: "waiting for the rollout to be observed"},
+		},
+		{
+			name:   "statefulset rolling out",
+			object: testStatefulSet(appsv1.RollingUpdateStatefulSetStrategyType, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "fluent-bit-1", UpdateRevision: "fluent-bit-2"}),
+			want:   &v1alpha.WorkloadStatus{Kind: "StatefulSet", Name: "fluent-bit", DesiredReplicas: 2, ReadyReplicas: 2, UpdatedReplicas: 1, Message: "1/2 replicas updated"},
+		},
+		{
+			name:   "statefulset updated on delete doesn't wait for its revision",
+			object: testStatefulSet(appsv1.OnDeleteStatefulSetStrategyType, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2, UpdatedReplicas: 0, CurrentRevision: "fluent-bit-1", UpdateRevision: "fluent-bit-2"}),
+			want:   &v1alpha.WorkloadStatus{Kind: "StatefulSet", Name: "fluent-bit", DesiredReplicas: 2, ReadyReplicas: 2, Ready: true},
+		},
+		{
+			name:   "statefulset with replicas that aren't ready",
+			object: testStatefulSet(appsv1.RollingUpdateStatefulSetStrategyType, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 1, UpdatedReplicas: 2, CurrentRevision: "fluent-bit-2", UpdateRevision: "fluent-bit-2"}),
+			want:   &v1alpha.WorkloadStatus{Kind: "StatefulSet", Name: "fluent-bit", DesiredReplicas: 2, ReadyReplicas: 1, UpdatedReplicas: 2, Message: "1/2 replicas ready"},
+		},
+		{
+			name:   "statefulset that is ready",
+			object: testStatefulSet(appsv1.RollingUpdateStatefulSetStrategyType, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2, UpdatedReplicas: 2, CurrentRevision: "fluent-bit-2", UpdateRevision: "fluent-bit-2"}),
+			want:   &v1alpha.WorkloadStatus{Kind: "StatefulSet", Name: "fluent-bit", DesiredReplicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2, Ready: true},
+		},
+		{
+			name:   "daemonset whose rollout isn't observed yet",
+			object: testDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 5, NumberReady: 5, UpdatedNumberScheduled: 5, NumberAvailable: 5}),
+			want:   &v1alpha.WorkloadStatus{Kind: "DaemonSet", Name: "fluent-bit", DesiredReplicas: 5, ReadyReplicas: 5, UpdatedReplicas: 5, Message: "waiting for the rollout to be observed"},
+		},
+		{
+			name:   "daemonset rolling out",
+			object: testDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 5, NumberReady: 5, UpdatedNumberScheduled: 2, NumberAvailable: 5}),
+			want:   &v1alpha.WorkloadStatus{Kind: "DaemonSet", Name: "fluent-bit", DesiredReplicas: 5, ReadyReplicas: 5, UpdatedReplicas: 2, Message: "2/5 pods updated"},
+		},
+		{
+			name:   "daemonset with unavailable pods",
+			object: testDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 5, NumberReady: 4, UpdatedNumberScheduled: 5, NumberAvailable: 4}),
+			want:   &v1alpha.WorkloadStatus{Kind: "DaemonSet", Name: "fluent-bit", DesiredReplicas: 5, ReadyReplicas: 4, UpdatedReplicas: 5, Message: "4/5 pods available"},
+		},
+		{
+			name:   "daemonset that is ready",
+			object: testDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 5, NumberReady: 5, UpdatedNumberScheduled: 5, NumberAvailable: 5}),
+			want:   &v1alpha.WorkloadStatus{Kind: "DaemonSet", Name: "fluent-bit", DesiredReplicas: 5, ReadyReplicas: 5, UpdatedReplicas: 5, Ready: true},
+		},
+		{
+			name:   "other kind",
+			object: &corev1.Pod{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := workloadStatus(tt.object)
+
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("workloadStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodFailureReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []corev1.ContainerStatus
+		conditions []corev1.PodCondition
+		want       string
+	}{
+		{
+			name:       "running pod",
+			containers: []corev1.ContainerStatus{{Name: "fluent-bit", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+		},
+		{
+			name:       "container that is being created",
+			containers: []corev1.ContainerStatus{{Name: "fluent-bit", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}},
+		},
+		{
+			name: "container crash looping after it was killed",
+			containers: []corev1.ContainerStatus{{
+				Name:                 "fluent-bit",
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+			}},
+			want: "pod fluent-bit-x7k2p container fluent-bit: CrashLoopBackOff (last terminated: OOMKilled, exit code 137)",
+		},
+		{
+			name: "container crash looping",
+			containers: []corev1.ContainerStatus{
+				{Name: "config-reloader", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				{Name: "fluent-bit", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+			},
+			want: "pod fluent-bit-x7k2p container fluent-bit: CrashLoopBackOff",
+		},
+		{
+			name:       "image that can't be pulled",
+			containers: []corev1.ContainerStatus{{Name: "fluent-bit", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}},
+			want:       "pod fluent-bit-x7k2p container fluent-bit: ImagePullBackOff",
+		},
+		{
+			name:       "pod that can't be scheduled",
+			conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available: 3 Insufficient memory."}},
+			want:       "pod fluent-bit-x7k2p: Unschedulable: 0/3 nodes are available: 3 Insufficient memory.",
+		},
+		{
+			name:       "scheduled pod",
+			conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit-x7k2p", Namespace: "acme"},
+				Status:     corev1.PodStatus{ContainerStatuses: tt.containers, Conditions: tt.conditions},
+			}
+
+			if got := podFailureReason(pod); got != tt.want {
+				t.Errorf("podFailureReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package v1alpha
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	CollectorSetKind      string = "CollectorSet"
+	CollectorSetPlural    string = "collectorsets"
+	CollectorSetSingular  string = "collectorset"
+	CollectorSetShortName string = "colset"
+	CollectorSetName      string = CollectorSetPlural + "." + GroupName
+)
+
+// CollectorSetSpec defines the desired state of CollectorSet. Exactly one of TenantSelector and Tenants is set.
+// +kubebuilder:validation:XValidation:rule="has(self.tenantSelector) != has(self.tenants)",message="exactly one of tenantSelector and tenants must be set"
+type CollectorSetSpec struct {
+	// Template is the Collector deployed for every selected tenant.
+	Template CollectorTemplate `json:"template"`
+	// TenantSelector selects the Tenants to deploy the collector for by their labels.
+	// +optional
+	TenantSelector *metav1.LabelSelector `json:"tenantSelector,omitempty"`
+	// Tenants lists the Tenants to deploy the collector for, with their value overrides.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Tenants []CollectorSetTenant `json:"tenants,omitempty"`
+}
+
+// CollectorTemplate describes the Collectors of a CollectorSet. Their tenant is set from the Tenant each of
+// them is deployed for.
+type CollectorTemplate struct {
+	// +optional
+	Metadata CollectorTemplateMetadata `json:"metadata,omitempty"`
+	Spec     CollectorTemplateSpec     `json:"spec"`
+}
+
+// CollectorTemplateMetadata are the labels and annotations of the Collectors of a CollectorSet.
+type CollectorTemplateMetadata struct {
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// CollectorTemplateSpec is the spec of the Collectors of a CollectorSet, a CollectorSpec without the tenant.
+type CollectorTemplateSpec struct {
+	Collector CollectorInfo `json:"collector"`
+	// Instance of the tenants the collectors are deployed for.
+	// +kubebuilder:validation:MinLength=1
+	Instance string `json:"instance"`
+	// Cluster is the environment the collectors run in (gke-dev, gke-prod, etc), it selects the chart source.
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
+	// ChartSource is the name of the operator configured chart source to install the collectors from.
+	// +optional
+	ChartSource string `json:"chartSource,omitempty"`
+	// DriftPolicy is what the operator does when the live objects of a release drift from its manifest.
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+}
+
+// CollectorSetTenant is a Tenant a CollectorSet deploys the collector for.
+type CollectorSetTenant struct {
+	// Name of the Tenant.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Values are chart values merged over the configuration of the template for this tenant.
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Values *apiextensionsv1.JSON `json:"values,omitempty"`
+}
+
+// CollectorSet is the Schema for the collector set API, a collector deployed for many tenants.
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=colset
+// +kubebuilder:printcolumn:name="Collector",type=string,JSONPath=`.spec.template.spec.collector.name`
+// +kubebuilder:printcolumn:name="Tenants",type=integer,JSONPath=`.status.tenants`
+// +kubebuilder:printcolumn:name="Available",type=integer,JSONPath=`.status.availableCollectors`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type CollectorSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CollectorSetSpec   `json:"spec,omitempty"`
+	Status CollectorSetStatus `json:"status,omitempty"`
+}
+
+// CollectorSetList is a list of CollectorSet resources.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type CollectorSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CollectorSet `json:"items"`
+}
+
+// CollectorSetStatus defines the observed state of CollectorSet.
+type CollectorSetStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchMergeKey:"type" patchStrategy:"merge" protobuf:"bytes,1,rep,name=conditions"`
+	// ObservedGeneration is the metadata.generation of the CollectorSet the status was last reconciled from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Tenants is the number of tenants selected by the CollectorSet.
+	Tenants int32 `json:"tenants,omitempty"`
+	// Collectors is the number of Collectors of the CollectorSet.
+	Collectors int32 `json:"collectors,omitempty"`
+	// UpdatedCollectors is the number of Collectors that match the current template.
+	UpdatedCollectors int32 `json:"updatedCollectors,omitempty"`
+	// AvailableCollectors is the number of Collectors whose current generation is Available.
+	AvailableCollectors int32 `json:"availableCollectors,omitempty"`
+}
@@ -0,0 +1,245 @@
+package v1alpha
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+
+	"kube8-operator/pkg/apis/collector/v1beta1"
+)
+
+// Annotations that keep what one version can't represent when a Collector is converted to the other, so that
+// converting it back restores it as it was.
+const (
+	// ClusterAnnotation keeps a Spec.Cluster that isn't one of the v1beta1 environments.
+	ClusterAnnotation = "conversion." + GroupName + "/v1alpha-cluster"
+	// ConfigurationAnnotation keeps Spec.Collector.Configuration as it was written, its base64 YAML doesn't
+	// survive the round trip through the v1beta1 values object byte for byte.
+	ConfigurationAnnotation = "conversion." + GroupName + "/v1alpha-configuration"
+)
+
+// ConvertTo converts the Collector to the v1beta1 storage version.
+func (c *Collector) ConvertTo(dst *v1beta1.Collector) error {
+	dst.ObjectMeta = *c.ObjectMeta.DeepCopy()
+	dst.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind(Kind))
+
+	annotations := dst.Annotations
+	for _, key := range []string{ClusterAnnotation, ConfigurationAnnotation} {
+		delete(annotations, key)
+	}
+
+	dst.Spec = v1beta1.CollectorSpec{
+		Collector: v1beta1.CollectorInfo{
+			Name:     c.Spec.Collector.Name,
+			Instance: c.Spec.Tenant.Instance,
+			Version:  c.Spec.Collector.Version,
+		},
+		Tenant: v1beta1.TenantInfo{
+			ID:        c.Spec.Tenant.ID,
+			Namespace: c.Spec.Tenant.Reference,
+			Name:      c.Spec.Tenant.Name,
+		},
+		DriftPolicy: v1beta1.DriftPolicy(c.Spec.DriftPolicy),
+	}
+
+	switch environment := v1beta1.Environment(c.Spec.Cluster); environment {
+	case "", v1beta1.EnvironmentDevelopment, v1beta1.EnvironmentStaging, v1beta1.EnvironmentProduction:
+		dst.Spec.Environment = environment
+	default:
+		annotations = setAnnotation(annotations, ClusterAnnotation, c.Spec.Cluster)
+	}
+
+	if c.Spec.ChartSource != "" {
+		dst.Spec.ChartSource = &v1beta1.ChartSource{Name: c.Spec.ChartSource}
+	}
+
+	configuration := &v1beta1.Configuration{}
+
+	if c.Spec.Collector.Configuration != "" {
+		// Configuration that doesn't decode to an object has no v1beta1 values, it is only kept in the annotation
+		configuration.Values, _ = decodeConfiguration(c.Spec.Collector.Configuration)
+
+		if encoded, err := encodeConfiguration(configuration.Values); err != nil || encoded != c.Spec.Collector.Configuration {
+			annotations = setAnnotation(annotations, ConfigurationAnnotation, c.Spec.Collector.Configuration)
+		}
+	}
+
+	for _, source := range c.Spec.Collector.ConfigurationFrom {
+		configuration.From = append(configuration.From, v1beta1.ConfigurationSource(source))
+	}
+
+	if configuration.Values != nil || len(configuration.From) > 0 {
+		dst.Spec.Configuration = configuration
+	}
+
+	dst.SetAnnotations(emptyToNil(annotations))
+
+	dst.Status = v1beta1.CollectorStatus{
+		Conditions:         c.Status.Conditions,
+		ObservedGeneration: c.Status.ObservedGeneration,
+		ChartVersion:       c.Status.ChartVersion,
+		HelmRevision:       c.Status.HelmRevision,
+		ConfigurationHash:  c.Status.ConfigurationHash,
+		LastReconcileTime:  c.Status.LastReconcileTime,
+		FailedRevision:     c.Status.FailedRevision,
+		RestoredRevision:   c.Status.RestoredRevision,
+		ReadyReplicas:      c.Status.ReadyReplicas,
+		DesiredReplicas:    c.Status.DesiredReplicas,
+		LastPodFailure:     c.Status.LastPodFailure,
+	}
+
+	for _, workload := range c.Status.Workloads {
+		dst.Status.Workloads = append(dst.Status.Workloads, v1beta1.WorkloadStatus(workload))
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the v1beta1 storage version to the Collector.
+func (c *Collector) ConvertFrom(src *v1beta1.Collector) error {
+	c.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	c.SetGroupVersionKind(SchemeGroupVersion.WithKind(Kind))
+
+	annotations := c.Annotations
+	for _, key := range []string{ClusterAnnotation, ConfigurationAnnotation} {
+		delete(annotations, key)
+	}
+
+	c.Spec = CollectorSpec{
+		Collector: CollectorInfo{
+			Name:    src.Spec.Collector.Name,
+			Version: src.Spec.Collector.Version,
+		},
+		Tenant: TenantInfo{
+			ID:        src.Spec.Tenant.ID,
+			Reference: src.Spec.Tenant.Namespace,
+			Instance:  src.Spec.Collector.Instance,
+			Name:      src.Spec.Tenant.Name,
+		},
+		Cluster:     string(src.Spec.Environment),
+		DriftPolicy: DriftPolicy(src.Spec.DriftPolicy),
+	}
+
+	if cluster, ok := src.Annotations[ClusterAnnotation]; ok && src.Spec.Environment == "" {
+		c.Spec.Cluster = cluster
+	}
+
+	if src.Spec.ChartSource != nil {
+		c.Spec.ChartSource = src.Spec.ChartSource.Name
+	}
+
+	var values *apiextensionsv1.JSON
+
+	if src.Spec.Configuration != nil {
+		values = src.Spec.Configuration.Values
+
+		for _, source := range src.Spec.Configuration.From {
+			c.Spec.Collector.ConfigurationFrom = append(c.Spec.Collector.ConfigurationFrom, ConfigurationSource(source))
+		}
+	}
+
+	// The configuration as it was written is used as long as the values weren't changed since
+	original, ok := src.Annotations[ConfigurationAnnotation]
+	if originalValues, _ := decodeConfiguration(original); ok && equalValues(originalValues, values) {
+		c.Spec.Collector.Configuration = original
+	} else {
+		configuration, err := encodeConfiguration(values)
+		if err != nil {
+			return err
+		}
+
+		c.Spec.Collector.Configuration = configuration
+	}
+
+	c.SetAnnotations(emptyToNil(annotations))
+
+	c.Status = CollectorStatus{
+		Conditions:         src.Status.Conditions,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		ChartVersion:       src.Status.ChartVersion,
+		HelmRevision:       src.Status.HelmRevision,
+		ConfigurationHash:  src.Status.ConfigurationHash,
+		LastReconcileTime:  src.Status.LastReconcileTime,
+		FailedRevision:     src.Status.FailedRevision,
+		RestoredRevision:   src.Status.RestoredRevision,
+		ReadyReplicas:      src.Status.ReadyReplicas,
+		DesiredReplicas:    src.Status.DesiredReplicas,
+		LastPodFailure:     src.Status.LastPodFailure,
+	}
+
+	for _, workload := range src.Status.Workloads {
+		c.Status.Workloads = append(c.Status.Workloads, WorkloadStatus(workload))
+	}
+
+	return nil
+}
+
+// decodeConfiguration decodes base64 encoded YAML values to a JSON object.
+func decodeConfiguration(configuration string) (*apiextensionsv1.JSON, error) {
+	decoded, err := base64.StdEncoding.DecodeString(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := yaml.YAMLToJSON(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(values), []byte("{")) {
+		return nil, errors.New("configuration is not an object")
+	}
+
+	return &apiextensionsv1.JSON{Raw: values}, nil
+}
+
+// encodeConfiguration encodes the values as base64 encoded YAML.
+func encodeConfiguration(values *apiextensionsv1.JSON) (string, error) {
+	if values == nil || len(values.Raw) == 0 {
+		return "", nil
+	}
+
+	decoded, err := yaml.JSONToYAML(values.Raw)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(decoded), nil
+}
+
+// equalValues reports whether the values hold the same object, whatever their formatting.
+func equalValues(a *apiextensionsv1.JSON, b *apiextensionsv1.JSON) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	var objectA, objectB interface{}
+	if json.Unmarshal(a.Raw, &objectA) != nil || json.Unmarshal(b.Raw, &objectB) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(objectA, objectB)
+}
+
+func setAnnotation(annotations map[string]string, key string, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[key] = value
+
+	return annotations
+}
+
+func emptyToNil(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	return annotations
+}
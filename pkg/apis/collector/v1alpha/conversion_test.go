@@ -0,0 +1,233 @@
+package v1alpha
+
+import (
+	"encoding/base64"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kube8-operator/pkg/apis/collector/v1beta1"
+)
+
+func encode(configuration string) string {
+	return base64.StdEncoding.EncodeToString([]byte(configuration))
+}
+
+func TestCollectorRoundTrip(t *testing.T) {
+	reconciled := metav1.Now()
+
+	tests := []struct {
+		name      string
+		collector Collector
+		// annotations are the annotations expected on the v1beta1 Collector
+		annotations map[string]string
+	}{
+		{
+			name: "environment cluster and canonical configuration",
+			collector: Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme", Labels: map[string]string{"team": "a"}},
+				Spec: CollectorSpec{
+					Collector: CollectorInfo{
+						Name:          "fluent-bit",
+						Version:       "~1.4",
+						Configuration: encode("output:\n  host: logs.example.com\n"),
+						ConfigurationFrom: []ConfigurationSource{
+							{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "outputs"}, Key: "values.yaml"}},
+						},
+					},
+					Tenant:      TenantInfo{ID: "t-1", Reference: "acme", Instance: "primary", Name: "acme"},
+					Cluster:     "production",
+					ChartSource: "mirror",
+					DriftPolicy: DriftPolicyCorrect,
+				},
+				Status: CollectorStatus{
+					Conditions:        []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Installed"}},
+					ChartVersion:      "1.4.3",
+					HelmRevision:      3,
+					LastReconcileTime: &reconciled,
+					Workloads:         []WorkloadStatus{{Kind: "DaemonSet", Name: "fluent-bit", Ready: true}},
+				},
+			},
+		},
+		{
+			name: "cluster that isn't an environment",
+			collector: Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"},
+				Spec:       CollectorSpec{Collector: CollectorInfo{Name: "fluent-bit"}, Tenant: TenantInfo{Reference: "acme"}, Cluster: "eu-west-1"},
+			},
+			annotations: map[string]string{ClusterAnnotation: "eu-west-1"},
+		},
+		{
+			name: "configuration that isn't canonical",
+			collector: Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme", Annotations: map[string]string{"owner": "platform"}},
+				Spec: CollectorSpec{
+					Collector: CollectorInfo{Name: "fluent-bit", Configuration: encode("# outputs\noutput: {host: logs.example.com}\n")},
+					Tenant:    TenantInfo{Reference: "acme"},
+					Cluster:   "staging",
+				},
+			},
+			annotations: map[string]string{"owner": "platform", ConfigurationAnnotation: encode("# outputs\noutput: {host: logs.example.com}\n")},
+		},
+		{
+			name: "configuration that isn't an object",
+			collector: Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"},
+				Spec:       CollectorSpec{Collector: CollectorInfo{Name: "fluent-bit", Configuration: encode("- a\n- b\n")}, Tenant: TenantInfo{Reference: "acme"}},
+			},
+			annotations: map[string]string{ConfigurationAnnotation: encode("- a\n- b\n")},
+		},
+		{
+			name: "configuration that isn't base64",
+			collector: Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"},
+				Spec:       CollectorSpec{Collector: CollectorInfo{Name: "fluent-bit", Configuration: "output: {}"}, Tenant: TenantInfo{Reference: "acme"}},
+			},
+			annotations: map[string]string{ConfigurationAnnotation: "output: {}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &v1beta1.Collector{}
+			if err := tt.collector.ConvertTo(stored); err != nil {
+				t.Fatalf("ConvertTo() error = %v", err)
+			}
+
+			if !equality.Semantic.DeepEqual(stored.Annotations, tt.annotations) {
+				t.Errorf("v1beta1 annotations = %v, want %v", stored.Annotations, tt.annotations)
+			}
+
+			converted := &Collector{}
+			if err := converted.ConvertFrom(stored); err != nil {
+				t.Fatalf("ConvertFrom() error = %v", err)
+			}
+
+			tt.collector.SetGroupVersionKind(SchemeGroupVersion.WithKind(Kind))
+
+			if !equality.Semantic.DeepEqual(converted, &tt.collector) {
+				t.Errorf("round trip = %+v, want %+v", converted, &tt.collector)
+			}
+		})
+	}
+}
+
+func TestV1beta1CollectorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		collector v1beta1.Collector
+	}{
+		{
+			name: "values and sources",
+			collector: v1beta1.Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"},
+				Spec: v1beta1.CollectorSpec{
+					Collector:   v1beta1.CollectorInfo{Name: "fluent-bit", Instance: "primary", Version: "1.4.3"},
+					Tenant:      v1beta1.TenantInfo{ID: "t-1", Namespace: "acme", Name: "acme"},
+					Environment: v1beta1.EnvironmentDevelopment,
+					Configuration: &v1beta1.Configuration{
+						Values: &apiextensionsv1.JSON{Raw: []byte(`{"output":{"host":"logs.example.com","port":24224}}`)},
+						From: []v1beta1.ConfigurationSource{
+							{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}, Key: "values.yaml"}},
+						},
+					},
+					ChartSource: &v1beta1.ChartSource{Name: "mirror"},
+				},
+			},
+		},
+		{
+			name: "sources only",
+			collector: v1beta1.Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"},
+				Spec: v1beta1.CollectorSpec{
+					Collector: v1beta1.CollectorInfo{Name: "fluent-bit"},
+					Tenant:    v1beta1.TenantInfo{Namespace: "acme"},
+					Configuration: &v1beta1.Configuration{
+						From: []v1beta1.ConfigurationSource{
+							{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "outputs"}, Key: "values.yaml"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "no configuration",
+			collector: v1beta1.Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "acme"},
+				Spec: v1beta1.CollectorSpec{
+					Collector:   v1beta1.CollectorInfo{Name: "fluent-bit"},
+					Tenant:      v1beta1.TenantInfo{Namespace: "acme"},
+					Environment: v1beta1.EnvironmentStaging,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &Collector{}
+			if err := collector.ConvertFrom(&tt.collector); err != nil {
+				t.Fatalf("ConvertFrom() error = %v", err)
+			}
+
+			converted := &v1beta1.Collector{}
+			if err := collector.ConvertTo(converted); err != nil {
+				t.Fatalf("ConvertTo() error = %v", err)
+			}
+
+			tt.collector.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind(Kind))
+
+			if !equality.Semantic.DeepEqual(converted, &tt.collector) {
+				t.Errorf("round trip = %+v, want %+v", converted, &tt.collector)
+			}
+		})
+	}
+}
+
+func TestConvertFromChangedValues(t *testing.T) {
+	original := encode("# outputs\noutput: {host: logs.example.com}\n")
+
+	tests := []struct {
+		name              string
+		environment       v1beta1.Environment
+		values            string
+		wantCluster       string
+		wantConfiguration string
+	}{
+		{name: "unchanged values keep the original", values: `{"output":{"host":"logs.example.com"}}`, wantCluster: "eu-west-1", wantConfiguration: original},
+		{name: "changed values are encoded again", values: `{"output":{"host":"other.example.com"}}`, wantCluster: "eu-west-1", wantConfiguration: encode("output:\n  host: other.example.com\n")},
+		{name: "an environment replaces the kept cluster", environment: v1beta1.EnvironmentProduction, values: `{"output":{"host":"logs.example.com"}}`, wantCluster: "production", wantConfiguration: original},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &v1beta1.Collector{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs", Annotations: map[string]string{ClusterAnnotation: "eu-west-1", ConfigurationAnnotation: original}},
+				Spec: v1beta1.CollectorSpec{
+					Environment:   tt.environment,
+					Configuration: &v1beta1.Configuration{Values: &apiextensionsv1.JSON{Raw: []byte(tt.values)}},
+				},
+			}
+
+			collector := &Collector{}
+			if err := collector.ConvertFrom(stored); err != nil {
+				t.Fatalf("ConvertFrom() error = %v", err)
+			}
+
+			if collector.Spec.Cluster != tt.wantCluster {
+				t.Errorf("cluster = %q, want %q", collector.Spec.Cluster, tt.wantCluster)
+			}
+
+			if collector.Spec.Collector.Configuration != tt.wantConfiguration {
+				t.Errorf("configuration = %q, want %q", collector.Spec.Collector.Configuration, tt.wantConfiguration)
+			}
+
+			if len(collector.Annotations) != 0 {
+				t.Errorf("conversion annotations are left on the Collector: %v", collector.Annotations)
+			}
+		})
+	}
+}
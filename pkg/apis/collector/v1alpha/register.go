@@ -30,6 +30,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Collector{},
 		&CollectorList{},
+		&Tenant{},
+		&TenantList{},
+		&CollectorSet{},
+		&CollectorSetList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 
@@ -0,0 +1,91 @@
+package v1alpha
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TenantKind     string = "Tenant"
+	TenantPlural   string = "tenants"
+	TenantSingular string = "tenant"
+	TenantName     string = TenantPlural + "." + GroupName
+)
+
+// TenantSpec defines the desired state of Tenant.
+type TenantSpec struct {
+	// ID is the tenant ID of the customer.
+	// +optional
+	ID string `json:"id,omitempty"`
+	// Namespace is the namespace provisioned for the tenant, which its collectors are deployed to. It defaults
+	// to the name of the Tenant and can't be changed. The kube-* namespaces and default are reserved.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="namespace is immutable"
+	// +kubebuilder:validation:XValidation:rule="!self.startsWith('kube-') && self != 'default'",message="namespace is reserved"
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// ResourceQuota is the hard limits of the ResourceQuota of the tenant namespace, there is none when empty.
+	// +optional
+	ResourceQuota corev1.ResourceList `json:"resourceQuota,omitempty"`
+	// LimitRange is the limits of the LimitRange of the tenant namespace, there is none when empty.
+	// +optional
+	LimitRange []corev1.LimitRangeItem `json:"limitRange,omitempty"`
+	// NetworkPolicy is the traffic allowed in and out of the tenant namespace, everything else is denied.
+	// +optional
+	NetworkPolicy TenantNetworkPolicy `json:"networkPolicy,omitempty"`
+	// Rules are the permissions of the tenant ServiceAccount in the tenant namespace.
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// TenantNetworkPolicy is the traffic the default-deny NetworkPolicy of a tenant namespace allows.
+type TenantNetworkPolicy struct {
+	// +optional
+	Ingress []networkingv1.NetworkPolicyIngressRule `json:"ingress,omitempty"`
+	// +optional
+	Egress []networkingv1.NetworkPolicyEgressRule `json:"egress,omitempty"`
+}
+
+// Tenant is the Schema for the tenant API, a customer whose collectors run in a namespace of their own.
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="ID",type=string,JSONPath=`.spec.id`
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.status.namespace`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:validation:XValidation:rule="(has(self.spec) && has(self.spec.__namespace__)) || !(self.metadata.name.startsWith('kube-') || self.metadata.name == 'default')",message="the name of a Tenant without a namespace is its namespace, which is reserved"
+type Tenant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TenantSpec   `json:"spec,omitempty"`
+	Status TenantStatus `json:"status,omitempty"`
+}
+
+// TenantList is a list of Tenant resources.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type TenantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Tenant `json:"items"`
+}
+
+// TenantStatus defines the observed state of Tenant.
+type TenantStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchMergeKey:"type" patchStrategy:"merge" protobuf:"bytes,1,rep,name=conditions"`
+	// ObservedGeneration is the metadata.generation of the Tenant the status was last reconciled from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Namespace is the namespace provisioned for the tenant.
+	Namespace string `json:"namespace,omitempty"`
+}
@@ -1,6 +1,7 @@
 package v1alpha
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -14,28 +15,91 @@ const (
 	Name      string = Plural + "." + GroupName
 )
 
+// CollectorInfo selects the collector chart and its configuration.
 type CollectorInfo struct {
-	Name          string `json:"name"`
-	Version       string `json:"version"`
+	// Name of the collector, it is the name of the chart to install.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Version is the chart version to install: an exact version (1.4.2), a semver constraint (~1.4, >=2.0 <3) or latest.
+	// +optional
+	Version string `json:"version"`
+	// Configuration is the base64 encoded YAML values of the collector chart.
+	// +optional
 	Configuration string `json:"configuration"`
+	// ConfigurationFrom are ConfigMap and Secret keys in the namespace of the Collector holding YAML chart values.
+	// They are merged in order, and Configuration is merged over them.
+	// +optional
+	ConfigurationFrom []ConfigurationSource `json:"configurationFrom,omitempty"`
 }
 
+// ConfigurationSource is a ConfigMap or a Secret key holding YAML chart values, exactly one of them is set.
+type ConfigurationSource struct {
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// TenantInfo identifies the tenant the collector is deployed for.
 type TenantInfo struct {
-	ID        string `json:"id"`
+	// ID is the tenant ID of the customer being deployed.
+	// +optional
+	ID string `json:"id"`
+	// Reference of the tenant, its lowercased form is the namespace the collector is deployed to, unless it is
+	// deployed for a Tenant.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
 	Reference string `json:"reference"`
-	Instance  string `json:"instance"`
+	// Instance of the tenant this collector is deployed for.
+	// +kubebuilder:validation:MinLength=1
+	Instance string `json:"instance"`
+	// Name of the Tenant the collector is deployed for. The collector isn't deployed until the Tenant is Ready,
+	// and is then deployed to the namespace provisioned for the Tenant.
+	// +optional
+	Name string `json:"name,omitempty"`
 }
 
+// DriftPolicy is what the operator does when the live objects of the collector release drift from the
+// manifest of the deployed Helm revision, e.g. after a kubectl edit.
+// +kubebuilder:validation:Enum=ignore;report;correct
+type DriftPolicy string
+
+const (
+	// DriftPolicyIgnore doesn't check the release for drift.
+	DriftPolicyIgnore DriftPolicy = "ignore"
+	// DriftPolicyReport reports drift in the Drifted condition and an Event.
+	DriftPolicyReport DriftPolicy = "report"
+	// DriftPolicyCorrect reports drift and re-applies the drifted objects from the manifest.
+	DriftPolicyCorrect DriftPolicy = "correct"
+)
+
 // CollectorSpec defines the desired state of Collector.
 type CollectorSpec struct {
 	Collector CollectorInfo `json:"collector"`
 	Tenant    TenantInfo    `json:"tenant"`
-	Cluster   string        `json:"cluster"`
+	// Cluster is the environment the collector runs in (gke-dev, gke-prod, etc), it selects the chart source.
+	// +optional
+	Cluster string `json:"cluster"`
+	// ChartSource is the name of the operator configured chart source to install the collector from.
+	// When empty, the chart source configured for the cluster is used.
+	ChartSource string `json:"chartSource,omitempty"`
+	// DriftPolicy is what the operator does when the live objects of the release drift from its manifest, report by default.
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
 }
 
+// Collector is the Schema for the collector API.
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=col
+// +kubebuilder:printcolumn:name="Collector",type=string,JSONPath=`.spec.collector.name`
+// +kubebuilder:printcolumn:name="Tenant",type=string,JSONPath=`.spec.tenant.reference`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.chartVersion`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type Collector struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -44,8 +108,9 @@ type Collector struct {
 	Status CollectorStatus `json:"status,omitempty"`
 }
 
-// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // CollectorList is a list of Collector resources.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
 type CollectorList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
@@ -56,5 +121,42 @@ type CollectorList struct {
 // CollectorStatus defines the observed state of Collector.
 type CollectorStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchMergeKey:"type" patchStrategy:"merge" protobuf:"bytes,1,rep,name=conditions"`
+	// ObservedGeneration is the metadata.generation of the Collector the status was last reconciled from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ChartVersion is the chart version Spec.Collector.Version resolved to when the collector was last installed or upgraded.
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// HelmRevision is the revision of the Helm release deployed for the collector.
+	HelmRevision int `json:"helmRevision,omitempty"`
+	// ConfigurationHash is the SHA-256 hash of the values HelmRevision was deployed with, merged from
+	// Configuration and ConfigurationFrom.
+	ConfigurationHash string `json:"configurationHash,omitempty"`
+	// LastReconcileTime is when the collector was last reconciled, successfully or not.
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+	// FailedRevision is the Helm revision of the last upgrade that failed and was rolled back.
+	FailedRevision int `json:"failedRevision,omitempty"`
+	// RestoredRevision is the Helm revision the release was rolled back to after FailedRevision failed.
+	RestoredRevision int `json:"restoredRevision,omitempty"`
+	// ReadyReplicas and DesiredReplicas are summed over the workloads of the release.
+	ReadyReplicas   int32 `json:"readyReplicas,omitempty"`
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// Workloads is the readiness of the Deployments, StatefulSets and DaemonSets of the release.
+	Workloads []WorkloadStatus `json:"workloads,omitempty"`
+	// LastPodFailure is why a pod of the collector last failed, e.g. CrashLoopBackOff. It is cleared once every workload is ready.
+	LastPodFailure string `json:"lastPodFailure,omitempty"`
+}
+
+// WorkloadStatus is the readiness of a Deployment, StatefulSet or DaemonSet of the collector release.
+type WorkloadStatus struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	DesiredReplicas int32  `json:"desiredReplicas"`
+	ReadyReplicas   int32  `json:"readyReplicas"`
+	UpdatedReplicas int32  `json:"updatedReplicas"`
+	// Ready is true once the rollout of the current spec is complete and every desired replica is available.
+	Ready bool `json:"ready"`
+	// Message describes the rollout while the workload isn't ready.
+	Message string `json:"message,omitempty"`
 }
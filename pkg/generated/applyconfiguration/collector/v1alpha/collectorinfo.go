@@ -21,9 +21,10 @@ package v1alpha
 // CollectorInfoApplyConfiguration represents an declarative configuration of the CollectorInfo type for use
 // with apply.
 type CollectorInfoApplyConfiguration struct {
-	Name          *string `json:"name,omitempty"`
-	Version       *string `json:"version,omitempty"`
-	Configuration *string `json:"configuration,omitempty"`
+	Name              *string                                 `json:"name,omitempty"`
+	Version           *string                                 `json:"version,omitempty"`
+	Configuration     *string                                 `json:"configuration,omitempty"`
+	ConfigurationFrom []ConfigurationSourceApplyConfiguration `json:"configurationFrom,omitempty"`
 }
 
 // CollectorInfoApplyConfiguration constructs an declarative configuration of the CollectorInfo type for use with
@@ -55,3 +56,16 @@ func (b *CollectorInfoApplyConfiguration) WithConfiguration(value string) *Colle
 	b.Configuration = &value
 	return b
 }
+
+// WithConfigurationFrom adds the given value to the ConfigurationFrom field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the ConfigurationFrom field.
+func (b *CollectorInfoApplyConfiguration) WithConfigurationFrom(values ...*ConfigurationSourceApplyConfiguration) *CollectorInfoApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithConfigurationFrom")
+		}
+		b.ConfigurationFrom = append(b.ConfigurationFrom, *values[i])
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CollectorSetStatusApplyConfiguration represents an declarative configuration of the CollectorSetStatus type for use
+// with apply.
+type CollectorSetStatusApplyConfiguration struct {
+	Conditions          []v1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration  *int64         `json:"observedGeneration,omitempty"`
+	Tenants             *int32         `json:"tenants,omitempty"`
+	Collectors          *int32         `json:"collectors,omitempty"`
+	UpdatedCollectors   *int32         `json:"updatedCollectors,omitempty"`
+	AvailableCollectors *int32         `json:"availableCollectors,omitempty"`
+}
+
+// CollectorSetStatusApplyConfiguration constructs an declarative configuration of the CollectorSetStatus type for use with
+// apply.
+func CollectorSetStatus() *CollectorSetStatusApplyConfiguration {
+	return &CollectorSetStatusApplyConfiguration{}
+}
+
+// WithConditions adds the given value to the Conditions field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Conditions field.
+func (b *CollectorSetStatusApplyConfiguration) WithConditions(values ...v1.Condition) *CollectorSetStatusApplyConfiguration {
+	for i := range values {
+		b.Conditions = append(b.Conditions, values[i])
+	}
+	return b
+}
+
+// WithObservedGeneration sets the ObservedGeneration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ObservedGeneration field is set to the value of the last call.
+func (b *CollectorSetStatusApplyConfiguration) WithObservedGeneration(value int64) *CollectorSetStatusApplyConfiguration {
+	b.ObservedGeneration = &value
+	return b
+}
+
+// WithTenants sets the Tenants field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Tenants field is set to the value of the last call.
+func (b *CollectorSetStatusApplyConfiguration) WithTenants(value int32) *CollectorSetStatusApplyConfiguration {
+	b.Tenants = &value
+	return b
+}
+
+// WithCollectors sets the Collectors field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Collectors field is set to the value of the last call.
+func (b *CollectorSetStatusApplyConfiguration) WithCollectors(value int32) *CollectorSetStatusApplyConfiguration {
+	b.Collectors = &value
+	return b
+}
+
+// WithUpdatedCollectors sets the UpdatedCollectors field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the UpdatedCollectors field is set to the value of the last call.
+func (b *CollectorSetStatusApplyConfiguration) WithUpdatedCollectors(value int32) *CollectorSetStatusApplyConfiguration {
+	b.UpdatedCollectors = &value
+	return b
+}
+
+// WithAvailableCollectors sets the AvailableCollectors field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the AvailableCollectors field is set to the value of the last call.
+func (b *CollectorSetStatusApplyConfiguration) WithAvailableCollectors(value int32) *CollectorSetStatusApplyConfiguration {
+	b.AvailableCollectors = &value
+	return b
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// CollectorSetTenantApplyConfiguration represents an declarative configuration of the CollectorSetTenant type for use
+// with apply.
+type CollectorSetTenantApplyConfiguration struct {
+	Name   *string  `json:"name,omitempty"`
+	Values *v1.JSON `json:"values,omitempty"`
+}
+
+// CollectorSetTenantApplyConfiguration constructs an declarative configuration of the CollectorSetTenant type for use with
+// apply.
+func CollectorSetTenant() *CollectorSetTenantApplyConfiguration {
+	return &CollectorSetTenantApplyConfiguration{}
+}
+
+// WithName sets the Name field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Name field is set to the value of the last call.
+func (b *CollectorSetTenantApplyConfiguration) WithName(value string) *CollectorSetTenantApplyConfiguration {
+	b.Name = &value
+	return b
+}
+
+// WithValues sets the Values field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Values field is set to the value of the last call.
+func (b *CollectorSetTenantApplyConfiguration) WithValues(value v1.JSON) *CollectorSetTenantApplyConfiguration {
+	b.Values = &value
+	return b
+}
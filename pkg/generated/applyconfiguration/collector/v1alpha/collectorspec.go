@@ -18,12 +18,18 @@ limitations under the License.
 
 package v1alpha
 
+import (
+	collectorv1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
 // CollectorSpecApplyConfiguration represents an declarative configuration of the CollectorSpec type for use
 // with apply.
 type CollectorSpecApplyConfiguration struct {
-	Collector *CollectorInfoApplyConfiguration `json:"collector,omitempty"`
-	Tenant    *TenantInfoApplyConfiguration    `json:"tenant,omitempty"`
-	Cluster   *string                          `json:"cluster,omitempty"`
+	Collector   *CollectorInfoApplyConfiguration `json:"collector,omitempty"`
+	Tenant      *TenantInfoApplyConfiguration    `json:"tenant,omitempty"`
+	Cluster     *string                          `json:"cluster,omitempty"`
+	ChartSource *string                          `json:"chartSource,omitempty"`
+	DriftPolicy *collectorv1alpha.DriftPolicy    `json:"driftPolicy,omitempty"`
 }
 
 // CollectorSpecApplyConfiguration constructs an declarative configuration of the CollectorSpec type for use with
@@ -55,3 +61,19 @@ func (b *CollectorSpecApplyConfiguration) WithCluster(value string) *CollectorSp
 	b.Cluster = &value
 	return b
 }
+
+// WithChartSource sets the ChartSource field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ChartSource field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithChartSource(value string) *CollectorSpecApplyConfiguration {
+	b.ChartSource = &value
+	return b
+}
+
+// WithDriftPolicy sets the DriftPolicy field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DriftPolicy field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithDriftPolicy(value collectorv1alpha.DriftPolicy) *CollectorSpecApplyConfiguration {
+	b.DriftPolicy = &value
+	return b
+}
@@ -25,7 +25,18 @@ import (
 // CollectorStatusApplyConfiguration represents an declarative configuration of the CollectorStatus type for use
 // with apply.
 type CollectorStatusApplyConfiguration struct {
-	Conditions []v1.Condition `json:"conditions,omitempty"`
+	Conditions         []v1.Condition                     `json:"conditions,omitempty"`
+	ObservedGeneration *int64                             `json:"observedGeneration,omitempty"`
+	ChartVersion       *string                            `json:"chartVersion,omitempty"`
+	HelmRevision       *int                               `json:"helmRevision,omitempty"`
+	ConfigurationHash  *string                            `json:"configurationHash,omitempty"`
+	LastReconcileTime  *v1.Time                           `json:"lastReconcileTime,omitempty"`
+	FailedRevision     *int                               `json:"failedRevision,omitempty"`
+	RestoredRevision   *int                               `json:"restoredRevision,omitempty"`
+	ReadyReplicas      *int32                             `json:"readyReplicas,omitempty"`
+	DesiredReplicas    *int32                             `json:"desiredReplicas,omitempty"`
+	Workloads          []WorkloadStatusApplyConfiguration `json:"workloads,omitempty"`
+	LastPodFailure     *string                            `json:"lastPodFailure,omitempty"`
 }
 
 // CollectorStatusApplyConfiguration constructs an declarative configuration of the CollectorStatus type for use with
@@ -43,3 +54,96 @@ func (b *CollectorStatusApplyConfiguration) WithConditions(values ...v1.Conditio
 	}
 	return b
 }
+
+// WithObservedGeneration sets the ObservedGeneration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ObservedGeneration field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithObservedGeneration(value int64) *CollectorStatusApplyConfiguration {
+	b.ObservedGeneration = &value
+	return b
+}
+
+// WithChartVersion sets the ChartVersion field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ChartVersion field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithChartVersion(value string) *CollectorStatusApplyConfiguration {
+	b.ChartVersion = &value
+	return b
+}
+
+// WithHelmRevision sets the HelmRevision field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the HelmRevision field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithHelmRevision(value int) *CollectorStatusApplyConfiguration {
+	b.HelmRevision = &value
+	return b
+}
+
+// WithConfigurationHash sets the ConfigurationHash field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ConfigurationHash field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithConfigurationHash(value string) *CollectorStatusApplyConfiguration {
+	b.ConfigurationHash = &value
+	return b
+}
+
+// WithLastReconcileTime sets the LastReconcileTime field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the LastReconcileTime field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithLastReconcileTime(value v1.Time) *CollectorStatusApplyConfiguration {
+	b.LastReconcileTime = &value
+	return b
+}
+
+// WithFailedRevision sets the FailedRevision field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the FailedRevision field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithFailedRevision(value int) *CollectorStatusApplyConfiguration {
+	b.FailedRevision = &value
+	return b
+}
+
+// WithRestoredRevision sets the RestoredRevision field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the RestoredRevision field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithRestoredRevision(value int) *CollectorStatusApplyConfiguration {
+	b.RestoredRevision = &value
+	return b
+}
+
+// WithReadyReplicas sets the ReadyReplicas field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ReadyReplicas field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithReadyReplicas(value int32) *CollectorStatusApplyConfiguration {
+	b.ReadyReplicas = &value
+	return b
+}
+
+// WithDesiredReplicas sets the DesiredReplicas field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DesiredReplicas field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithDesiredReplicas(value int32) *CollectorStatusApplyConfiguration {
+	b.DesiredReplicas = &value
+	return b
+}
+
+// WithWorkloads adds the given value to the Workloads field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Workloads field.
+func (b *CollectorStatusApplyConfiguration) WithWorkloads(values ...*WorkloadStatusApplyConfiguration) *CollectorStatusApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithWorkloads")
+		}
+		b.Workloads = append(b.Workloads, *values[i])
+	}
+	return b
+}
+
+// WithLastPodFailure sets the LastPodFailure field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the LastPodFailure field is set to the value of the last call.
+func (b *CollectorStatusApplyConfiguration) WithLastPodFailure(value string) *CollectorStatusApplyConfiguration {
+	b.LastPodFailure = &value
+	return b
+}
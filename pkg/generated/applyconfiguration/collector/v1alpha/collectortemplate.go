@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha
+
+// CollectorTemplateApplyConfiguration represents an declarative configuration of the CollectorTemplate type for use
+// with apply.
+type CollectorTemplateApplyConfiguration struct {
+	Metadata *CollectorTemplateMetadataApplyConfiguration `json:"metadata,omitempty"`
+	Spec     *CollectorTemplateSpecApplyConfiguration     `json:"spec,omitempty"`
+}
+
+// CollectorTemplateApplyConfiguration constructs an declarative configuration of the CollectorTemplate type for use with
+// apply.
+func CollectorTemplate() *CollectorTemplateApplyConfiguration {
+	return &CollectorTemplateApplyConfiguration{}
+}
+
+// WithMetadata sets the Metadata field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Metadata field is set to the value of the last call.
+func (b *CollectorTemplateApplyConfiguration) WithMetadata(value *CollectorTemplateMetadataApplyConfiguration) *CollectorTemplateApplyConfiguration {
+	b.Metadata = value
+	return b
+}
+
+// WithSpec sets the Spec field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Spec field is set to the value of the last call.
+func (b *CollectorTemplateApplyConfiguration) WithSpec(value *CollectorTemplateSpecApplyConfiguration) *CollectorTemplateApplyConfiguration {
+	b.Spec = value
+	return b
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	collectorv1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+)
+
+// CollectorTemplateSpecApplyConfiguration represents an declarative configuration of the CollectorTemplateSpec type for use
+// with apply.
+type CollectorTemplateSpecApplyConfiguration struct {
+	Collector   *CollectorInfoApplyConfiguration `json:"collector,omitempty"`
+	Instance    *string                          `json:"instance,omitempty"`
+	Cluster     *string                          `json:"cluster,omitempty"`
+	ChartSource *string                          `json:"chartSource,omitempty"`
+	DriftPolicy *collectorv1alpha.DriftPolicy    `json:"driftPolicy,omitempty"`
+}
+
+// CollectorTemplateSpecApplyConfiguration constructs an declarative configuration of the CollectorTemplateSpec type for use with
+// apply.
+func CollectorTemplateSpec() *CollectorTemplateSpecApplyConfiguration {
+	return &CollectorTemplateSpecApplyConfiguration{}
+}
+
+// WithCollector sets the Collector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Collector field is set to the value of the last call.
+func (b *CollectorTemplateSpecApplyConfiguration) WithCollector(value *CollectorInfoApplyConfiguration) *CollectorTemplateSpecApplyConfiguration {
+	b.Collector = value
+	return b
+}
+
+// WithInstance sets the Instance field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Instance field is set to the value of the last call.
+func (b *CollectorTemplateSpecApplyConfiguration) WithInstance(value string) *CollectorTemplateSpecApplyConfiguration {
+	b.Instance = &value
+	return b
+}
+
+// WithCluster sets the Cluster field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Cluster field is set to the value of the last call.
+func (b *CollectorTemplateSpecApplyConfiguration) WithCluster(value string) *CollectorTemplateSpecApplyConfiguration {
+	b.Cluster = &value
+	return b
+}
+
+// WithChartSource sets the ChartSource field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ChartSource field is set to the value of the last call.
+func (b *CollectorTemplateSpecApplyConfiguration) WithChartSource(value string) *CollectorTemplateSpecApplyConfiguration {
+	b.ChartSource = &value
+	return b
+}
+
+// WithDriftPolicy sets the DriftPolicy field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DriftPolicy field is set to the value of the last call.
+func (b *CollectorTemplateSpecApplyConfiguration) WithDriftPolicy(value collectorv1alpha.DriftPolicy) *CollectorTemplateSpecApplyConfiguration {
+	b.DriftPolicy = &value
+	return b
+}
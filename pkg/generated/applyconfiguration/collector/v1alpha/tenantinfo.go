@@ -24,6 +24,7 @@ type TenantInfoApplyConfiguration struct {
 	ID        *string `json:"id,omitempty"`
 	Reference *string `json:"reference,omitempty"`
 	Instance  *string `json:"instance,omitempty"`
+	Name      *string `json:"name,omitempty"`
 }
 
 // TenantInfoApplyConfiguration constructs an declarative configuration of the TenantInfo type for use with
@@ -55,3 +56,11 @@ func (b *TenantInfoApplyConfiguration) WithInstance(value string) *TenantInfoApp
 	b.Instance = &value
 	return b
 }
+
+// WithName sets the Name field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Name field is set to the value of the last call.
+func (b *TenantInfoApplyConfiguration) WithName(value string) *TenantInfoApplyConfiguration {
+	b.Name = &value
+	return b
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	v1 "k8s.io/api/networking/v1"
+)
+
+// TenantNetworkPolicyApplyConfiguration represents an declarative configuration of the TenantNetworkPolicy type for use
+// with apply.
+type TenantNetworkPolicyApplyConfiguration struct {
+	Ingress []v1.NetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress  []v1.NetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+// TenantNetworkPolicyApplyConfiguration constructs an declarative configuration of the TenantNetworkPolicy type for use with
+// apply.
+func TenantNetworkPolicy() *TenantNetworkPolicyApplyConfiguration {
+	return &TenantNetworkPolicyApplyConfiguration{}
+}
+
+// WithIngress adds the given value to the Ingress field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Ingress field.
+func (b *TenantNetworkPolicyApplyConfiguration) WithIngress(values ...v1.NetworkPolicyIngressRule) *TenantNetworkPolicyApplyConfiguration {
+	for i := range values {
+		b.Ingress = append(b.Ingress, values[i])
+	}
+	return b
+}
+
+// WithEgress adds the given value to the Egress field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Egress field.
+func (b *TenantNetworkPolicyApplyConfiguration) WithEgress(values ...v1.NetworkPolicyEgressRule) *TenantNetworkPolicyApplyConfiguration {
+	for i := range values {
+		b.Egress = append(b.Egress, values[i])
+	}
+	return b
+}
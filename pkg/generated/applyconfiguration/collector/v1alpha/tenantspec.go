@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// TenantSpecApplyConfiguration represents an declarative configuration of the TenantSpec type for use
+// with apply.
+type TenantSpecApplyConfiguration struct {
+	ID            *string                                `json:"id,omitempty"`
+	Namespace     *string                                `json:"namespace,omitempty"`
+	ResourceQuota *v1.ResourceList                       `json:"resourceQuota,omitempty"`
+	LimitRange    []v1.LimitRangeItem                    `json:"limitRange,omitempty"`
+	NetworkPolicy *TenantNetworkPolicyApplyConfiguration `json:"networkPolicy,omitempty"`
+	Rules         []rbacv1.PolicyRule                    `json:"rules,omitempty"`
+}
+
+// TenantSpecApplyConfiguration constructs an declarative configuration of the TenantSpec type for use with
+// apply.
+func TenantSpec() *TenantSpecApplyConfiguration {
+	return &TenantSpecApplyConfiguration{}
+}
+
+// WithID sets the ID field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ID field is set to the value of the last call.
+func (b *TenantSpecApplyConfiguration) WithID(value string) *TenantSpecApplyConfiguration {
+	b.ID = &value
+	return b
+}
+
+// WithNamespace sets the Namespace field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Namespace field is set to the value of the last call.
+func (b *TenantSpecApplyConfiguration) WithNamespace(value string) *TenantSpecApplyConfiguration {
+	b.Namespace = &value
+	return b
+}
+
+// WithResourceQuota sets the ResourceQuota field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ResourceQuota field is set to the value of the last call.
+func (b *TenantSpecApplyConfiguration) WithResourceQuota(value v1.ResourceList) *TenantSpecApplyConfiguration {
+	b.ResourceQuota = &value
+	return b
+}
+
+// WithLimitRange adds the given value to the LimitRange field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the LimitRange field.
+func (b *TenantSpecApplyConfiguration) WithLimitRange(values ...v1.LimitRangeItem) *TenantSpecApplyConfiguration {
+	for i := range values {
+		b.LimitRange = append(b.LimitRange, values[i])
+	}
+	return b
+}
+
+// WithNetworkPolicy sets the NetworkPolicy field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the NetworkPolicy field is set to the value of the last call.
+func (b *TenantSpecApplyConfiguration) WithNetworkPolicy(value *TenantNetworkPolicyApplyConfiguration) *TenantSpecApplyConfiguration {
+	b.NetworkPolicy = value
+	return b
+}
+
+// WithRules adds the given value to the Rules field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Rules field.
+func (b *TenantSpecApplyConfiguration) WithRules(values ...rbacv1.PolicyRule) *TenantSpecApplyConfiguration {
+	for i := range values {
+		b.Rules = append(b.Rules, values[i])
+	}
+	return b
+}
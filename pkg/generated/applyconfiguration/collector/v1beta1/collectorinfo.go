@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1beta1
+
+// CollectorInfoApplyConfiguration represents an declarative configuration of the CollectorInfo type for use
+// with apply.
+type CollectorInfoApplyConfiguration struct {
+	Name     *string `json:"name,omitempty"`
+	Instance *string `json:"instance,omitempty"`
+	Version  *string `json:"version,omitempty"`
+}
+
+// CollectorInfoApplyConfiguration constructs an declarative configuration of the CollectorInfo type for use with
+// apply.
+func CollectorInfo() *CollectorInfoApplyConfiguration {
+	return &CollectorInfoApplyConfiguration{}
+}
+
+// WithName sets the Name field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Name field is set to the value of the last call.
+func (b *CollectorInfoApplyConfiguration) WithName(value string) *CollectorInfoApplyConfiguration {
+	b.Name = &value
+	return b
+}
+
+// WithInstance sets the Instance field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Instance field is set to the value of the last call.
+func (b *CollectorInfoApplyConfiguration) WithInstance(value string) *CollectorInfoApplyConfiguration {
+	b.Instance = &value
+	return b
+}
+
+// WithVersion sets the Version field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Version field is set to the value of the last call.
+func (b *CollectorInfoApplyConfiguration) WithVersion(value string) *CollectorInfoApplyConfiguration {
+	b.Version = &value
+	return b
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	collectorv1beta1 "kube8-operator/pkg/apis/collector/v1beta1"
+)
+
+// CollectorSpecApplyConfiguration represents an declarative configuration of the CollectorSpec type for use
+// with apply.
+type CollectorSpecApplyConfiguration struct {
+	Collector     *CollectorInfoApplyConfiguration `json:"collector,omitempty"`
+	Tenant        *TenantInfoApplyConfiguration    `json:"tenant,omitempty"`
+	Environment   *collectorv1beta1.Environment    `json:"environment,omitempty"`
+	Configuration *ConfigurationApplyConfiguration `json:"configuration,omitempty"`
+	ChartSource   *ChartSourceApplyConfiguration   `json:"chartSource,omitempty"`
+	DriftPolicy   *collectorv1beta1.DriftPolicy    `json:"driftPolicy,omitempty"`
+}
+
+// CollectorSpecApplyConfiguration constructs an declarative configuration of the CollectorSpec type for use with
+// apply.
+func CollectorSpec() *CollectorSpecApplyConfiguration {
+	return &CollectorSpecApplyConfiguration{}
+}
+
+// WithCollector sets the Collector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Collector field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithCollector(value *CollectorInfoApplyConfiguration) *CollectorSpecApplyConfiguration {
+	b.Collector = value
+	return b
+}
+
+// WithTenant sets the Tenant field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Tenant field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithTenant(value *TenantInfoApplyConfiguration) *CollectorSpecApplyConfiguration {
+	b.Tenant = value
+	return b
+}
+
+// WithEnvironment sets the Environment field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Environment field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithEnvironment(value collectorv1beta1.Environment) *CollectorSpecApplyConfiguration {
+	b.Environment = &value
+	return b
+}
+
+// WithConfiguration sets the Configuration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Configuration field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithConfiguration(value *ConfigurationApplyConfiguration) *CollectorSpecApplyConfiguration {
+	b.Configuration = value
+	return b
+}
+
+// WithChartSource sets the ChartSource field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ChartSource field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithChartSource(value *ChartSourceApplyConfiguration) *CollectorSpecApplyConfiguration {
+	b.ChartSource = value
+	return b
+}
+
+// WithDriftPolicy sets the DriftPolicy field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DriftPolicy field is set to the value of the last call.
+func (b *CollectorSpecApplyConfiguration) WithDriftPolicy(value collectorv1beta1.DriftPolicy) *CollectorSpecApplyConfiguration {
+	b.DriftPolicy = &value
+	return b
+}
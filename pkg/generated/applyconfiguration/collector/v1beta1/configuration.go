@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// ConfigurationApplyConfiguration represents an declarative configuration of the Configuration type for use
+// with apply.
+type ConfigurationApplyConfiguration struct {
+	Values *v1.JSON                                `json:"values,omitempty"`
+	From   []ConfigurationSourceApplyConfiguration `json:"from,omitempty"`
+}
+
+// ConfigurationApplyConfiguration constructs an declarative configuration of the Configuration type for use with
+// apply.
+func Configuration() *ConfigurationApplyConfiguration {
+	return &ConfigurationApplyConfiguration{}
+}
+
+// WithValues sets the Values field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Values field is set to the value of the last call.
+func (b *ConfigurationApplyConfiguration) WithValues(value v1.JSON) *ConfigurationApplyConfiguration {
+	b.Values = &value
+	return b
+}
+
+// WithFrom adds the given value to the From field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the From field.
+func (b *ConfigurationApplyConfiguration) WithFrom(values ...*ConfigurationSourceApplyConfiguration) *ConfigurationApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithFrom")
+		}
+		b.From = append(b.From, *values[i])
+	}
+	return b
+}
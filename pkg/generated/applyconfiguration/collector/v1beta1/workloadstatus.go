@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1beta1
+
+// WorkloadStatusApplyConfiguration represents an declarative configuration of the WorkloadStatus type for use
+// with apply.
+type WorkloadStatusApplyConfiguration struct {
+	Kind            *string `json:"kind,omitempty"`
+	Name            *string `json:"name,omitempty"`
+	DesiredReplicas *int32  `json:"desiredReplicas,omitempty"`
+	ReadyReplicas   *int32  `json:"readyReplicas,omitempty"`
+	UpdatedReplicas *int32  `json:"updatedReplicas,omitempty"`
+	Ready           *bool   `json:"ready,omitempty"`
+	Message         *string `json:"message,omitempty"`
+}
+
+// WorkloadStatusApplyConfiguration constructs an declarative configuration of the WorkloadStatus type for use with
+// apply.
+func WorkloadStatus() *WorkloadStatusApplyConfiguration {
+	return &WorkloadStatusApplyConfiguration{}
+}
+
+// WithKind sets the Kind field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Kind field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithKind(value string) *WorkloadStatusApplyConfiguration {
+	b.Kind = &value
+	return b
+}
+
+// WithName sets the Name field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Name field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithName(value string) *WorkloadStatusApplyConfiguration {
+	b.Name = &value
+	return b
+}
+
+// WithDesiredReplicas sets the DesiredReplicas field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DesiredReplicas field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithDesiredReplicas(value int32) *WorkloadStatusApplyConfiguration {
+	b.DesiredReplicas = &value
+	return b
+}
+
+// WithReadyReplicas sets the ReadyReplicas field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ReadyReplicas field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithReadyReplicas(value int32) *WorkloadStatusApplyConfiguration {
+	b.ReadyReplicas = &value
+	return b
+}
+
+// WithUpdatedReplicas sets the UpdatedReplicas field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the UpdatedReplicas field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithUpdatedReplicas(value int32) *WorkloadStatusApplyConfiguration {
+	b.UpdatedReplicas = &value
+	return b
+}
+
+// WithReady sets the Ready field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Ready field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithReady(value bool) *WorkloadStatusApplyConfiguration {
+	b.Ready = &value
+	return b
+}
+
+// WithMessage sets the Message field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Message field is set to the value of the last call.
+func (b *WorkloadStatusApplyConfiguration) WithMessage(value string) *WorkloadStatusApplyConfiguration {
+	b.Message = &value
+	return b
+}
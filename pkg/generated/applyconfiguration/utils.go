@@ -20,7 +20,9 @@ package applyconfiguration
 
 import (
 	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	v1beta1 "kube8-operator/pkg/apis/collector/v1beta1"
 	collectorv1alpha "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+	collectorv1beta1 "kube8-operator/pkg/generated/applyconfiguration/collector/v1beta1"
 
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -34,12 +36,58 @@ func ForKind(kind schema.GroupVersionKind) interface{} {
 		return &collectorv1alpha.CollectorApplyConfiguration{}
 	case v1alpha.SchemeGroupVersion.WithKind("CollectorInfo"):
 		return &collectorv1alpha.CollectorInfoApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorSet"):
+		return &collectorv1alpha.CollectorSetApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorSetSpec"):
+		return &collectorv1alpha.CollectorSetSpecApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorSetStatus"):
+		return &collectorv1alpha.CollectorSetStatusApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorSetTenant"):
+		return &collectorv1alpha.CollectorSetTenantApplyConfiguration{}
 	case v1alpha.SchemeGroupVersion.WithKind("CollectorSpec"):
 		return &collectorv1alpha.CollectorSpecApplyConfiguration{}
 	case v1alpha.SchemeGroupVersion.WithKind("CollectorStatus"):
 		return &collectorv1alpha.CollectorStatusApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorTemplate"):
+		return &collectorv1alpha.CollectorTemplateApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorTemplateMetadata"):
+		return &collectorv1alpha.CollectorTemplateMetadataApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("CollectorTemplateSpec"):
+		return &collectorv1alpha.CollectorTemplateSpecApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("ConfigurationSource"):
+		return &collectorv1alpha.ConfigurationSourceApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("Tenant"):
+		return &collectorv1alpha.TenantApplyConfiguration{}
 	case v1alpha.SchemeGroupVersion.WithKind("TenantInfo"):
 		return &collectorv1alpha.TenantInfoApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("TenantNetworkPolicy"):
+		return &collectorv1alpha.TenantNetworkPolicyApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("TenantSpec"):
+		return &collectorv1alpha.TenantSpecApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("TenantStatus"):
+		return &collectorv1alpha.TenantStatusApplyConfiguration{}
+	case v1alpha.SchemeGroupVersion.WithKind("WorkloadStatus"):
+		return &collectorv1alpha.WorkloadStatusApplyConfiguration{}
+
+		// Group=example.com, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithKind("ChartSource"):
+		return &collectorv1beta1.ChartSourceApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("Collector"):
+		return &collectorv1beta1.CollectorApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("CollectorInfo"):
+		return &collectorv1beta1.CollectorInfoApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("CollectorSpec"):
+		return &collectorv1beta1.CollectorSpecApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("CollectorStatus"):
+		return &collectorv1beta1.CollectorStatusApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("Configuration"):
+		return &collectorv1beta1.ConfigurationApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("ConfigurationSource"):
+		return &collectorv1beta1.ConfigurationSourceApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("TenantInfo"):
+		return &collectorv1beta1.TenantInfoApplyConfiguration{}
+	case v1beta1.SchemeGroupVersion.WithKind("WorkloadStatus"):
+		return &collectorv1beta1.WorkloadStatusApplyConfiguration{}
 
 	}
 	return nil
@@ -21,6 +21,7 @@ package versioned
 import (
 	"fmt"
 	examplev1alpha "kube8-operator/pkg/generated/clientset/versioned/typed/collector/v1alpha"
+	examplev1beta1 "kube8-operator/pkg/generated/clientset/versioned/typed/collector/v1beta1"
 	"net/http"
 
 	discovery "k8s.io/client-go/discovery"
@@ -31,12 +32,14 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	ExampleV1alpha() examplev1alpha.ExampleV1alphaInterface
+	ExampleV1beta1() examplev1beta1.ExampleV1beta1Interface
 }
 
 // Clientset contains the clients for groups.
 type Clientset struct {
 	*discovery.DiscoveryClient
 	exampleV1alpha *examplev1alpha.ExampleV1alphaClient
+	exampleV1beta1 *examplev1beta1.ExampleV1beta1Client
 }
 
 // ExampleV1alpha retrieves the ExampleV1alphaClient
@@ -44,6 +47,11 @@ func (c *Clientset) ExampleV1alpha() examplev1alpha.ExampleV1alphaInterface {
 	return c.exampleV1alpha
 }
 
+// ExampleV1beta1 retrieves the ExampleV1beta1Client
+func (c *Clientset) ExampleV1beta1() examplev1beta1.ExampleV1beta1Interface {
+	return c.exampleV1beta1
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -92,6 +100,10 @@ func NewForConfigAndClient(c *rest.Config, httpClient *http.Client) (*Clientset,
 	if err != nil {
 		return nil, err
 	}
+	cs.exampleV1beta1, err = examplev1beta1.NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
@@ -114,6 +126,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.exampleV1alpha = examplev1alpha.New(c)
+	cs.exampleV1beta1 = examplev1beta1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
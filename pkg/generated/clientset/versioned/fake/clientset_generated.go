@@ -22,6 +22,8 @@ import (
 	clientset "kube8-operator/pkg/generated/clientset/versioned"
 	examplev1alpha "kube8-operator/pkg/generated/clientset/versioned/typed/collector/v1alpha"
 	fakeexamplev1alpha "kube8-operator/pkg/generated/clientset/versioned/typed/collector/v1alpha/fake"
+	examplev1beta1 "kube8-operator/pkg/generated/clientset/versioned/typed/collector/v1beta1"
+	fakeexamplev1beta1 "kube8-operator/pkg/generated/clientset/versioned/typed/collector/v1beta1/fake"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -84,3 +86,8 @@ var (
 func (c *Clientset) ExampleV1alpha() examplev1alpha.ExampleV1alphaInterface {
 	return &fakeexamplev1alpha.FakeExampleV1alpha{Fake: &c.Fake}
 }
+
+// ExampleV1beta1 retrieves the ExampleV1beta1Client
+func (c *Clientset) ExampleV1beta1() examplev1beta1.ExampleV1beta1Interface {
+	return &fakeexamplev1beta1.FakeExampleV1beta1{Fake: &c.Fake}
+}
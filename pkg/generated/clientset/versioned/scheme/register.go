@@ -20,6 +20,7 @@ package scheme
 
 import (
 	examplev1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	examplev1beta1 "kube8-operator/pkg/apis/collector/v1beta1"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -33,6 +34,7 @@ var Codecs = serializer.NewCodecFactory(Scheme)
 var ParameterCodec = runtime.NewParameterCodec(Scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	examplev1alpha.AddToScheme,
+	examplev1beta1.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -29,6 +29,8 @@ import (
 type ExampleV1alphaInterface interface {
 	RESTClient() rest.Interface
 	CollectorsGetter
+	CollectorSetsGetter
+	TenantsGetter
 }
 
 // ExampleV1alphaClient is used to interact with features provided by the example.com group.
@@ -40,6 +42,14 @@ func (c *ExampleV1alphaClient) Collectors(namespace string) CollectorInterface {
 	return newCollectors(c, namespace)
 }
 
+func (c *ExampleV1alphaClient) CollectorSets(namespace string) CollectorSetInterface {
+	return newCollectorSets(c, namespace)
+}
+
+func (c *ExampleV1alphaClient) Tenants() TenantInterface {
+	return newTenants(c)
+}
+
 // NewForConfig creates a new ExampleV1alphaClient for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
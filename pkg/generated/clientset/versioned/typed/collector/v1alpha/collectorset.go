@@ -0,0 +1,256 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	collectorv1alpha "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+	scheme "kube8-operator/pkg/generated/clientset/versioned/scheme"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CollectorSetsGetter has a method to return a CollectorSetInterface.
+// A group's client should implement this interface.
+type CollectorSetsGetter interface {
+	CollectorSets(namespace string) CollectorSetInterface
+}
+
+// CollectorSetInterface has methods to work with CollectorSet resources.
+type CollectorSetInterface interface {
+	Create(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.CreateOptions) (*v1alpha.CollectorSet, error)
+	Update(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.UpdateOptions) (*v1alpha.CollectorSet, error)
+	UpdateStatus(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.UpdateOptions) (*v1alpha.CollectorSet, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha.CollectorSet, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha.CollectorSetList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha.CollectorSet, err error)
+	Apply(ctx context.Context, collectorSet *collectorv1alpha.CollectorSetApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.CollectorSet, err error)
+	ApplyStatus(ctx context.Context, collectorSet *collectorv1alpha.CollectorSetApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.CollectorSet, err error)
+	CollectorSetExpansion
+}
+
+// collectorSets implements CollectorSetInterface
+type collectorSets struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCollectorSets returns a CollectorSets
+func newCollectorSets(c *ExampleV1alphaClient, namespace string) *collectorSets {
+	return &collectorSets{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the collectorSet, and returns the corresponding collectorSet object, and an error if there is any.
+func (c *collectorSets) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha.CollectorSet, err error) {
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CollectorSets that match those selectors.
+func (c *collectorSets) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha.CollectorSetList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha.CollectorSetList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested collectorSets.
+func (c *collectorSets) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a collectorSet and creates it.  Returns the server's representation of the collectorSet, and an error, if there is any.
+func (c *collectorSets) Create(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.CreateOptions) (result *v1alpha.CollectorSet, err error) {
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(collectorSet).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a collectorSet and updates it. Returns the server's representation of the collectorSet, and an error, if there is any.
+func (c *collectorSets) Update(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.UpdateOptions) (result *v1alpha.CollectorSet, err error) {
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(collectorSet.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(collectorSet).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *collectorSets) UpdateStatus(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.UpdateOptions) (result *v1alpha.CollectorSet, err error) {
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(collectorSet.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(collectorSet).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the collectorSet and deletes it. Returns an error if one occurs.
+func (c *collectorSets) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *collectorSets) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("collectorsets").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched collectorSet.
+func (c *collectorSets) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha.CollectorSet, err error) {
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied collectorSet.
+func (c *collectorSets) Apply(ctx context.Context, collectorSet *collectorv1alpha.CollectorSetApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.CollectorSet, err error) {
+	if collectorSet == nil {
+		return nil, fmt.Errorf("collectorSet provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(collectorSet)
+	if err != nil {
+		return nil, err
+	}
+	name := collectorSet.Name
+	if name == nil {
+		return nil, fmt.Errorf("collectorSet.Name must be provided to Apply")
+	}
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(*name).
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *collectorSets) ApplyStatus(ctx context.Context, collectorSet *collectorv1alpha.CollectorSetApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.CollectorSet, err error) {
+	if collectorSet == nil {
+		return nil, fmt.Errorf("collectorSet provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(collectorSet)
+	if err != nil {
+		return nil, err
+	}
+
+	name := collectorSet.Name
+	if name == nil {
+		return nil, fmt.Errorf("collectorSet.Name must be provided to Apply")
+	}
+
+	result = &v1alpha.CollectorSet{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("collectorsets").
+		Name(*name).
+		SubResource("status").
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -33,6 +33,14 @@ func (c *FakeExampleV1alpha) Collectors(namespace string) v1alpha.CollectorInter
 	return &FakeCollectors{c, namespace}
 }
 
+func (c *FakeExampleV1alpha) CollectorSets(namespace string) v1alpha.CollectorSetInterface {
+	return &FakeCollectorSets{c, namespace}
+}
+
+func (c *FakeExampleV1alpha) Tenants() v1alpha.TenantInterface {
+	return &FakeTenants{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeExampleV1alpha) RESTClient() rest.Interface {
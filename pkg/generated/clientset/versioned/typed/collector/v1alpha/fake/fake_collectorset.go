@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	collectorv1alpha "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCollectorSets implements CollectorSetInterface
+type FakeCollectorSets struct {
+	Fake *FakeExampleV1alpha
+	ns   string
+}
+
+var collectorsetsResource = v1alpha.SchemeGroupVersion.WithResource("collectorsets")
+
+var collectorsetsKind = v1alpha.SchemeGroupVersion.WithKind("CollectorSet")
+
+// Get takes name of the collectorSet, and returns the corresponding collectorSet object, and an error if there is any.
+func (c *FakeCollectorSets) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha.CollectorSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(collectorsetsResource, c.ns, name), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
+
+// List takes label and field selectors, and returns the list of CollectorSets that match those selectors.
+func (c *FakeCollectorSets) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha.CollectorSetList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(collectorsetsResource, collectorsetsKind, c.ns, opts), &v1alpha.CollectorSetList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha.CollectorSetList{ListMeta: obj.(*v1alpha.CollectorSetList).ListMeta}
+	for _, item := range obj.(*v1alpha.CollectorSetList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested collectorSets.
+func (c *FakeCollectorSets) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(collectorsetsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a collectorSet and creates it.  Returns the server's representation of the collectorSet, and an error, if there is any.
+func (c *FakeCollectorSets) Create(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.CreateOptions) (result *v1alpha.CollectorSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(collectorsetsResource, c.ns, collectorSet), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
+
+// Update takes the representation of a collectorSet and updates it. Returns the server's representation of the collectorSet, and an error, if there is any.
+func (c *FakeCollectorSets) Update(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.UpdateOptions) (result *v1alpha.CollectorSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(collectorsetsResource, c.ns, collectorSet), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCollectorSets) UpdateStatus(ctx context.Context, collectorSet *v1alpha.CollectorSet, opts v1.UpdateOptions) (*v1alpha.CollectorSet, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(collectorsetsResource, "status", c.ns, collectorSet), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
+
+// Delete takes name of the collectorSet and deletes it. Returns an error if one occurs.
+func (c *FakeCollectorSets) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(collectorsetsResource, c.ns, name, opts), &v1alpha.CollectorSet{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCollectorSets) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(collectorsetsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha.CollectorSetList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched collectorSet.
+func (c *FakeCollectorSets) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha.CollectorSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(collectorsetsResource, c.ns, name, pt, data, subresources...), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied collectorSet.
+func (c *FakeCollectorSets) Apply(ctx context.Context, collectorSet *collectorv1alpha.CollectorSetApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.CollectorSet, err error) {
+	if collectorSet == nil {
+		return nil, fmt.Errorf("collectorSet provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(collectorSet)
+	if err != nil {
+		return nil, err
+	}
+	name := collectorSet.Name
+	if name == nil {
+		return nil, fmt.Errorf("collectorSet.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(collectorsetsResource, c.ns, *name, types.ApplyPatchType, data), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeCollectorSets) ApplyStatus(ctx context.Context, collectorSet *collectorv1alpha.CollectorSetApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.CollectorSet, err error) {
+	if collectorSet == nil {
+		return nil, fmt.Errorf("collectorSet provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(collectorSet)
+	if err != nil {
+		return nil, err
+	}
+	name := collectorSet.Name
+	if name == nil {
+		return nil, fmt.Errorf("collectorSet.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(collectorsetsResource, c.ns, *name, types.ApplyPatchType, data, "status"), &v1alpha.CollectorSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.CollectorSet), err
+}
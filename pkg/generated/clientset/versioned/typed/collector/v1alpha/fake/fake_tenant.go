@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	collectorv1alpha "kube8-operator/pkg/generated/applyconfiguration/collector/v1alpha"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeTenants implements TenantInterface
+type FakeTenants struct {
+	Fake *FakeExampleV1alpha
+}
+
+var tenantsResource = v1alpha.SchemeGroupVersion.WithResource("tenants")
+
+var tenantsKind = v1alpha.SchemeGroupVersion.WithKind("Tenant")
+
+// Get takes name of the tenant, and returns the corresponding tenant object, and an error if there is any.
+func (c *FakeTenants) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha.Tenant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(tenantsResource, name), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
+
+// List takes label and field selectors, and returns the list of Tenants that match those selectors.
+func (c *FakeTenants) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha.TenantList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(tenantsResource, tenantsKind, opts), &v1alpha.TenantList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha.TenantList{ListMeta: obj.(*v1alpha.TenantList).ListMeta}
+	for _, item := range obj.(*v1alpha.TenantList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested tenants.
+func (c *FakeTenants) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(tenantsResource, opts))
+}
+
+// Create takes the representation of a tenant and creates it.  Returns the server's representation of the tenant, and an error, if there is any.
+func (c *FakeTenants) Create(ctx context.Context, tenant *v1alpha.Tenant, opts v1.CreateOptions) (result *v1alpha.Tenant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(tenantsResource, tenant), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
+
+// Update takes the representation of a tenant and updates it. Returns the server's representation of the tenant, and an error, if there is any.
+func (c *FakeTenants) Update(ctx context.Context, tenant *v1alpha.Tenant, opts v1.UpdateOptions) (result *v1alpha.Tenant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(tenantsResource, tenant), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeTenants) UpdateStatus(ctx context.Context, tenant *v1alpha.Tenant, opts v1.UpdateOptions) (*v1alpha.Tenant, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(tenantsResource, "status", tenant), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
+
+// Delete takes name of the tenant and deletes it. Returns an error if one occurs.
+func (c *FakeTenants) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteActionWithOptions(tenantsResource, name, opts), &v1alpha.Tenant{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeTenants) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(tenantsResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha.TenantList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched tenant.
+func (c *FakeTenants) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha.Tenant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(tenantsResource, name, pt, data, subresources...), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied tenant.
+func (c *FakeTenants) Apply(ctx context.Context, tenant *collectorv1alpha.TenantApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.Tenant, err error) {
+	if tenant == nil {
+		return nil, fmt.Errorf("tenant provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(tenant)
+	if err != nil {
+		return nil, err
+	}
+	name := tenant.Name
+	if name == nil {
+		return nil, fmt.Errorf("tenant.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(tenantsResource, *name, types.ApplyPatchType, data), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeTenants) ApplyStatus(ctx context.Context, tenant *collectorv1alpha.TenantApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha.Tenant, err error) {
+	if tenant == nil {
+		return nil, fmt.Errorf("tenant provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(tenant)
+	if err != nil {
+		return nil, err
+	}
+	name := tenant.Name
+	if name == nil {
+		return nil, fmt.Errorf("tenant.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(tenantsResource, *name, types.ApplyPatchType, data, "status"), &v1alpha.Tenant{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha.Tenant), err
+}
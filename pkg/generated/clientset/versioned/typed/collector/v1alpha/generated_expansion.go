@@ -19,3 +19,7 @@ limitations under the License.
 package v1alpha
 
 type CollectorExpansion interface{}
+
+type CollectorSetExpansion interface{}
+
+type TenantExpansion interface{}
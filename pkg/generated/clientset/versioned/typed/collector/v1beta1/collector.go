@@ -0,0 +1,256 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	v1beta1 "kube8-operator/pkg/apis/collector/v1beta1"
+	collectorv1beta1 "kube8-operator/pkg/generated/applyconfiguration/collector/v1beta1"
+	scheme "kube8-operator/pkg/generated/clientset/versioned/scheme"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CollectorsGetter has a method to return a CollectorInterface.
+// A group's client should implement this interface.
+type CollectorsGetter interface {
+	Collectors(namespace string) CollectorInterface
+}
+
+// CollectorInterface has methods to work with Collector resources.
+type CollectorInterface interface {
+	Create(ctx context.Context, collector *v1beta1.Collector, opts v1.CreateOptions) (*v1beta1.Collector, error)
+	Update(ctx context.Context, collector *v1beta1.Collector, opts v1.UpdateOptions) (*v1beta1.Collector, error)
+	UpdateStatus(ctx context.Context, collector *v1beta1.Collector, opts v1.UpdateOptions) (*v1beta1.Collector, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1beta1.Collector, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1beta1.CollectorList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.Collector, err error)
+	Apply(ctx context.Context, collector *collectorv1beta1.CollectorApplyConfiguration, opts v1.ApplyOptions) (result *v1beta1.Collector, err error)
+	ApplyStatus(ctx context.Context, collector *collectorv1beta1.CollectorApplyConfiguration, opts v1.ApplyOptions) (result *v1beta1.Collector, err error)
+	CollectorExpansion
+}
+
+// collectors implements CollectorInterface
+type collectors struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCollectors returns a Collectors
+func newCollectors(c *ExampleV1beta1Client, namespace string) *collectors {
+	return &collectors{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the collector, and returns the corresponding collector object, and an error if there is any.
+func (c *collectors) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1beta1.Collector, err error) {
+	result = &v1beta1.Collector{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of Collectors that match those selectors.
+func (c *collectors) List(ctx context.Context, opts v1.ListOptions) (result *v1beta1.CollectorList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1beta1.CollectorList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("collectors").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested collectors.
+func (c *collectors) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("collectors").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a collector and creates it.  Returns the server's representation of the collector, and an error, if there is any.
+func (c *collectors) Create(ctx context.Context, collector *v1beta1.Collector, opts v1.CreateOptions) (result *v1beta1.Collector, err error) {
+	result = &v1beta1.Collector{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("collectors").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(collector).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a collector and updates it. Returns the server's representation of the collector, and an error, if there is any.
+func (c *collectors) Update(ctx context.Context, collector *v1beta1.Collector, opts v1.UpdateOptions) (result *v1beta1.Collector, err error) {
+	result = &v1beta1.Collector{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(collector.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(collector).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *collectors) UpdateStatus(ctx context.Context, collector *v1beta1.Collector, opts v1.UpdateOptions) (result *v1beta1.Collector, err error) {
+	result = &v1beta1.Collector{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(collector.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(collector).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the collector and deletes it. Returns an error if one occurs.
+func (c *collectors) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *collectors) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("collectors").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched collector.
+func (c *collectors) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.Collector, err error) {
+	result = &v1beta1.Collector{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied collector.
+func (c *collectors) Apply(ctx context.Context, collector *collectorv1beta1.CollectorApplyConfiguration, opts v1.ApplyOptions) (result *v1beta1.Collector, err error) {
+	if collector == nil {
+		return nil, fmt.Errorf("collector provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(collector)
+	if err != nil {
+		return nil, err
+	}
+	name := collector.Name
+	if name == nil {
+		return nil, fmt.Errorf("collector.Name must be provided to Apply")
+	}
+	result = &v1beta1.Collector{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(*name).
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *collectors) ApplyStatus(ctx context.Context, collector *collectorv1beta1.CollectorApplyConfiguration, opts v1.ApplyOptions) (result *v1beta1.Collector, err error) {
+	if collector == nil {
+		return nil, fmt.Errorf("collector provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(collector)
+	if err != nil {
+		return nil, err
+	}
+
+	name := collector.Name
+	if name == nil {
+		return nil, fmt.Errorf("collector.Name must be provided to Apply")
+	}
+
+	result = &v1beta1.Collector{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("collectors").
+		Name(*name).
+		SubResource("status").
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
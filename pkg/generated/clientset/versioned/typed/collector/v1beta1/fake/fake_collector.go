@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	v1beta1 "kube8-operator/pkg/apis/collector/v1beta1"
+	collectorv1beta1 "kube8-operator/pkg/generated/applyconfiguration/collector/v1beta1"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCollectors implements CollectorInterface
+type FakeCollectors struct {
+	Fake *FakeExampleV1beta1
+	ns   string
+}
+
+var collectorsResource = v1beta1.SchemeGroupVersion.WithResource("collectors")
+
+var collectorsKind = v1beta1.SchemeGroupVersion.WithKind("Collector")
+
+// Get takes name of the collector, and returns the corresponding collector object, and an error if there is any.
+func (c *FakeCollectors) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1beta1.Collector, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(collectorsResource, c.ns, name), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
+
+// List takes label and field selectors, and returns the list of Collectors that match those selectors.
+func (c *FakeCollectors) List(ctx context.Context, opts v1.ListOptions) (result *v1beta1.CollectorList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(collectorsResource, collectorsKind, c.ns, opts), &v1beta1.CollectorList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1beta1.CollectorList{ListMeta: obj.(*v1beta1.CollectorList).ListMeta}
+	for _, item := range obj.(*v1beta1.CollectorList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested collectors.
+func (c *FakeCollectors) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(collectorsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a collector and creates it.  Returns the server's representation of the collector, and an error, if there is any.
+func (c *FakeCollectors) Create(ctx context.Context, collector *v1beta1.Collector, opts v1.CreateOptions) (result *v1beta1.Collector, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(collectorsResource, c.ns, collector), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
+
+// Update takes the representation of a collector and updates it. Returns the server's representation of the collector, and an error, if there is any.
+func (c *FakeCollectors) Update(ctx context.Context, collector *v1beta1.Collector, opts v1.UpdateOptions) (result *v1beta1.Collector, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(collectorsResource, c.ns, collector), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCollectors) UpdateStatus(ctx context.Context, collector *v1beta1.Collector, opts v1.UpdateOptions) (*v1beta1.Collector, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(collectorsResource, "status", c.ns, collector), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
+
+// Delete takes name of the collector and deletes it. Returns an error if one occurs.
+func (c *FakeCollectors) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(collectorsResource, c.ns, name, opts), &v1beta1.Collector{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCollectors) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(collectorsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1beta1.CollectorList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched collector.
+func (c *FakeCollectors) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.Collector, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(collectorsResource, c.ns, name, pt, data, subresources...), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied collector.
+func (c *FakeCollectors) Apply(ctx context.Context, collector *collectorv1beta1.CollectorApplyConfiguration, opts v1.ApplyOptions) (result *v1beta1.Collector, err error) {
+	if collector == nil {
+		return nil, fmt.Errorf("collector provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(collector)
+	if err != nil {
+		return nil, err
+	}
+	name := collector.Name
+	if name == nil {
+		return nil, fmt.Errorf("collector.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(collectorsResource, c.ns, *name, types.ApplyPatchType, data), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeCollectors) ApplyStatus(ctx context.Context, collector *collectorv1beta1.CollectorApplyConfiguration, opts v1.ApplyOptions) (result *v1beta1.Collector, err error) {
+	if collector == nil {
+		return nil, fmt.Errorf("collector provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(collector)
+	if err != nil {
+		return nil, err
+	}
+	name := collector.Name
+	if name == nil {
+		return nil, fmt.Errorf("collector.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(collectorsResource, c.ns, *name, types.ApplyPatchType, data, "status"), &v1beta1.Collector{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Collector), err
+}
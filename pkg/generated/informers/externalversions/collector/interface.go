@@ -20,6 +20,7 @@ package collector
 
 import (
 	v1alpha "kube8-operator/pkg/generated/informers/externalversions/collector/v1alpha"
+	v1beta1 "kube8-operator/pkg/generated/informers/externalversions/collector/v1beta1"
 	internalinterfaces "kube8-operator/pkg/generated/informers/externalversions/internalinterfaces"
 )
 
@@ -27,6 +28,8 @@ import (
 type Interface interface {
 	// V1alpha provides access to shared informers for resources in V1alpha.
 	V1alpha() v1alpha.Interface
+	// V1beta1 provides access to shared informers for resources in V1beta1.
+	V1beta1() v1beta1.Interface
 }
 
 type group struct {
@@ -44,3 +47,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (g *group) V1alpha() v1alpha.Interface {
 	return v1alpha.New(g.factory, g.namespace, g.tweakListOptions)
 }
+
+// V1beta1 returns a new v1beta1.Interface.
+func (g *group) V1beta1() v1beta1.Interface {
+	return v1beta1.New(g.factory, g.namespace, g.tweakListOptions)
+}
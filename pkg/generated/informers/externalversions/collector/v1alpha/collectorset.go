@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	"context"
+	collectorv1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	versioned "kube8-operator/pkg/generated/clientset/versioned"
+	internalinterfaces "kube8-operator/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha "kube8-operator/pkg/generated/listers/collector/v1alpha"
+	time "time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// CollectorSetInformer provides access to a shared informer and lister for
+// CollectorSets.
+type CollectorSetInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha.CollectorSetLister
+}
+
+type collectorSetInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewCollectorSetInformer constructs a new informer for CollectorSet type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewCollectorSetInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredCollectorSetInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredCollectorSetInformer constructs a new informer for CollectorSet type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredCollectorSetInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ExampleV1alpha().CollectorSets(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ExampleV1alpha().CollectorSets(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&collectorv1alpha.CollectorSet{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *collectorSetInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredCollectorSetInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *collectorSetInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&collectorv1alpha.CollectorSet{}, f.defaultInformer)
+}
+
+func (f *collectorSetInformer) Lister() v1alpha.CollectorSetLister {
+	return v1alpha.NewCollectorSetLister(f.Informer().GetIndexer())
+}
@@ -26,6 +26,10 @@ import (
 type Interface interface {
 	// Collectors returns a CollectorInformer.
 	Collectors() CollectorInformer
+	// CollectorSets returns a CollectorSetInformer.
+	CollectorSets() CollectorSetInformer
+	// Tenants returns a TenantInformer.
+	Tenants() TenantInformer
 }
 
 type version struct {
@@ -43,3 +47,13 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) Collectors() CollectorInformer {
 	return &collectorInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// CollectorSets returns a CollectorSetInformer.
+func (v *version) CollectorSets() CollectorSetInformer {
+	return &collectorSetInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// Tenants returns a TenantInformer.
+func (v *version) Tenants() TenantInformer {
+	return &tenantInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -21,6 +21,7 @@ package externalversions
 import (
 	"fmt"
 	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+	v1beta1 "kube8-operator/pkg/apis/collector/v1beta1"
 
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
@@ -55,6 +56,14 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=example.com, Version=v1alpha
 	case v1alpha.SchemeGroupVersion.WithResource("collectors"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Example().V1alpha().Collectors().Informer()}, nil
+	case v1alpha.SchemeGroupVersion.WithResource("collectorsets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Example().V1alpha().CollectorSets().Informer()}, nil
+	case v1alpha.SchemeGroupVersion.WithResource("tenants"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Example().V1alpha().Tenants().Informer()}, nil
+
+		// Group=example.com, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("collectors"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Example().V1beta1().Collectors().Informer()}, nil
 
 	}
 
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CollectorSetLister helps list CollectorSets.
+// All objects returned here must be treated as read-only.
+type CollectorSetLister interface {
+	// List lists all CollectorSets in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha.CollectorSet, err error)
+	// CollectorSets returns an object that can list and get CollectorSets.
+	CollectorSets(namespace string) CollectorSetNamespaceLister
+	CollectorSetListerExpansion
+}
+
+// collectorSetLister implements the CollectorSetLister interface.
+type collectorSetLister struct {
+	indexer cache.Indexer
+}
+
+// NewCollectorSetLister returns a new CollectorSetLister.
+func NewCollectorSetLister(indexer cache.Indexer) CollectorSetLister {
+	return &collectorSetLister{indexer: indexer}
+}
+
+// List lists all CollectorSets in the indexer.
+func (s *collectorSetLister) List(selector labels.Selector) (ret []*v1alpha.CollectorSet, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha.CollectorSet))
+	})
+	return ret, err
+}
+
+// CollectorSets returns an object that can list and get CollectorSets.
+func (s *collectorSetLister) CollectorSets(namespace string) CollectorSetNamespaceLister {
+	return collectorSetNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CollectorSetNamespaceLister helps list and get CollectorSets.
+// All objects returned here must be treated as read-only.
+type CollectorSetNamespaceLister interface {
+	// List lists all CollectorSets in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha.CollectorSet, err error)
+	// Get retrieves the CollectorSet from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha.CollectorSet, error)
+	CollectorSetNamespaceListerExpansion
+}
+
+// collectorSetNamespaceLister implements the CollectorSetNamespaceLister
+// interface.
+type collectorSetNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CollectorSets in the indexer for a given namespace.
+func (s collectorSetNamespaceLister) List(selector labels.Selector) (ret []*v1alpha.CollectorSet, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha.CollectorSet))
+	})
+	return ret, err
+}
+
+// Get retrieves the CollectorSet from the indexer for a given namespace and name.
+func (s collectorSetNamespaceLister) Get(name string) (*v1alpha.CollectorSet, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha.Resource("collectorset"), name)
+	}
+	return obj.(*v1alpha.CollectorSet), nil
+}
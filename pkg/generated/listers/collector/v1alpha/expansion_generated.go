@@ -25,3 +25,15 @@ type CollectorListerExpansion interface{}
 // CollectorNamespaceListerExpansion allows custom methods to be added to
 // CollectorNamespaceLister.
 type CollectorNamespaceListerExpansion interface{}
+
+// CollectorSetListerExpansion allows custom methods to be added to
+// CollectorSetLister.
+type CollectorSetListerExpansion interface{}
+
+// CollectorSetNamespaceListerExpansion allows custom methods to be added to
+// CollectorSetNamespaceLister.
+type CollectorSetNamespaceListerExpansion interface{}
+
+// TenantListerExpansion allows custom methods to be added to
+// TenantLister.
+type TenantListerExpansion interface{}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes collector-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha
+
+import (
+	v1alpha "kube8-operator/pkg/apis/collector/v1alpha"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TenantLister helps list Tenants.
+// All objects returned here must be treated as read-only.
+type TenantLister interface {
+	// List lists all Tenants in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha.Tenant, err error)
+	// Get retrieves the Tenant from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha.Tenant, error)
+	TenantListerExpansion
+}
+
+// tenantLister implements the TenantLister interface.
+type tenantLister struct {
+	indexer cache.Indexer
+}
+
+// NewTenantLister returns a new TenantLister.
+func NewTenantLister(indexer cache.Indexer) TenantLister {
+	return &tenantLister{indexer: indexer}
+}
+
+// List lists all Tenants in the indexer.
+func (s *tenantLister) List(selector labels.Selector) (ret []*v1alpha.Tenant, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha.Tenant))
+	})
+	return ret, err
+}
+
+// Get retrieves the Tenant from the index for a given name.
+func (s *tenantLister) Get(name string) (*v1alpha.Tenant, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha.Resource("tenant"), name)
+	}
+	return obj.(*v1alpha.Tenant), nil
+}